@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// instance launched outside the tool that can be brought under management
+type adoptCandidate struct {
+	InstanceID   string
+	Name         string
+	InstanceType string
+	State        string
+	PublicIP     string
+	PrivateIP    string
+	Warnings     []string
+}
+
+type adoptResult struct {
+	InstanceID string
+	Warnings   []string
+	Err        error
+}
+
+// instances of the region without the AUTO-BOX tag, terminated ones left out
+func (a *AWS) adoptableInstances(client ec2API) ([]adoptCandidate, error) {
+	ctx := a.jobContext()
+
+	candidates := []adoptCandidate{}
+	paginator := ec2.NewDescribeInstancesPaginator(client, &ec2.DescribeInstancesInput{
+		Filters: []types.Filter{
+			{
+				Name:   aws.String("instance-state-name"),
+				Values: []string{"pending", "running", "stopping", "stopped"},
+			},
+		},
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, reservation := range resp.Reservations {
+			for _, instance := range reservation.Instances {
+				if instanceTag(instance, "AUTO-BOX") == "true" {
+					continue
+				}
+				ipInfo := instanceIPs(instance)
+				candidates = append(candidates, adoptCandidate{
+					InstanceID:   ipInfo.InstanceID,
+					Name:         instanceTag(instance, "Name"),
+					InstanceType: string(instance.InstanceType),
+					State:        string(instance.State.Name),
+					PublicIP:     ipInfo.PublicIP,
+					PrivateIP:    ipInfo.PrivateIP,
+					Warnings:     a.keyPairWarnings(instance),
+				})
+			}
+		}
+	}
+
+	return candidates, nil
+}
+
+// tags the instances as boxes of batchT and adds the AUTO-BOX security group
+// so VNC and the post launch scripts get through. Boxes in another VPC than
+// the group keep their own groups. progress gets a step per box
+func (a *AWS) adoptEC2Instances(client ec2API, instanceIDs []string, batchT, securityGroupID string, progress progressFunc) ([]adoptResult, error) {
+	ctx := a.jobContext()
+
+	if len(instanceIDs) == 0 {
+		return nil, fmt.Errorf("no boxes to adopt")
+	}
+
+	vpcID, err := a.resolveVpcID(client)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: instanceIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	progress.total(len(instanceIDs))
+	results := []adoptResult{}
+	for _, reservation := range resp.Reservations {
+		for _, instance := range reservation.Instances {
+			progress.start(*instance.InstanceId)
+			result := adoptResult{
+				InstanceID: *instance.InstanceId,
+				Warnings:   a.keyPairWarnings(instance),
+			}
+
+			groupIDs := []string{}
+			for _, group := range instance.SecurityGroups {
+				groupIDs = append(groupIDs, aws.ToString(group.GroupId))
+			}
+			switch {
+			case slices.Contains(groupIDs, securityGroupID):
+			case aws.ToString(instance.VpcId) != vpcID:
+				result.Warnings = append(result.Warnings, fmt.Sprintf("in %s, security group %s not added", aws.ToString(instance.VpcId), securityGroupID))
+			default:
+				_, err := client.ModifyInstanceAttribute(ctx, &ec2.ModifyInstanceAttributeInput{
+					InstanceId: instance.InstanceId,
+					Groups:     append(groupIDs, securityGroupID),
+				})
+				if err != nil {
+					result.Err = err
+					progress.finish(result.InstanceID, "", err)
+					results = append(results, result)
+					continue
+				}
+			}
+
+			_, result.Err = client.CreateTags(ctx, &ec2.CreateTagsInput{
+				Resources: []string{*instance.InstanceId},
+				Tags:      a.boxTags(batchT),
+			})
+			progress.finish(result.InstanceID, batchOrAll(batchT), result.Err)
+			results = append(results, result)
+		}
+	}
+
+	return results, nil
+}
+
+// scripts and Windows passwords need the instance's key pair in the region folder
+func (a *AWS) keyPairWarnings(instance types.Instance) []string {
+	switch keyName := aws.ToString(instance.KeyName); keyName {
+	case a.keyPairName():
+		return nil
+	case "":
+		return []string{"launched without a key pair, no Windows password"}
+	default:
+		return []string{fmt.Sprintf("key pair %s, copy %s.pem to ./%s for passwords", keyName, keyName, a.Region)}
+	}
+}
+
+// AUTO-BOX, workspace and BatchTag plus the custom tags every box gets
+func (a *AWS) boxTags(batchT string) []types.Tag {
+	tags := a.ownedTags(types.Tag{
+		Key:   aws.String("BatchTag"),
+		Value: aws.String(batchT),
+	})
+	for key, value := range a.Tags {
+		tags = append(tags, types.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(value),
+		})
+	}
+	return tags
+}
+
+func instanceTag(instance types.Instance, key string) string {
+	value, _ := tagLookup(instance.Tags, key)
+	return value
+}
+
+func tagLookup(tags []types.Tag, key string) (string, bool) {
+	for _, tag := range tags {
+		if aws.ToString(tag.Key) == key {
+			return aws.ToString(tag.Value), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// instance launched by hand, no AUTO-BOX tags
+func addUnmanagedInstance(client *fakeEC2, keyName, vpcID string, state types.InstanceStateName) string {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	instance := client.launch([]types.Tag{{Key: aws.String("Name"), Value: aws.String("by-hand")}}, true, false)
+	instance.State = &types.InstanceState{Name: state}
+	instance.VpcId = aws.String(vpcID)
+	instance.SecurityGroups = []types.GroupIdentifier{{GroupId: aws.String("sg-handmade")}}
+	if keyName != "" {
+		instance.KeyName = aws.String(keyName)
+	}
+	client.instances = append(client.instances, instance)
+	return *instance.InstanceId
+}
+
+func TestAdoptableInstances(t *testing.T) {
+	client := newFakeEC2()
+	sameKey := addUnmanagedInstance(client, "autobox-test", "vpc-default", types.InstanceStateNameRunning)
+	otherKey := addUnmanagedInstance(client, "laptop", "vpc-default", types.InstanceStateNameStopped)
+	noKey := addUnmanagedInstance(client, "", "vpc-default", types.InstanceStateNameRunning)
+	addUnmanagedInstance(client, "autobox-test", "vpc-default", types.InstanceStateNameTerminated)
+	client.addInstance("web", types.InstanceStateNameRunning)
+
+	candidates, err := testAWS().adoptableInstances(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		instanceID   string
+		wantWarnings int
+	}{
+		{sameKey, 0},
+		{otherKey, 1},
+		{noKey, 1},
+	}
+	if len(candidates) != len(tests) {
+		t.Fatalf("%d candidates, want %d", len(candidates), len(tests))
+	}
+	for i, tt := range tests {
+		candidate := candidates[i]
+		if candidate.InstanceID != tt.instanceID || candidate.Name != "by-hand" {
+			t.Errorf("candidate %d = %+v, want %s", i, candidate, tt.instanceID)
+		}
+		if len(candidate.Warnings) != tt.wantWarnings {
+			t.Errorf("%s warnings = %v, want %d", tt.instanceID, candidate.Warnings, tt.wantWarnings)
+		}
+	}
+}
+
+func TestAdoptEC2Instances(t *testing.T) {
+	tests := []struct {
+		name         string
+		vpcID        string
+		groups       []string
+		wantGroups   []string
+		wantWarnings int
+	}{
+		{"security group added", "vpc-default", []string{"sg-handmade"}, []string{"sg-handmade", "sg-auto"}, 0},
+		{"security group already there", "vpc-default", []string{"sg-auto"}, []string{"sg-auto"}, 0},
+		{"other VPC keeps its groups", "vpc-other", []string{"sg-handmade"}, []string{"sg-handmade"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeEC2()
+			instanceID := addUnmanagedInstance(client, "autobox-test", tt.vpcID, types.InstanceStateNameRunning)
+			instance := client.instance(instanceID)
+			instance.SecurityGroups = nil
+			for _, groupID := range tt.groups {
+				instance.SecurityGroups = append(instance.SecurityGroups, types.GroupIdentifier{GroupId: aws.String(groupID)})
+			}
+			a := testAWS()
+			a.Tags = map[string]string{"Owner": "qa"}
+
+			results, err := a.adoptEC2Instances(client, []string{instanceID}, "web", "sg-auto", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 1 || results[0].Err != nil {
+				t.Fatalf("results = %+v", results)
+			}
+			if len(results[0].Warnings) != tt.wantWarnings {
+				t.Errorf("warnings = %v, want %d", results[0].Warnings, tt.wantWarnings)
+			}
+
+			var groups []string
+			for _, group := range instance.SecurityGroups {
+				groups = append(groups, aws.ToString(group.GroupId))
+			}
+			if !slices.Equal(groups, tt.wantGroups) {
+				t.Errorf("security groups = %v, want %v", groups, tt.wantGroups)
+			}
+
+			// adopted boxes show up like deployed ones
+			ids, err := a.batchInstanceIDs(client, "web")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(ids, []string{instanceID}) {
+				t.Errorf("batch web = %v, want the adopted box", ids)
+			}
+			if owner, _ := tagLookup(instance.Tags, "Owner"); owner != "qa" {
+				t.Error("custom tags not applied")
+			}
+		})
+	}
+}
+
+func TestAdoptNoBoxes(t *testing.T) {
+	_, err := testAWS().adoptEC2Instances(newFakeEC2(), nil, "web", "sg-auto", nil)
+	if err == nil {
+		t.Error("expected an error for an empty box list")
+	}
+}
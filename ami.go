@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+type amiInfo struct {
+	ID   string
+	Name string
+	// device of the root volume, encrypted when launching for hibernation
+	RootDevice string
+}
+
+// AMI IDs are region specific, AmiMap keeps the copy for each region
+func (a *AWS) regionAmiID() string {
+	if amiID, ok := a.AmiMap[a.Region]; ok && amiID != "" {
+		return amiID
+	}
+	return a.AmiID
+}
+
+// the AMI to deploy in the current region from now on, over the copy AmiMap
+// kept for it. The copies of the other regions stay
+func (a *AWS) setAmi(spec string) {
+	a.AmiID = spec
+	if a.AmiMap != nil {
+		a.AmiMap[a.Region] = spec
+	}
+}
+
+// the AMI setting can be:
+//   - an AMI ID: ami-0abc123
+//   - owner + name pattern, newest match wins: amazon:Windows_Server-2022-English-Full-Base-*
+//   - an SSM parameter path: /aws/service/ami-windows-latest/Windows_Server-2022-English-Full-Base
+func (a *AWS) resolveAmi(client ec2API) (amiInfo, error) {
+	spec := strings.TrimSpace(a.regionAmiID())
+	cacheKey := a.Region + "|" + spec
+	if ami, ok := a.amiCache[cacheKey]; ok {
+		return ami, nil
+	}
+
+	var ami amiInfo
+	var err error
+	switch {
+	case strings.HasPrefix(spec, "ami-"):
+		ami, err = a.describeAmi(client, spec)
+	case strings.HasPrefix(spec, "resolve:ssm:"), strings.HasPrefix(spec, "/"):
+		ami, err = a.resolveAmiFromSsm(client, strings.TrimPrefix(spec, "resolve:ssm:"))
+	case strings.Contains(spec, ":"):
+		owner, pattern, _ := strings.Cut(spec, ":")
+		ami, err = a.resolveAmiByName(client, owner, pattern)
+	default:
+		err = fmt.Errorf("AMI setting %q is not an AMI ID, owner:name-pattern or SSM parameter path", spec)
+	}
+	if err != nil {
+		return amiInfo{}, err
+	}
+
+	if a.amiCache == nil {
+		a.amiCache = map[string]amiInfo{}
+	}
+	a.amiCache[cacheKey] = ami
+	return ami, nil
+}
+
+func (a *AWS) describeAmi(client ec2API, amiID string) (amiInfo, error) {
+	ctx := a.jobContext()
+
+	resp, err := client.DescribeImages(ctx, &ec2.DescribeImagesInput{
+		ImageIds: []string{amiID},
+	})
+	if err != nil {
+		return amiInfo{}, err
+	}
+	if len(resp.Images) == 0 {
+		return amiInfo{}, fmt.Errorf("AMI %s not found in %s", amiID, a.Region)
+	}
+
+	ami := amiInfo{ID: amiID, RootDevice: aws.ToString(resp.Images[0].RootDeviceName)}
+	if resp.Images[0].Name != nil {
+		ami.Name = *resp.Images[0].Name
+	}
+	return ami, nil
+}
+
+func (a *AWS) resolveAmiFromSsm(client ec2API, parameter string) (amiInfo, error) {
+	ctx := a.jobContext()
+
+	ssmClient, err := a.createSsmClient()
+	if err != nil {
+		return amiInfo{}, err
+	}
+	resp, err := ssmClient.GetParameter(ctx, &ssm.GetParameterInput{
+		Name: aws.String(parameter),
+	})
+	if err != nil {
+		return amiInfo{}, err
+	}
+	if resp.Parameter == nil || resp.Parameter.Value == nil {
+		return amiInfo{}, fmt.Errorf("SSM parameter %s has no value", parameter)
+	}
+
+	return a.describeAmi(client, *resp.Parameter.Value)
+}
+
+// newest available image from owner matching the name pattern
+func (a *AWS) resolveAmiByName(client ec2API, owner, pattern string) (amiInfo, error) {
+	ctx := a.jobContext()
+
+	resp, err := client.DescribeImages(ctx, &ec2.DescribeImagesInput{
+		Owners: []string{owner},
+		Filters: []types.Filter{
+			{
+				Name:   aws.String("name"),
+				Values: []string{pattern},
+			},
+			{
+				Name:   aws.String("state"),
+				Values: []string{"available"},
+			},
+		},
+	})
+	if err != nil {
+		return amiInfo{}, err
+	}
+
+	var newest *types.Image
+	for i, image := range resp.Images {
+		if image.CreationDate == nil {
+			continue
+		}
+		// CreationDate is ISO 8601 so string order is time order
+		if newest == nil || *image.CreationDate > *newest.CreationDate {
+			newest = &resp.Images[i]
+		}
+	}
+	if newest == nil {
+		return amiInfo{}, fmt.Errorf("no AMI owned by %s matches %s in %s", owner, pattern, a.Region)
+	}
+
+	return amiInfo{ID: *newest.ImageId, Name: aws.ToString(newest.Name), RootDevice: aws.ToString(newest.RootDeviceName)}, nil
+}
+
+// AMI line for the header: name + ID for the current region
+func (a *AWS) amiHeader() string {
+	client, err := a.createEc2Client()
+	if err != nil {
+		return fmt.Sprintf("AMI: %s", err)
+	}
+	ami, err := a.resolveAmi(client)
+	if err != nil {
+		return fmt.Sprintf("AMI: %s", err)
+	}
+	return fmt.Sprintf("AMI: %s (%s)", ami.Name, ami.ID)
+}
+
+// copies the AMI configured for the current region into targetRegion
+// and records the new ID in AmiMap. ctx stops the wait for the copy
+func (a *AWS) copyAmiToRegion(ctx context.Context, targetRegion string, progress progressFunc) (string, error) {
+	if targetRegion == a.Region {
+		return "", fmt.Errorf("target region %s is the current region", targetRegion)
+	}
+	sourceClient, err := a.createEc2Client()
+	if err != nil {
+		return "", err
+	}
+	target := *a
+	target.Region = targetRegion
+	targetClient, err := target.createEc2Client()
+	if err != nil {
+		return "", err
+	}
+	return a.copyAmi(ctx, sourceClient, targetClient, targetRegion, progress)
+}
+
+// the copy AmiMap has for targetRegion is reused only while it was made from
+// the AMI the current region resolves to now, a newer image is copied again.
+// progress gets a step for the copy
+func (a *AWS) copyAmi(ctx context.Context, sourceClient, targetClient ec2API, targetRegion string, progress progressFunc) (string, error) {
+	progress.total(1)
+	sourceAmi, err := a.resolveAmi(sourceClient)
+	if err != nil {
+		return "", err
+	}
+	sourceAmiID := sourceAmi.ID
+	step := fmt.Sprintf("%s to %s", sourceAmiID, targetRegion)
+	if amiID := a.AmiMap[targetRegion]; amiID != "" && a.AmiSources[targetRegion] == sourceAmiID {
+		progress.finish(step, fmt.Sprintf("%s, copied before", amiID), nil)
+		return amiID, nil
+	}
+	progress.start(step)
+	amiID, err := a.copyImage(ctx, sourceAmi, targetClient, targetRegion)
+	progress.finish(step, amiID, err)
+	return amiID, err
+}
+
+// copies sourceAmi into targetRegion and waits until the copy is available
+func (a *AWS) copyImage(ctx context.Context, sourceAmi amiInfo, targetClient ec2API, targetRegion string) (string, error) {
+	sourceAmiID := sourceAmi.ID
+	imageName := sourceAmi.Name
+	if imageName == "" {
+		imageName = fmt.Sprintf("%s-%s", sourceAmiID, targetRegion)
+	}
+
+	resp, err := targetClient.CopyImage(ctx, &ec2.CopyImageInput{
+		Name:          aws.String(imageName),
+		SourceImageId: aws.String(sourceAmiID),
+		SourceRegion:  aws.String(a.Region),
+		Description:   aws.String(fmt.Sprintf("Copy of %s from %s", sourceAmiID, a.Region)),
+		TagSpecifications: []types.TagSpecification{
+			{
+				ResourceType: types.ResourceTypeImage,
+				Tags:         a.ownedTags(),
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	newAmiID := *resp.ImageId
+
+	// copies of large Windows images can take a while
+	waiter := ec2.NewImageAvailableWaiter(targetClient)
+	err = waiter.Wait(ctx, &ec2.DescribeImagesInput{
+		ImageIds: []string{newAmiID},
+	}, 60*time.Minute)
+	if err != nil {
+		return newAmiID, err
+	}
+
+	// the source region keeps its setting as typed, a name pattern or SSM
+	// parameter still resolves to the newest image there
+	if a.AmiMap == nil {
+		a.AmiMap = map[string]string{}
+	}
+	if _, ok := a.AmiMap[a.Region]; !ok {
+		a.AmiMap[a.Region] = a.AmiID
+	}
+	a.AmiMap[targetRegion] = newAmiID
+	if a.AmiSources == nil {
+		a.AmiSources = map[string]string{}
+	}
+	a.AmiSources[targetRegion] = sourceAmiID
+
+	return newAmiID, nil
+}
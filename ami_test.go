@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestResolveAmi(t *testing.T) {
+	image := func(id, name, created string, state types.ImageState) types.Image {
+		return types.Image{
+			ImageId:         aws.String(id),
+			Name:            aws.String(name),
+			CreationDate:    aws.String(created),
+			State:           state,
+			ImageOwnerAlias: aws.String("amazon"),
+		}
+	}
+	images := []types.Image{
+		image("ami-old", "Windows_Server-2022-English-Full-Base-2024.11.13", "2024-11-13T00:00:00.000Z", types.ImageStateAvailable),
+		image("ami-new", "Windows_Server-2022-English-Full-Base-2025.01.15", "2025-01-15T00:00:00.000Z", types.ImageStateAvailable),
+		image("ami-pending", "Windows_Server-2022-English-Full-Base-2025.02.12", "2025-02-12T00:00:00.000Z", types.ImageStatePending),
+		image("ami-core", "Windows_Server-2022-English-Core-Base-2025.03.01", "2025-03-01T00:00:00.000Z", types.ImageStateAvailable),
+	}
+
+	tests := []struct {
+		name     string
+		amiID    string
+		amiMap   map[string]string
+		wantID   string
+		wantName string
+		wantErr  bool
+	}{
+		{"AMI ID", "ami-old", nil, "ami-old", "Windows_Server-2022-English-Full-Base-2024.11.13", false},
+		{"region map wins", "ami-old", map[string]string{"us-east-1": "ami-new"}, "ami-new", "Windows_Server-2022-English-Full-Base-2025.01.15", false},
+		{"newest available match", "amazon:Windows_Server-2022-English-Full-Base-*", nil, "ami-new", "Windows_Server-2022-English-Full-Base-2025.01.15", false},
+		{"other owner", "self:Windows_Server-2022-*", nil, "", "", true},
+		{"unknown AMI", "ami-missing", nil, "", "", true},
+		{"bad setting", "windows-2022", nil, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeEC2()
+			client.images = images
+			a := testAWS()
+			a.AmiID = tt.amiID
+			a.AmiMap = tt.amiMap
+
+			got, err := a.resolveAmi(client)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %t", err, tt.wantErr)
+			}
+			if got.ID != tt.wantID || got.Name != tt.wantName {
+				t.Errorf("resolveAmi = %+v, want %s %s", got, tt.wantID, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestResolveAmiCache(t *testing.T) {
+	client := newFakeEC2()
+	client.images = testImages()
+	a := testAWS()
+
+	for range 3 {
+		_, err := a.resolveAmi(client)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := client.callCount("DescribeImages"); got != 1 {
+		t.Errorf("DescribeImages called %d times, want 1", got)
+	}
+
+	// a new region is a new lookup
+	a.Region = "us-west-2"
+	_, err := a.resolveAmi(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := client.callCount("DescribeImages"); got != 2 {
+		t.Errorf("DescribeImages called %d times after a region change, want 2", got)
+	}
+	// the next job resolves it again
+	c := a.clone()
+	_, err = c.resolveAmi(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := client.callCount("DescribeImages"); got != 3 {
+		t.Errorf("DescribeImages called %d times for a clone, want 3", got)
+	}
+}
+
+func TestSetAmi(t *testing.T) {
+	a := testAWS()
+	a.AmiMap = map[string]string{"us-east-1": "ami-copy", "eu-west-1": "ami-eu"}
+
+	a.setAmi("ami-typed")
+	if got := a.regionAmiID(); got != "ami-typed" {
+		t.Errorf("regionAmiID = %s, want the AMI just set over the copy", got)
+	}
+	a.Region = "eu-west-1"
+	if got := a.regionAmiID(); got != "ami-eu" {
+		t.Errorf("regionAmiID in eu-west-1 = %s, want its copy ami-eu", got)
+	}
+
+	a = testAWS()
+	a.setAmi("amazon:Windows_Server-2022-*")
+	if a.AmiMap != nil || a.regionAmiID() != "amazon:Windows_Server-2022-*" {
+		t.Errorf("AmiMap = %v, AMI = %s", a.AmiMap, a.regionAmiID())
+	}
+}
+
+// a copy is reused while the source region still resolves to the AMI it was
+// made from, a newer source image is copied again
+func TestCopyAmi(t *testing.T) {
+	source, target := newFakeEC2(), newFakeEC2()
+	source.images = append(testImages(), types.Image{
+		ImageId: aws.String("ami-0old"),
+		Name:    aws.String("Windows_Server-2022-English-Full-Base-2024.12.10"),
+		State:   types.ImageStateAvailable,
+	})
+	a := testAWS()
+
+	first, err := a.copyAmi(context.Background(), source, target, "eu-west-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.AmiMap["eu-west-1"] != first || a.AmiSources["eu-west-1"] != "ami-0test" || a.AmiMap["us-east-1"] != "ami-0test" {
+		t.Fatalf("AmiMap = %v, AmiSources = %v", a.AmiMap, a.AmiSources)
+	}
+
+	again, err := a.copyAmi(context.Background(), source, target, "eu-west-1", nil)
+	if err != nil || again != first || target.callCount("CopyImage") != 1 {
+		t.Errorf("second copy = %s, %v after %d copies, want %s reused", again, err, target.callCount("CopyImage"), first)
+	}
+
+	// the source region now deploys another image
+	a.setAmi("ami-0old")
+	newer, err := a.copyAmi(context.Background(), source, target, "eu-west-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newer == first || a.AmiMap["eu-west-1"] != newer || a.AmiSources["eu-west-1"] != "ami-0old" {
+		t.Errorf("copy = %s, AmiMap = %v, AmiSources = %v, want a new copy of ami-0old", newer, a.AmiMap, a.AmiSources)
+	}
+}
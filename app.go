@@ -0,0 +1,161 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// saved next to the region folders the scripts go in
+const settingsFile = "settings.json"
+
+type applicationMain struct {
+	Provider    string `json:"provider"`
+	BatchTag    string `json:"batchtag"`
+	URL         string `json:"url"`
+	NumberBoxes int    `json:"numberboxes"`
+	// SkipConfirm lists the menu actions that run without typing the batch or
+	// region first (delete, delete-boxes, scale-down, manifest-apply, stop,
+	// force-unlock), on either provider
+	SkipConfirm []string `json:"skipconfirm"`
+	Digital     Digital  `json:"digital"`
+	Aws         AWS      `json:"aws"`
+}
+
+func defaultSettings() *applicationMain {
+	return &applicationMain{
+		Provider:    "aws",
+		NumberBoxes: 1,
+		Digital: Digital{
+			Region: "nyc3",
+			Size:   "s-2vcpu-4gb",
+			Image:  "ubuntu-24-04-x64",
+		},
+		Aws: AWS{
+			Region:         "us-east-1",
+			PemKeyFileName: "autobox",
+			AmiID:          "/aws/service/ami-windows-latest/Windows_Server-2022-English-Full-Base",
+			InstanceType:   "t3.medium",
+		},
+	}
+}
+
+// the saved settings over the defaults, the defaults alone before the first save
+func loadSettings() (*applicationMain, error) {
+	app := defaultSettings()
+	data, err := os.ReadFile(settingsFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return app, nil
+	}
+	if err != nil {
+		return app, err
+	}
+	err = json.Unmarshal(data, app)
+	if err != nil {
+		return app, fmt.Errorf("error reading %s:\n%w", settingsFile, err)
+	}
+	return app, nil
+}
+
+// keys and tokens are in there, only the operator reads it
+func saveSettings(app *applicationMain) error {
+	data, err := json.MarshalIndent(app, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(settingsFile, data, 0600)
+}
+
+func (app *applicationMain) getAppHeader() string {
+	provider, providerColor, region := "DigitalOcean", digitalColorFront, app.Digital.Region
+	if app.Provider == "aws" {
+		provider, providerColor, region = "AWS", awsColorFront, app.Aws.Region
+	}
+	headerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(headerColorFront))
+
+	header := strings.Builder{}
+	header.WriteString(headerStyle.Render("AUTO-BOX  "))
+	header.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color(providerColor)).Bold(true).Render(provider))
+	header.WriteString(headerStyle.Render(fmt.Sprintf("  Region: %s  # of Boxes: %d", region, app.NumberBoxes)))
+	header.WriteString("\n")
+	header.WriteString(headerStyle.Render("Batch: "))
+	header.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color(batchTagColor)).Render(cmp.Or(app.BatchTag, "ALL")))
+	header.WriteString("\n")
+	header.WriteString(headerStyle.Render(fmt.Sprintf("URL: %s", app.URL)))
+	header.WriteString("\n")
+	return header.String()
+}
+
+// <batch>_<address>.ps1, <address>.ps1 when there is no batch
+func postScriptName(batchT, address string) string {
+	if batchT == "" {
+		return address + ".ps1"
+	}
+	return fmt.Sprintf("%s_%s.ps1", batchT, address)
+}
+
+// writes the post launch script of the box at address to the scripts folder.
+// It opens URL on the box over ssh, run with -F sshConfig on AWS so private
+// boxes are reached through the bastion
+func (app *applicationMain) createPostSCRIPT(address, sshConfig string) error {
+	folder := scriptsFolder(app)
+	err := os.MkdirAll(folder, 0755)
+	if err != nil {
+		return err
+	}
+
+	ssh := fmt.Sprintf("ssh -o StrictHostKeyChecking=accept-new root@%s", address)
+	remote := fmt.Sprintf("nohup xdg-open '%s' >/dev/null 2>&1 &", app.URL)
+	if app.Provider == "aws" {
+		if sshConfig == "" {
+			return fmt.Errorf("no ssh config for the script of %s", address)
+		}
+		ssh = fmt.Sprintf("ssh -F \"%s\" %s", sshConfig, address)
+		remote = fmt.Sprintf("powershell -Command Start-Process '%s'", app.URL)
+	}
+
+	script := fmt.Sprintf("# post launch script of %s, batch %s\n%s \"%s\"\n", address, cmp.Or(app.BatchTag, "ALL"), ssh, remote)
+	return os.WriteFile(filepath.Join(folder, postScriptName(app.BatchTag, address)), []byte(script), 0644)
+}
+
+func powershell() string {
+	if runtime.GOOS == "windows" {
+		return "powershell"
+	}
+	return "pwsh"
+}
+
+// runs the post launch script at path and waits for it
+func (app *applicationMain) runPS1file(path, name string) error {
+	out, err := exec.Command(powershell(), "-NoProfile", "-ExecutionPolicy", "Bypass", "-File", path).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %w\n%s", name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// TightVNC's viewer where its installer puts it, vncviewer elsewhere
+func vncViewer() string {
+	if runtime.GOOS != "windows" {
+		return "vncviewer"
+	}
+	if viewer, err := exec.LookPath("tvnviewer"); err == nil {
+		return viewer
+	}
+	return filepath.Join(os.Getenv("ProgramFiles"), "TightVNC", "tvnviewer.exe")
+}
+
+// opens the viewer on target (host or host::port) and waits for it to close,
+// so a tunnel behind it stays up meanwhile
+func (app *applicationMain) runVNC(target string) error {
+	return exec.Command(vncViewer(), target).Run()
+}
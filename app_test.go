@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadSettings(t *testing.T) {
+	chdirTemp(t)
+
+	app, err := loadSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.Provider != "aws" || app.Aws.Region == "" || app.Digital.Region == "" {
+		t.Errorf("got %+v, want the defaults before the first save", app)
+	}
+
+	app.BatchTag = "web"
+	app.Aws.Region = "eu-west-1"
+	app.Digital.ApiToken = "token"
+	app.SkipConfirm = []string{confirmDelete}
+	if err := saveSettings(app); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.BatchTag != "web" || loaded.Aws.Region != "eu-west-1" || loaded.Digital.ApiToken != "token" || needsConfirm(loaded, confirmDelete) {
+		t.Errorf("got %+v, want the saved settings", loaded)
+	}
+	// settings missing from an older file keep their defaults
+	if loaded.Aws.InstanceType == "" {
+		t.Error("instance type lost its default")
+	}
+
+	os.WriteFile(settingsFile, []byte("{"), 0600)
+	if _, err := loadSettings(); err == nil {
+		t.Error("got no error for a broken settings file")
+	}
+}
+
+func TestCreatePostSCRIPT(t *testing.T) {
+	chdirTemp(t)
+
+	tests := []struct {
+		name     string
+		app      applicationMain
+		address  string
+		wantFile string
+		want     []string
+	}{
+		{
+			"aws batch",
+			applicationMain{Provider: "aws", BatchTag: "web", URL: "https://example.com", Aws: AWS{Region: "us-east-1"}},
+			"203.0.113.10", "us-east-1/web_203.0.113.10.ps1",
+			[]string{`ssh -F "ssh_config-web" 203.0.113.10`, "https://example.com"},
+		},
+		{
+			"aws no batch",
+			applicationMain{Provider: "aws", Aws: AWS{Region: "us-east-1"}},
+			"203.0.113.11", "us-east-1/203.0.113.11.ps1",
+			[]string{`ssh -F "ssh_config-web" 203.0.113.11`},
+		},
+		{
+			"digital",
+			applicationMain{Provider: "digital", BatchTag: "web", Digital: Digital{Region: "nyc3"}},
+			"203.0.113.12", "nyc3/web_203.0.113.12.ps1",
+			[]string{"root@203.0.113.12"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sshConfig := "ssh_config-web"
+			if tt.app.Provider == "digital" {
+				sshConfig = ""
+			}
+			err := tt.app.createPostSCRIPT(tt.address, sshConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			script, err := os.ReadFile(filepath.FromSlash(tt.wantFile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(script), want) {
+					t.Errorf("script %s has no %s:\n%s", tt.wantFile, want, script)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,597 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+type AWS struct {
+	Region            string            `json:"region"`
+	PemKeyFileName    string            `json:"pemkeyfilename"`
+	AmiID             string            `json:"amiid"`
+	AmiMap            map[string]string `json:"amimap"`
+	AmiSources        map[string]string `json:"amisources"` // source AMI of each copy in AmiMap
+	InstanceType      string            `json:"instancetype"`
+	Key               string            `json:"key"`
+	Secret            string            `json:"secret"`
+	ScalePolicy       string            `json:"scalepolicy"`
+	UserData          string            `json:"userdata"`
+	Tags              map[string]string `json:"tags"`
+	SecurityRules     []SecurityRule    `json:"securityrules"`
+	ElasticIPs        bool              `json:"elasticips"`
+	Vpc               string            `json:"vpc"`
+	Subnets           string            `json:"subnets"`
+	SpreadAZs         bool              `json:"spreadazs"`
+	PlacementGroup    string            `json:"placementgroup"`
+	PlacementStrategy string            `json:"placementstrategy"`
+	PrivateOnly       bool              `json:"privateonly"`
+	Bastion           string            `json:"bastion"`
+	SSHUser           string            `json:"sshuser"`
+	IPv6              bool              `json:"ipv6"`
+	// Hibernation launches boxes with an encrypted root volume so HIBERNATE keeps their RAM
+	Hibernation bool `json:"hibernation"`
+	// Endpoint points every client at a local emulator, EndpointOverrides per service (ec2, ssm)
+	Endpoint            string            `json:"endpoint"`
+	EndpointOverrides   map[string]string `json:"endpointoverrides"`
+	EndpointInsecureTLS bool              `json:"endpointinsecuretls"`
+	// Workspace scopes every lookup and delete to the resources tagged with it
+	Workspace string `json:"workspace"`
+	// LockHolder names this operator in batch locks, user@host when empty
+	LockHolder string `json:"lockholder"`
+	// HourlyRates prices instance types per hour (e.g. {"t3.micro": 0.0104}) for the inventory's cost column
+	HourlyRates map[string]float64 `json:"hourlyrates"`
+
+	// AMIs resolved by one job, a deploy looks each up once instead of per box.
+	// Clones start empty so the next job sees a changed SSM parameter or image
+	amiCache map[string]amiInfo
+	// ctx of the job the settings run for, its AWS calls stop with it
+	ctx context.Context
+}
+
+// inbound rule for the AUTO-BOX security group
+type SecurityRule struct {
+	Protocol string `json:"protocol" yaml:"protocol"`
+	Port     int32  `json:"port" yaml:"port"`
+	Cidr     string `json:"cidr" yaml:"cidr"`
+}
+
+var defaultSecurityRules = []SecurityRule{
+	{"tcp", 80, "0.0.0.0/0"},   // HTTP
+	{"tcp", 443, "0.0.0.0/0"},  // HTTPS
+	{"tcp", 5901, "0.0.0.0/0"}, // VNC
+	{"tcp", 22, "0.0.0.0/0"},   //telnet
+}
+
+// copy for a background job, it shares no maps or slices with a
+func (a *AWS) clone() AWS {
+	c := *a
+	c.AmiMap = maps.Clone(a.AmiMap)
+	c.AmiSources = maps.Clone(a.AmiSources)
+	c.Tags = maps.Clone(a.Tags)
+	c.SecurityRules = slices.Clone(a.SecurityRules)
+	c.EndpointOverrides = maps.Clone(a.EndpointOverrides)
+	c.HourlyRates = maps.Clone(a.HourlyRates)
+	c.amiCache = nil
+	return c
+}
+
+// ctx of the job, calls outside one run to the end
+func (a *AWS) jobContext() context.Context {
+	if a.ctx == nil {
+		return context.Background()
+	}
+	return a.ctx
+}
+
+type EC2InstanceIP struct {
+	InstanceID string
+	PublicIP   string
+	PrivateIP  string
+	IPv6       string
+}
+
+func (a *AWS) loadAwsConfig() (aws.Config, error) {
+	ctx := a.jobContext()
+	customCreds := aws.NewCredentialsCache(
+		credentials.NewStaticCredentialsProvider(a.Key, a.Secret, ""),
+	)
+	options := []func(*config.LoadOptions) error{
+		config.WithCredentialsProvider(customCreds),
+		config.WithRegion(a.Region),
+	}
+	// local emulators like LocalStack or moto
+	if a.Endpoint != "" {
+		options = append(options, config.WithBaseEndpoint(a.Endpoint))
+	}
+	if a.EndpointInsecureTLS {
+		options = append(options, config.WithHTTPClient(awshttp.NewBuildableClient().WithTransportOptions(func(tr *http.Transport) {
+			tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		})))
+	}
+	return config.LoadDefaultConfig(ctx, options...)
+}
+
+// EndpointOverrides wins over Endpoint for that service
+func (a *AWS) serviceEndpoint(service string) *string {
+	if endpoint, ok := a.EndpointOverrides[service]; ok && endpoint != "" {
+		return aws.String(endpoint)
+	}
+	return nil
+}
+
+func (a *AWS) createEc2Client() (*ec2.Client, error) {
+	cfg, err := a.loadAwsConfig()
+	if err != nil {
+		return nil, err
+	}
+	client := ec2.NewFromConfig(cfg, func(o *ec2.Options) {
+		if endpoint := a.serviceEndpoint("ec2"); endpoint != nil {
+			o.BaseEndpoint = endpoint
+		}
+	})
+	return client, nil
+}
+
+func (a *AWS) createSsmClient() (*ssm.Client, error) {
+	cfg, err := a.loadAwsConfig()
+	if err != nil {
+		return nil, err
+	}
+	client := ssm.NewFromConfig(cfg, func(o *ssm.Options) {
+		if endpoint := a.serviceEndpoint("ssm"); endpoint != nil {
+			o.BaseEndpoint = endpoint
+		}
+	})
+	return client, nil
+}
+
+func (a *AWS) getActiveEC2s(client ec2API) (int, error) {
+	ctx := a.jobContext()
+
+	// Describe instances with the AUTO-BOX tag
+	resp, err := client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		Filters: a.ownedFilters(types.Filter{
+			Name:   aws.String("instance-state-code"),
+			Values: []string{"16"},
+		}),
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return len(resp.Reservations), nil
+}
+
+func (a *AWS) createPEMFile(client ec2API) error {
+	ctx := a.jobContext()
+	// Check if the key pair already exists
+	keyName := a.keyPairName()
+	existingKEY, err := client.DescribeKeyPairs(ctx, &ec2.DescribeKeyPairsInput{
+		Filters: []types.Filter{
+			{
+				Name:   aws.String("key-name"),
+				Values: []string{keyName},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	// If a security group with the given name exists, return its ID
+	if len(existingKEY.KeyPairs) > 0 {
+		if !a.owns(existingKEY.KeyPairs[0].Tags) {
+			return fmt.Errorf("key pair %s belongs to another workspace, set a different PEM key name", keyName)
+		}
+		return nil
+	}
+
+	resp, err := client.CreateKeyPair(ctx, &ec2.CreateKeyPairInput{
+		KeyName: aws.String(keyName),
+		TagSpecifications: []types.TagSpecification{
+			{
+				ResourceType: types.ResourceTypeKeyPair,
+				Tags:         a.ownedTags(),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	scriptsFolder := fmt.Sprintf("./%s", a.Region)
+	// Ensure the directory exists
+	err2 := os.MkdirAll(scriptsFolder, 0755)
+	if err2 != nil {
+		return err2
+	}
+
+	// fileName := fmt.Sprintf("%s.pem", keyName)
+	fileName, err := filepath.Abs(filepath.Join(scriptsFolder, fmt.Sprintf("%s.pem", keyName)))
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(fileName, []byte(*resp.KeyMaterial), 0400)
+	if err != nil {
+		return err
+	}
+
+	a.restrictWindowsFilePermissions(fileName)
+	return nil
+}
+
+// will install in the configured VPC, that of the Subnets or the default VPC
+// when none is set.
+// sgName gets the workspace appended
+func (a *AWS) createSecurityGroup(sgName, description string, client ec2API) (string, error) {
+	ctx := a.jobContext()
+	sgName = a.scopedName(sgName)
+
+	vpcID, err := a.resolveVpcID(client)
+	if err != nil {
+		return "", err
+	}
+
+	// If a security group with the given name exists, return its ID
+	existing, err := a.findSecurityGroup(client, sgName, vpcID)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil {
+		return *existing.GroupId, nil
+	}
+
+	resp, err := client.CreateSecurityGroup(ctx, &ec2.CreateSecurityGroupInput{
+		GroupName:   aws.String(sgName),
+		Description: aws.String(description),
+		VpcId:       aws.String(vpcID),
+		TagSpecifications: []types.TagSpecification{
+			{
+				ResourceType: types.ResourceTypeSecurityGroup,
+				Tags:         a.ownedTags(),
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	securityGroupID := *resp.GroupId
+
+	rules := a.SecurityRules
+	if len(rules) == 0 {
+		rules = defaultSecurityRules
+	}
+
+	for _, rule := range rules {
+		_, err := client.AuthorizeSecurityGroupIngress(ctx, &ec2.AuthorizeSecurityGroupIngressInput{
+			GroupId:       aws.String(securityGroupID),
+			IpPermissions: []types.IpPermission{a.securityPermission(rule)},
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// fmt.Printf("Security group created: %s\n", securityGroupID)
+	return securityGroupID, nil
+}
+
+// the group named sgName (workspace included) in vpcID, nil when there is none
+func (a *AWS) findSecurityGroup(client ec2API, sgName, vpcID string) (*types.SecurityGroup, error) {
+	existingGroups, err := client.DescribeSecurityGroups(a.jobContext(), &ec2.DescribeSecurityGroupsInput{
+		Filters: []types.Filter{
+			{
+				Name:   aws.String("group-name"),
+				Values: []string{sgName},
+			},
+			{
+				Name:   aws.String("vpc-id"),
+				Values: []string{vpcID},
+			},
+		},
+	})
+	if err != nil || len(existingGroups.SecurityGroups) == 0 {
+		return nil, err
+	}
+	if !a.owns(existingGroups.SecurityGroups[0].Tags) {
+		return nil, fmt.Errorf("security group %s belongs to another workspace", sgName)
+	}
+	return &existingGroups.SecurityGroups[0], nil
+}
+
+// ingress of rule, no CIDR is open to everyone
+func (a *AWS) securityPermission(rule SecurityRule) types.IpPermission {
+	cidr := rule.Cidr
+	if cidr == "" {
+		cidr = "0.0.0.0/0"
+	}
+	permission := types.IpPermission{
+		IpProtocol: aws.String(rule.Protocol),
+		FromPort:   aws.Int32(rule.Port),
+		ToPort:     aws.Int32(rule.Port),
+	}
+	if strings.Contains(cidr, ":") {
+		permission.Ipv6Ranges = []types.Ipv6Range{
+			{CidrIpv6: aws.String(cidr)},
+		}
+		return permission
+	}
+	permission.IpRanges = []types.IpRange{
+		{CidrIp: aws.String(cidr)},
+	}
+	// open to everyone means both address families on dual-stack
+	if a.IPv6 && cidr == "0.0.0.0/0" {
+		permission.Ipv6Ranges = []types.Ipv6Range{
+			{CidrIpv6: aws.String("::/0")},
+		}
+	}
+	return permission
+}
+
+// subnetID empty = default subnet
+func (a *AWS) createEC2Instance(securityGroupID string, client ec2API, batchT, subnetID string) (string, error) {
+	ctx := a.jobContext()
+
+	ami, err := a.resolveAmi(client)
+	if err != nil {
+		return "", err
+	}
+
+	tags := a.boxTags(batchT)
+
+	var userData *string
+	if a.UserData != "" {
+		userData = aws.String(base64.StdEncoding.EncodeToString([]byte(a.UserData)))
+	}
+
+	var subnet *string
+	if subnetID != "" {
+		subnet = aws.String(subnetID)
+	}
+	var placement *types.Placement
+	if a.PlacementGroup != "" {
+		placement = &types.Placement{
+			GroupName: aws.String(a.PlacementGroup),
+		}
+	}
+
+	input := &ec2.RunInstancesInput{
+		ImageId:      aws.String(ami.ID),
+		InstanceType: types.InstanceType(a.InstanceType),
+		KeyName:      aws.String(a.keyPairName()),
+		SecurityGroupIds: []string{
+			securityGroupID,
+		},
+		SubnetId:  subnet,
+		Placement: placement,
+		MinCount:  aws.Int32(1),
+		MaxCount:  aws.Int32(1),
+		UserData:  userData,
+		// persistent spot boxes stop instead of terminating, so POWER and
+		// RESIZE can take them down
+		InstanceMarketOptions: &types.InstanceMarketOptionsRequest{
+			MarketType: types.MarketTypeSpot,
+			SpotOptions: &types.SpotMarketOptions{
+				SpotInstanceType:             types.SpotInstanceTypePersistent,
+				InstanceInterruptionBehavior: types.InstanceInterruptionBehaviorStop,
+			},
+		},
+	}
+
+	// hibernation writes the RAM to the root volume, which has to be encrypted
+	if a.Hibernation {
+		if ami.RootDevice == "" {
+			return "", fmt.Errorf("AMI %s has no root device to encrypt for hibernation", ami.ID)
+		}
+		input.HibernationOptions = &types.HibernationOptionsRequest{Configured: aws.Bool(true)}
+		input.BlockDeviceMappings = []types.BlockDeviceMapping{
+			{
+				DeviceName: aws.String(ami.RootDevice),
+				Ebs:        &types.EbsBlockDevice{Encrypted: aws.Bool(true)},
+			},
+		}
+	}
+
+	// private boxes only get a public IP when they are the batch's bastion,
+	// IPv6 boxes get an address from the dual-stack subnet
+	if a.PrivateOnly || a.IPv6 {
+		publicIP := !a.PrivateOnly
+		if a.PrivateOnly && a.Bastion == "auto" && a.needsBastionBox(client, batchT) {
+			publicIP = true
+			tags = append(tags, types.Tag{
+				Key:   aws.String(bastionRoleTag),
+				Value: aws.String("bastion"),
+			})
+		}
+		networkInterface := types.InstanceNetworkInterfaceSpecification{
+			DeviceIndex:              aws.Int32(0),
+			SubnetId:                 subnet,
+			Groups:                   []string{securityGroupID},
+			AssociatePublicIpAddress: aws.Bool(publicIP),
+		}
+		if a.IPv6 {
+			networkInterface.Ipv6AddressCount = aws.Int32(1)
+		}
+		input.NetworkInterfaces = []types.InstanceNetworkInterfaceSpecification{networkInterface}
+		input.SubnetId = nil
+		input.SecurityGroupIds = nil
+	}
+
+	input.TagSpecifications = []types.TagSpecification{
+		{
+			ResourceType: types.ResourceTypeInstance,
+			Tags:         tags,
+		},
+	}
+
+	resp, err := client.RunInstances(ctx, input)
+	if err != nil {
+		return "", err
+	}
+
+	instanceID := *resp.Instances[0].InstanceId
+	fmt.Printf("EC2 instance created: %s\n", instanceID)
+	return instanceID, nil
+}
+
+func (a *AWS) deleteEC2Instances(client ec2API, batchT string) error {
+	ctx := a.jobContext()
+	if err := a.checkDeleteAll(batchT); err != nil {
+		return err
+	}
+
+	// Describe instances with the AUTO-BOX tag, only the workspace's when set
+	resp, err := client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		Filters: a.ownedFilters(),
+	})
+	if batchT != "" {
+		resp, err = client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+			Filters: a.ownedFilters(types.Filter{
+				Name:   aws.String("tag:BatchTag"),
+				Values: []string{batchT},
+			}),
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	var instanceIDs []string
+	for _, reservation := range resp.Reservations {
+		for _, instance := range reservation.Instances {
+			instanceIDs = append(instanceIDs, *instance.InstanceId)
+		}
+	}
+
+	if len(instanceIDs) == 0 {
+		fmt.Println("No EC2 instances with tag AUTO-BOX found.")
+		return nil
+	}
+
+	err = a.terminateEC2Instances(client, instanceIDs)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Terminated All EC2 instances: %v\n", instanceIDs)
+	return nil
+}
+
+// whether the key pair exists and is the workspace's
+func (a *AWS) ownsKeyPair(client ec2API) (bool, error) {
+	existingKEY, err := client.DescribeKeyPairs(a.jobContext(), &ec2.DescribeKeyPairsInput{
+		Filters: []types.Filter{
+			{
+				Name:   aws.String("key-name"),
+				Values: []string{a.keyPairName()},
+			},
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(existingKEY.KeyPairs) > 0 && a.owns(existingKEY.KeyPairs[0].Tags), nil
+}
+
+// false when there is no key pair of the workspace to delete
+func (a *AWS) deletePEMFile(client ec2API) (bool, error) {
+	ctx := a.jobContext()
+
+	owned, err := a.ownsKeyPair(client)
+	if err != nil || !owned {
+		return false, err
+	}
+
+	// Delete the key pair
+	_, err = client.DeleteKeyPair(ctx, &ec2.DeleteKeyPairInput{
+		KeyName: aws.String(a.keyPairName()),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (a *AWS) compileIPaddressesAws(client ec2API, batchT string) (ips []string, fullEC2 []EC2InstanceIP, err error) {
+	ctx := a.jobContext()
+	// stopped boxes have no public IP, their scripts would be named after the private one
+	live := types.Filter{
+		Name:   aws.String("instance-state-name"),
+		Values: []string{"pending", "running"},
+	}
+
+	// Describe EC2 instances
+	resp, err := client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		Filters: a.ownedFilters(types.Filter{
+			Name:   aws.String("tag:BatchTag"),
+			Values: []string{batchT},
+		}, live),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if batchT == "" {
+		resp, err = client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+			Filters: a.ownedFilters(live),
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Iterate over reservations and instances to collect IP addresses
+	for _, reservation := range resp.Reservations {
+		for _, instance := range reservation.Instances {
+			ipInfo := EC2InstanceIP{
+				InstanceID: *instance.InstanceId,
+			}
+
+			// Check if Public IP exists
+			if instance.PublicIpAddress != nil {
+				ipInfo.PublicIP = *instance.PublicIpAddress
+				ips = append(ips, *instance.PublicIpAddress)
+			}
+
+			// Check if Private IP exists
+			if instance.PrivateIpAddress != nil {
+				ipInfo.PrivateIP = *instance.PrivateIpAddress
+			}
+
+			if instance.Ipv6Address != nil {
+				ipInfo.IPv6 = *instance.Ipv6Address
+			}
+
+			fullEC2 = append(fullEC2, ipInfo)
+		}
+	}
+
+	return ips, fullEC2, nil
+}
+
+func (a *AWS) cloneLambda() error {
+
+	return nil
+}
+
+func (a *AWS) upgradeLambda() error {
+
+	return nil
+}
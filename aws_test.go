@@ -0,0 +1,434 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func testAWS() *AWS {
+	return &AWS{
+		Region:         "us-east-1",
+		PemKeyFileName: "autobox-test",
+		AmiID:          "ami-0test",
+		InstanceType:   "t3.micro",
+	}
+}
+
+func testImages() []types.Image {
+	return []types.Image{
+		{
+			ImageId:        aws.String("ami-0test"),
+			Name:           aws.String("Windows_Server-2022-English-Full-Base-2025.01.15"),
+			State:          types.ImageStateAvailable,
+			RootDeviceName: aws.String("/dev/sda1"),
+			EnaSupport:     aws.Bool(true),
+		},
+	}
+}
+
+func TestGetActiveEC2s(t *testing.T) {
+	tests := []struct {
+		name   string
+		states []types.InstanceStateName
+		want   int
+	}{
+		{"none", nil, 0},
+		{"running only", []types.InstanceStateName{"running", "running"}, 2},
+		{"skips stopped and terminated", []types.InstanceStateName{"running", "stopped", "terminated"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeEC2()
+			for _, state := range tt.states {
+				client.addInstance("web", state)
+			}
+
+			got, err := testAWS().getActiveEC2s(client)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("getActiveEC2s = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreatePEMFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		existing   bool
+		wantCreate int
+		wantFile   bool
+	}{
+		{"new key pair", false, 1, true},
+		{"existing key pair is kept", true, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chdirTemp(t)
+			a := testAWS()
+			client := newFakeEC2()
+			if tt.existing {
+				client.keyPairs = []types.KeyPairInfo{{KeyName: aws.String(a.PemKeyFileName)}}
+			}
+
+			err := a.createPEMFile(client)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := client.callCount("CreateKeyPair"); got != tt.wantCreate {
+				t.Errorf("CreateKeyPair called %d times, want %d", got, tt.wantCreate)
+			}
+			_, err = os.Stat(a.pemFilePath())
+			if gotFile := err == nil; gotFile != tt.wantFile {
+				t.Errorf("PEM file written = %t, want %t", gotFile, tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestDeletePEMFile(t *testing.T) {
+	a := testAWS()
+	client := newFakeEC2()
+	client.keyPairs = []types.KeyPairInfo{
+		{KeyName: aws.String(a.PemKeyFileName)},
+		{KeyName: aws.String("someone-else")},
+	}
+
+	deleted, err := a.deletePEMFile(client)
+	if err != nil || !deleted {
+		t.Fatalf("deleted = %t, err = %v", deleted, err)
+	}
+	if len(client.keyPairs) != 1 || aws.ToString(client.keyPairs[0].KeyName) != "someone-else" {
+		t.Errorf("key pairs left = %v, want only someone-else", client.keyPairs)
+	}
+}
+
+func TestCreateSecurityGroup(t *testing.T) {
+	tests := []struct {
+		name      string
+		rules     []SecurityRule
+		ipv6      bool
+		existing  bool
+		wantRules int
+		wantIPv6  int
+	}{
+		{"default rules", nil, false, false, len(defaultSecurityRules), 0},
+		{"custom rules", []SecurityRule{{"tcp", 3389, "10.0.0.0/8"}}, false, false, 1, 0},
+		{"IPv6 CIDR rule", []SecurityRule{{"tcp", 22, "2001:db8::/32"}}, false, false, 1, 1},
+		{"IPv6 opens ::/0 next to 0.0.0.0/0", []SecurityRule{{"tcp", 80, ""}, {"tcp", 22, "10.0.0.0/8"}}, true, false, 2, 1},
+		{"existing group is reused", nil, false, true, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := testAWS()
+			a.SecurityRules = tt.rules
+			a.IPv6 = tt.ipv6
+			client := newFakeEC2()
+			if tt.existing {
+				client.securityGroups = []types.SecurityGroup{
+					{
+						GroupId:   aws.String("sg-existing"),
+						GroupName: aws.String("sgAutoBox"),
+						VpcId:     aws.String("vpc-default"),
+					},
+				}
+			}
+
+			sgID, err := a.createSecurityGroup("sgAutoBox", "test", client)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.existing && sgID != "sg-existing" {
+				t.Errorf("security group = %s, want sg-existing", sgID)
+			}
+			if len(client.ingress) != tt.wantRules {
+				t.Fatalf("%d ingress rules, want %d", len(client.ingress), tt.wantRules)
+			}
+			ipv6Ranges := 0
+			for _, ingress := range client.ingress {
+				if aws.ToString(ingress.GroupId) != sgID {
+					t.Errorf("rule added to %s, want %s", aws.ToString(ingress.GroupId), sgID)
+				}
+				ipv6Ranges += len(ingress.IpPermissions[0].Ipv6Ranges)
+			}
+			if ipv6Ranges != tt.wantIPv6 {
+				t.Errorf("%d IPv6 ranges, want %d", ipv6Ranges, tt.wantIPv6)
+			}
+		})
+	}
+}
+
+func TestCreateEC2Instance(t *testing.T) {
+	tests := []struct {
+		name          string
+		configure     func(a *AWS)
+		subnetID      string
+		wantInterface bool
+		wantPublicIP  bool
+		wantBastion   bool
+	}{
+		{"defaults", func(a *AWS) {}, "", false, true, false},
+		{"subnet and placement", func(a *AWS) { a.PlacementGroup = "pg-web" }, "subnet-a", false, true, false},
+		{"private box", func(a *AWS) { a.PrivateOnly = true }, "subnet-a", true, false, false},
+		{"first private box of the batch is the bastion", func(a *AWS) {
+			a.PrivateOnly = true
+			a.Bastion = "auto"
+		}, "subnet-a", true, true, true},
+		{"IPv6 box", func(a *AWS) { a.IPv6 = true }, "subnet-a", true, true, false},
+		{"hibernation", func(a *AWS) { a.Hibernation = true }, "", false, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := testAWS()
+			a.Tags = map[string]string{"Owner": "qa"}
+			a.UserData = "<powershell>hostname</powershell>"
+			tt.configure(a)
+			client := newFakeEC2()
+			client.images = testImages()
+
+			instanceID, err := a.createEC2Instance("sg-1", client, "web", tt.subnetID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			input := client.runInputs[0]
+			if input.InstanceMarketOptions == nil || input.InstanceMarketOptions.MarketType != types.MarketTypeSpot {
+				t.Error("box is not launched as spot")
+			}
+			spot := input.InstanceMarketOptions.SpotOptions
+			if spot == nil || spot.SpotInstanceType != types.SpotInstanceTypePersistent || spot.InstanceInterruptionBehavior != types.InstanceInterruptionBehaviorStop {
+				t.Errorf("spot options = %+v, want a persistent request that stops the box", spot)
+			}
+			gotHibernation := input.HibernationOptions != nil && aws.ToBool(input.HibernationOptions.Configured)
+			if gotHibernation != a.Hibernation {
+				t.Errorf("hibernation configured = %t, want %t", gotHibernation, a.Hibernation)
+			}
+			if a.Hibernation && (len(input.BlockDeviceMappings) != 1 || aws.ToString(input.BlockDeviceMappings[0].DeviceName) != "/dev/sda1" ||
+				!aws.ToBool(input.BlockDeviceMappings[0].Ebs.Encrypted)) {
+				t.Errorf("block devices = %+v, want the root volume encrypted", input.BlockDeviceMappings)
+			}
+			if input.UserData == nil {
+				t.Error("user data not passed")
+			}
+			if gotInterface := len(input.NetworkInterfaces) > 0; gotInterface != tt.wantInterface {
+				t.Errorf("network interface = %t, want %t", gotInterface, tt.wantInterface)
+			}
+			if tt.wantInterface && (input.SubnetId != nil || input.SecurityGroupIds != nil) {
+				t.Error("subnet and security groups must move to the network interface")
+			}
+			if !tt.wantInterface && aws.ToString(input.SubnetId) != tt.subnetID {
+				t.Errorf("subnet = %s, want %s", aws.ToString(input.SubnetId), tt.subnetID)
+			}
+			if a.PlacementGroup != "" && (input.Placement == nil || aws.ToString(input.Placement.GroupName) != a.PlacementGroup) {
+				t.Error("placement group not passed")
+			}
+
+			instance := client.instance(instanceID)
+			if gotPublic := instance.PublicIpAddress != nil; gotPublic != tt.wantPublicIP {
+				t.Errorf("public IP = %t, want %t", gotPublic, tt.wantPublicIP)
+			}
+			for _, key := range []string{"AUTO-BOX", "BatchTag", "Owner"} {
+				if _, ok := tagLookup(instance.Tags, key); !ok {
+					t.Errorf("tag %s missing", key)
+				}
+			}
+			if _, gotBastion := tagLookup(instance.Tags, bastionRoleTag); gotBastion != tt.wantBastion {
+				t.Errorf("bastion tag = %t, want %t", gotBastion, tt.wantBastion)
+			}
+		})
+	}
+}
+
+func TestCreateEC2InstanceSecondPrivateBoxIsNotBastion(t *testing.T) {
+	a := testAWS()
+	a.PrivateOnly = true
+	a.Bastion = "auto"
+	client := newFakeEC2()
+	client.images = testImages()
+
+	ids := []string{}
+	for range 2 {
+		instanceID, err := a.createEC2Instance("sg-1", client, "web", "subnet-a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, instanceID)
+	}
+
+	if client.instance(ids[1]).PublicIpAddress != nil {
+		t.Error("second private box got a public IP")
+	}
+}
+
+func TestCreateEC2InstanceErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		amiID  string
+		failOn string
+	}{
+		{"unknown AMI", "ami-0missing", ""},
+		{"bad AMI setting", "windows", ""},
+		{"RunInstances fails", "ami-0test", "RunInstances"},
+		{"hibernation without a root device", "ami-0noroot", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := testAWS()
+			a.AmiID = tt.amiID
+			a.Hibernation = true
+			client := newFakeEC2()
+			client.images = append(testImages(), types.Image{ImageId: aws.String("ami-0noroot"), State: types.ImageStateAvailable})
+			client.failOn = map[string]error{tt.failOn: errors.New("InsufficientInstanceCapacity")}
+
+			_, err := a.createEC2Instance("sg-1", client, "web", "")
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if len(client.instances) != 0 {
+				t.Errorf("%d boxes launched, want 0", len(client.instances))
+			}
+		})
+	}
+}
+
+func TestDeleteEC2Instances(t *testing.T) {
+	tests := []struct {
+		name           string
+		workspace      string
+		batchT         string
+		wantTerminated []string
+		wantErr        bool
+	}{
+		{"empty batch tag deletes every batch of the workspace", "alice", "", []string{"web", "web", "db"}, false},
+		{"every batch needs a workspace", "", "", nil, true},
+		{"only the batch", "", "web", []string{"web", "web"}, false},
+		{"unknown batch", "", "nope", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeEC2()
+			client.addInstance("web", types.InstanceStateNameRunning, workspaceTag, "alice")
+			client.addInstance("web", types.InstanceStateNameStopped, workspaceTag, "alice")
+			client.addInstance("db", types.InstanceStateNameRunning, workspaceTag, "alice")
+			client.instances = append(client.instances, types.Instance{
+				InstanceId: aws.String("i-notours"),
+				State:      &types.InstanceState{Name: types.InstanceStateNameRunning},
+			})
+			a := testAWS()
+			a.Workspace = tt.workspace
+
+			err := a.deleteEC2Instances(client, tt.batchT)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %t", err, tt.wantErr)
+			}
+
+			var terminated []string
+			for _, instance := range client.instances {
+				if instance.State.Name == types.InstanceStateNameTerminated {
+					batchT, _ := tagLookup(instance.Tags, "BatchTag")
+					terminated = append(terminated, batchT)
+				}
+			}
+			if !slices.Equal(terminated, tt.wantTerminated) {
+				t.Errorf("terminated batches %v, want %v", terminated, tt.wantTerminated)
+			}
+			wantTerminateCalls := 1
+			if len(tt.wantTerminated) == 0 {
+				wantTerminateCalls = 0
+			}
+			if got := client.callCount("TerminateInstances"); got != wantTerminateCalls {
+				t.Errorf("TerminateInstances called %d times, want %d", got, wantTerminateCalls)
+			}
+		})
+	}
+}
+
+func TestDeleteEC2InstancesError(t *testing.T) {
+	client := newFakeEC2()
+	client.addInstance("web", types.InstanceStateNameRunning)
+	client.failOn = map[string]error{"DescribeInstances": errors.New("UnauthorizedOperation")}
+
+	err := testAWS().deleteEC2Instances(client, "web")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if client.callCount("TerminateInstances") != 0 {
+		t.Error("terminated boxes after a failed lookup")
+	}
+}
+
+func TestCompileIPaddressesAws(t *testing.T) {
+	tests := []struct {
+		name          string
+		batchT        string
+		wantBoxes     int
+		wantIPs       int
+		wantDescribes int
+	}{
+		{"empty batch tag lists every batch", "", 3, 2, 2},
+		{"one batch", "web", 2, 1, 1},
+		{"unknown batch", "nope", 0, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeEC2()
+			client.addInstance("web", types.InstanceStateNameRunning)
+			private := client.addInstance("web", types.InstanceStateNameRunning)
+			client.instance(private).PublicIpAddress = nil
+			client.instance(private).Ipv6Address = aws.String("2600:1f18::9")
+			client.addInstance("db", types.InstanceStateNameRunning)
+			client.addInstance("web", types.InstanceStateNameStopped)
+
+			ips, boxes, err := testAWS().compileIPaddressesAws(client, tt.batchT)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(boxes) != tt.wantBoxes {
+				t.Errorf("%d boxes, want %d", len(boxes), tt.wantBoxes)
+			}
+			if len(ips) != tt.wantIPs {
+				t.Errorf("%d public IPs, want %d", len(ips), tt.wantIPs)
+			}
+			if got := client.callCount("DescribeInstances"); got != tt.wantDescribes {
+				t.Errorf("DescribeInstances called %d times, want %d", got, tt.wantDescribes)
+			}
+			for _, box := range boxes {
+				if box.InstanceID == private && (box.PublicIP != "" || box.IPv6 == "" || box.PrivateIP == "") {
+					t.Errorf("private box addresses = %+v", box)
+				}
+			}
+		})
+	}
+}
+
+func TestClone(t *testing.T) {
+	a := testAWS()
+	a.AmiMap = map[string]string{"us-east-1": "ami-0test"}
+	a.AmiSources = map[string]string{"us-west-2": "ami-0test"}
+	a.Tags = map[string]string{"Env": "lab"}
+	a.SecurityRules = []SecurityRule{{"tcp", 22, "10.0.0.0/8"}}
+	a.EndpointOverrides = map[string]string{"ec2": "http://localhost:4566"}
+	a.HourlyRates = map[string]float64{"t3.micro": 0.0104}
+
+	c := a.clone()
+	c.AmiMap["us-west-2"] = "ami-0copy"
+	c.AmiSources["eu-west-1"] = "ami-0test"
+	c.Tags["Env"] = "prod"
+	c.SecurityRules[0].Port = 3389
+	c.EndpointOverrides["ssm"] = "http://localhost:4566"
+	c.HourlyRates["t3.micro"] = 1
+
+	if len(a.AmiMap) != 1 || len(a.AmiSources) != 1 || a.Tags["Env"] != "lab" || a.SecurityRules[0].Port != 22 || len(a.EndpointOverrides) != 1 || a.HourlyRates["t3.micro"] != 0.0104 {
+		t.Errorf("clone shares settings with the original: %+v", a)
+	}
+	if c.Region != a.Region || c.AmiID != a.AmiID {
+		t.Errorf("clone = %+v, want the settings of %+v", c, a)
+	}
+}
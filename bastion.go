@@ -0,0 +1,195 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+const (
+	bastionRoleTag   = "AUTO-BOX-ROLE"
+	bastionHostAlias = "autobox-bastion"
+)
+
+func (a *AWS) sshUser() string {
+	if a.SSHUser != "" {
+		return a.SSHUser
+	}
+	return "Administrator"
+}
+
+func (a *AWS) batchBastion(client ec2API, batchT string) (*types.Instance, error) {
+	ctx := a.jobContext()
+
+	resp, err := client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		Filters: a.ownedFilters(
+			types.Filter{
+				Name:   aws.String("tag:BatchTag"),
+				Values: []string{batchT},
+			},
+			types.Filter{
+				Name:   aws.String(fmt.Sprintf("tag:%s", bastionRoleTag)),
+				Values: []string{"bastion"},
+			},
+			types.Filter{
+				Name:   aws.String("instance-state-name"),
+				Values: []string{"pending", "running", "stopping", "stopped"},
+			},
+		),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, reservation := range resp.Reservations {
+		for _, instance := range reservation.Instances {
+			return &instance, nil
+		}
+	}
+	return nil, nil
+}
+
+func (a *AWS) needsBastionBox(client ec2API, batchT string) bool {
+	bastion, err := a.batchBastion(client, batchT)
+	return err == nil && bastion == nil
+}
+
+// Bastion can be "auto" (a box of the batch), an instance ID or user@host of an existing host.
+// Returns user@host, empty when no bastion is configured
+func (a *AWS) resolveBastion(client ec2API, batchT string) (string, error) {
+	ctx := a.jobContext()
+
+	switch {
+	case a.Bastion == "":
+		return "", nil
+	case a.Bastion == "auto":
+		bastion, err := a.batchBastion(client, batchT)
+		if err != nil {
+			return "", err
+		}
+		if bastion == nil || bastion.PublicIpAddress == nil {
+			return "", fmt.Errorf("batch %s has no running bastion box", batchT)
+		}
+		return fmt.Sprintf("%s@%s", a.sshUser(), *bastion.PublicIpAddress), nil
+	case strings.HasPrefix(a.Bastion, "i-"):
+		resp, err := client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+			InstanceIds: []string{a.Bastion},
+		})
+		if err != nil {
+			return "", err
+		}
+		if len(resp.Reservations) == 0 || resp.Reservations[0].Instances[0].PublicIpAddress == nil {
+			return "", fmt.Errorf("bastion %s has no public IP", a.Bastion)
+		}
+		return fmt.Sprintf("%s@%s", a.sshUser(), *resp.Reservations[0].Instances[0].PublicIpAddress), nil
+	case strings.Contains(a.Bastion, "@"):
+		return a.Bastion, nil
+	default:
+		return fmt.Sprintf("%s@%s", a.sshUser(), a.Bastion), nil
+	}
+}
+
+// address post launch scripts and VNC should use: public IPv4, then IPv6,
+// then the private IP for boxes reached through the bastion. Empty when the
+// box cannot be reached from here
+func (a *AWS) boxAddress(box EC2InstanceIP) string {
+	switch {
+	case box.PublicIP != "":
+		return box.PublicIP
+	case box.IPv6 != "" && !a.PrivateOnly:
+		return ipv6LiteralName(box.IPv6)
+	case a.PrivateOnly || a.Bastion != "":
+		return box.PrivateIP
+	default:
+		return ""
+	}
+}
+
+// host alias of the bastion in the ssh config of the batch, one per config so
+// configs included side by side each jump through their own
+func (a *AWS) bastionAlias(batchT string) string {
+	return fmt.Sprintf("%s-%s-%s", bastionHostAlias, a.Region, cmp.Or(batchT, "all"))
+}
+
+// Windows resolves 2600-1f18--1.ipv6-literal.net to 2600:1f18::1 without DNS,
+// unlike the raw address it is safe in file names and host::port targets
+func ipv6LiteralName(address string) string {
+	literal := strings.ReplaceAll(address, ":", "-")
+	literal = strings.ReplaceAll(literal, "%", "s")
+	return literal + ".ipv6-literal.net"
+}
+
+// ssh config in the region folder so private boxes are reached through the bastion:
+// ssh -F ./us-east-1/ssh_config-BATCH 10.0.1.15
+func (a *AWS) writeSSHConfig(boxes []EC2InstanceIP, bastion, batchT string) (string, error) {
+	scriptsFolder := fmt.Sprintf("./%s", a.Region)
+	err := os.MkdirAll(scriptsFolder, 0755)
+	if err != nil {
+		return "", err
+	}
+	pemFile, err := filepath.Abs(a.pemFilePath())
+	if err != nil {
+		return "", err
+	}
+
+	config := strings.Builder{}
+	alias := a.bastionAlias(batchT)
+	if bastion != "" {
+		bastionUser, bastionHost, _ := strings.Cut(bastion, "@")
+		fmt.Fprintf(&config, "Host %s\n  HostName %s\n  User %s\n  IdentityFile \"%s\"\n  StrictHostKeyChecking accept-new\n\n",
+			alias, bastionHost, bastionUser, pemFile)
+	}
+	for _, box := range boxes {
+		address := a.boxAddress(box)
+		if address == "" {
+			continue
+		}
+		fmt.Fprintf(&config, "Host %s %s\n  HostName %s\n  User %s\n  IdentityFile \"%s\"\n  StrictHostKeyChecking accept-new\n",
+			address, box.InstanceID, address, a.sshUser(), pemFile)
+		if bastion != "" && box.PublicIP == "" {
+			fmt.Fprintf(&config, "  ProxyJump %s\n", alias)
+		}
+		config.WriteString("\n")
+	}
+
+	fileName := "ssh_config"
+	if batchT != "" {
+		fileName = fmt.Sprintf("ssh_config-%s", batchT)
+	}
+	filePath, err := filepath.Abs(filepath.Join(scriptsFolder, fileName))
+	if err != nil {
+		return "", err
+	}
+	return filePath, os.WriteFile(filePath, []byte(config.String()), 0600)
+}
+
+func (a *AWS) sshCommands(boxes []EC2InstanceIP, configFile string) []string {
+	commands := []string{}
+	for _, box := range boxes {
+		address := a.boxAddress(box)
+		if address == "" {
+			continue
+		}
+		commands = append(commands, fmt.Sprintf("ssh -F \"%s\" %s", configFile, address))
+	}
+	return commands
+}
+
+// forwards localPort to the box's VNC port through the bastion of the batch's
+// ssh config, kill the process to close it
+func (a *AWS) openVNCTunnel(configFile, batchT string, box EC2InstanceIP, localPort int) (*exec.Cmd, error) {
+	tunnel := exec.Command("ssh", "-F", configFile, "-N",
+		"-L", fmt.Sprintf("127.0.0.1:%d:%s:5901", localPort, box.PrivateIP),
+		a.bastionAlias(batchT))
+	err := tunnel.Start()
+	if err != nil {
+		return nil, err
+	}
+	return tunnel, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestResolveBastion(t *testing.T) {
+	client := newFakeEC2()
+	bastionID := client.addInstance("web", types.InstanceStateNameRunning, bastionRoleTag, "bastion")
+	client.addInstance("web", types.InstanceStateNameRunning)
+	client.addInstance("db", types.InstanceStateNameRunning)
+	bastionIP := *client.instance(bastionID).PublicIpAddress
+
+	tests := []struct {
+		name    string
+		bastion string
+		sshUser string
+		batchT  string
+		want    string
+		wantErr bool
+	}{
+		{"none", "", "", "web", "", false},
+		{"auto", "auto", "", "web", "Administrator@" + bastionIP, false},
+		{"auto without a bastion box", "auto", "", "db", "", true},
+		{"instance ID", bastionID, "ec2-user", "web", "ec2-user@" + bastionIP, false},
+		{"user@host", "admin@jump.example.com", "", "web", "admin@jump.example.com", false},
+		{"host", "jump.example.com", "ubuntu", "web", "ubuntu@jump.example.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := testAWS()
+			a.Bastion = tt.bastion
+			a.SSHUser = tt.sshUser
+
+			got, err := a.resolveBastion(client, tt.batchT)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %t", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolveBastion = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBoxAddress(t *testing.T) {
+	tests := []struct {
+		name        string
+		box         EC2InstanceIP
+		privateOnly bool
+		bastion     string
+		want        string
+	}{
+		{"public IP first", EC2InstanceIP{PublicIP: "54.0.0.1", PrivateIP: "10.0.0.1", IPv6: "2600:1f18::1"}, false, "", "54.0.0.1"},
+		{"IPv6 next", EC2InstanceIP{PrivateIP: "10.0.0.1", IPv6: "2600:1f18::1"}, false, "", "2600-1f18--1.ipv6-literal.net"},
+		{"private only", EC2InstanceIP{PrivateIP: "10.0.0.1", IPv6: "2600:1f18::1"}, true, "", "10.0.0.1"},
+		{"private through the bastion", EC2InstanceIP{PrivateIP: "10.0.0.1"}, false, "auto", "10.0.0.1"},
+		{"private without a bastion", EC2InstanceIP{PrivateIP: "10.0.0.1"}, false, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := testAWS()
+			a.PrivateOnly = tt.privateOnly
+			a.Bastion = tt.bastion
+			if got := a.boxAddress(tt.box); got != tt.want {
+				t.Errorf("boxAddress = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteSSHConfig(t *testing.T) {
+	chdirTemp(t)
+	a := testAWS()
+	a.PrivateOnly = true
+	boxes := []EC2InstanceIP{
+		{InstanceID: "i-bastion", PublicIP: "54.0.0.1", PrivateIP: "10.0.0.1"},
+		{InstanceID: "i-private", PrivateIP: "10.0.0.2"},
+	}
+
+	configFile, err := a.writeSSHConfig(boxes, "Administrator@54.0.0.1", "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := string(data)
+
+	alias := a.bastionAlias("web")
+	if !strings.Contains(config, "Host "+alias+"\n") {
+		t.Error("bastion host missing")
+	}
+	if strings.Count(config, "ProxyJump "+alias+"\n") != 1 {
+		t.Errorf("want exactly the private box behind the bastion:\n%s", config)
+	}
+	commands := a.sshCommands(boxes, configFile)
+	if len(commands) != 2 || !strings.HasSuffix(commands[1], " 10.0.0.2") {
+		t.Errorf("sshCommands = %v", commands)
+	}
+}
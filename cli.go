@@ -0,0 +1,426 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// exit codes of RunCLI
+const (
+	exitOK     = 0
+	exitFailed = 1
+	exitUsage  = 2
+	exitLocked = 3
+)
+
+var cliCommands = []string{"deploy", "delete", "list", "scripts", "run-urls", "verify", "manifest", "settings"}
+
+const cliUsage = `Usage: autobox <command> [flags]
+
+Commands:
+  deploy             launch the configured # of boxes into the batch
+  delete             delete the batch (every box when no batch) and its scripts
+  list               list the boxes of the batch
+  scripts            create the post launch scripts of the batch
+  run-urls           run the post launch scripts of the batch
+  verify             open TightVNC on the boxes of the batch
+  manifest plan|apply FILE
+                     show or make the changes a .yaml or .json manifest asks for
+  settings get [key] print the saved settings, or one of them (e.g. aws.region)
+  settings set key=value...
+                     change saved settings
+
+Flags override the saved settings for this run only:
+  --provider aws|digital  --batch TAG  --region REGION  --count N  --url URL
+  --ami AMI  --type INSTANCE_TYPE  --workspace NAME  --json
+
+delete, scripts, run-urls and verify take --boxes ID,ID to work on those boxes
+only, whatever their batch (instance IDs or addresses, IPs on DigitalOcean)
+
+Exit codes: 0 ok, 1 failed, 2 bad usage, 3 batch locked by another operator
+`
+
+// setting keys never printed in full
+var secretSettings = []string{"key", "secret", "apitoken"}
+
+type cliBox struct {
+	InstanceID string `json:"instanceId,omitempty"`
+	Address    string `json:"address"`
+	PublicIP   string `json:"publicIp,omitempty"`
+	PrivateIP  string `json:"privateIp,omitempty"`
+	IPv6       string `json:"ipv6,omitempty"`
+}
+
+type cliResult struct {
+	Command  string   `json:"command"`
+	OK       bool     `json:"ok"`
+	Result   string   `json:"result,omitempty"`
+	Error    string   `json:"error,omitempty"`
+	Boxes    []cliBox `json:"boxes,omitempty"`
+	Settings any      `json:"settings,omitempty"`
+}
+
+// IsCLICommand tells main to hand os.Args[1:] to RunCLI instead of showing the menu
+func IsCLICommand(name string) bool {
+	return slices.Contains(cliCommands, name) || name == "help" || name == "-h" || name == "--help"
+}
+
+// RunCLI runs one menu action without the menu, for CI and cron, and returns
+// the exit code. It reuses the job bodies the menu runs. Ctrl+C stops the job
+// before its next step, so batch locks are still released.
+func RunCLI(app *applicationMain, args []string) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return runCLI(ctx, app, args, os.Stdout, os.Stderr)
+}
+
+func runCLI(ctx context.Context, app *applicationMain, args []string, stdout, stderr io.Writer) int {
+	app.withContext(ctx)
+	if len(args) == 0 || !slices.Contains(cliCommands, args[0]) {
+		fmt.Fprint(stderr, cliUsage)
+		if len(args) > 0 && (args[0] == "help" || args[0] == "-h" || args[0] == "--help") {
+			return exitOK
+		}
+		return exitUsage
+	}
+	command := args[0]
+	args = args[1:]
+
+	subcommand := ""
+	switch command {
+	case "settings":
+		if len(args) == 0 || (args[0] != "get" && args[0] != "set") {
+			fmt.Fprintln(stderr, "settings takes get or set")
+			return exitUsage
+		}
+		subcommand, args = args[0], args[1:]
+	case "manifest":
+		if len(args) == 0 || (args[0] != "plan" && args[0] != "apply") {
+			fmt.Fprintln(stderr, "manifest takes plan or apply")
+			return exitUsage
+		}
+		subcommand, args = args[0], args[1:]
+	}
+
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { fmt.Fprint(stderr, cliUsage) }
+	jsonOut := flags.Bool("json", false, "print the result as JSON")
+	provider := flags.String("provider", "", "aws or digital")
+	batchT := flags.String("batch", "", "batch tag")
+	region := flags.String("region", "", "region to deploy")
+	count := flags.Int("count", 0, "# of boxes to deploy")
+	url := flags.String("url", "", "URL post launch")
+	ami := flags.String("ami", "", "AMI to deploy")
+	instanceType := flags.String("type", "", "instance type")
+	workspace := flags.String("workspace", "", "workspace")
+	boxes := flags.String("boxes", "", "boxes to work on instead of the batch")
+
+	// flags may come after the settings keys too
+	positional := []string{}
+	for {
+		err := flags.Parse(args)
+		if err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return exitOK
+			}
+			return exitUsage
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	switch {
+	case command == "manifest" && len(positional) != 1:
+		fmt.Fprintln(stderr, "manifest takes the path of one manifest file")
+		return exitUsage
+	case command != "settings" && command != "manifest" && len(positional) > 0:
+		fmt.Fprintf(stderr, "%s takes no arguments, got %s\n", command, strings.Join(positional, " "))
+		return exitUsage
+	}
+	only := boxList(splitBoxList(*boxes))
+	if flagGiven(flags, "boxes") && !slices.Contains([]string{"delete", "scripts", "run-urls", "verify"}, command) {
+		fmt.Fprintf(stderr, "%s does not take --boxes\n", command)
+		return exitUsage
+	}
+
+	// only the flags given on the command line override the settings
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "provider":
+			app.Provider = *provider
+		case "batch":
+			app.BatchTag = *batchT
+		case "count":
+			app.NumberBoxes = *count
+		case "url":
+			app.URL = *url
+		case "type":
+			app.Aws.InstanceType = *instanceType
+		case "workspace":
+			app.Aws.Workspace = *workspace
+		}
+	})
+	if flagGiven(flags, "region") {
+		if app.Provider == "aws" {
+			app.Aws.Region = *region
+		} else {
+			app.Digital.Region = *region
+		}
+	}
+	// for the region given
+	if flagGiven(flags, "ami") {
+		app.Aws.setAmi(*ami)
+	}
+	if app.Provider != "aws" && app.Provider != "digital" {
+		fmt.Fprintf(stderr, "unknown provider %q, use aws or digital\n", app.Provider)
+		return exitUsage
+	}
+
+	// a line per step on stderr, with --json only the JSON is printed
+	var progress progressFunc
+	if !*jsonOut {
+		progress = printProgress(stderr)
+	}
+
+	var err error
+	result := cliResult{Command: strings.TrimSpace(command + " " + subcommand)}
+	switch command {
+	case "deploy":
+		result.Result, err = deployBoxes(ctx, app, progress)
+	case "delete":
+		result.Result, err = deleteBoxes(ctx, app, only, nil, progress)
+	case "list":
+		result.Boxes, err = listBoxes(app)
+		result.Result = fmt.Sprintf("%d boxes", len(result.Boxes))
+	case "scripts":
+		result.Result, err = createPostScripts(ctx, app, only, progress)
+	case "run-urls":
+		result.Result, err = runPostURLs(ctx, app, only, progress)
+	case "verify":
+		result.Result, err = verifyBoxes(ctx, app, only, progress)
+	case "manifest":
+		result.Result, err = runManifest(app, positional[0], subcommand == "apply", progress)
+	case "settings":
+		if subcommand == "get" {
+			result.Settings, err = getSettings(app, positional)
+		} else {
+			err = setSettings(app, positional)
+			if err == nil {
+				err = saveSettings(app)
+			}
+			if err == nil {
+				result.Result = "Settings Saved"
+			}
+		}
+	}
+
+	result.OK = err == nil
+	if err != nil {
+		result.Error = err.Error()
+	}
+	printCLIResult(result, *jsonOut, stdout, stderr)
+
+	var locked *lockedError
+	switch {
+	case errors.As(err, &locked):
+		return exitLocked
+	case errors.Is(err, errSettingUsage):
+		return exitUsage
+	case err != nil:
+		return exitFailed
+	}
+	return exitOK
+}
+
+func flagGiven(flags *flag.FlagSet, name string) bool {
+	given := false
+	flags.Visit(func(f *flag.Flag) {
+		given = given || f.Name == name
+	})
+	return given
+}
+
+func printCLIResult(result cliResult, jsonOut bool, stdout, stderr io.Writer) {
+	if jsonOut {
+		out, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Fprintln(stdout, string(out))
+		return
+	}
+
+	for _, box := range result.Boxes {
+		fmt.Fprintf(stdout, "%-20s %-40s %s\n", box.InstanceID, box.Address, box.PrivateIP)
+	}
+	switch settings := result.Settings.(type) {
+	case nil:
+	case string:
+		fmt.Fprintln(stdout, settings)
+	default:
+		out, _ := json.MarshalIndent(settings, "", "  ")
+		fmt.Fprintln(stdout, string(out))
+	}
+	if result.Result != "" {
+		fmt.Fprintln(stdout, result.Result)
+	}
+	if result.Error != "" {
+		fmt.Fprintln(stderr, result.Error)
+	}
+}
+
+// boxes of the batch (every box when no batch), by the address scripts use
+func listBoxes(app *applicationMain) ([]cliBox, error) {
+	boxes := []cliBox{}
+
+	if app.Provider == "digital" {
+		ips, err := app.Digital.compileIPaddressesDigital(app.BatchTag)
+		if err != nil {
+			return nil, fmt.Errorf("error compiling IP addresses:\n%w", err)
+		}
+		for _, ip := range ips {
+			boxes = append(boxes, cliBox{Address: ip, PublicIP: ip})
+		}
+		return boxes, nil
+	}
+
+	pepa, err := app.Aws.createEc2Client()
+	if err != nil {
+		return nil, fmt.Errorf("error getting AWS credentials:\n%w", err)
+	}
+	_, found, err := app.Aws.compileIPaddressesAws(pepa, app.BatchTag)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling IP addresses:\n%w", err)
+	}
+	for _, box := range found {
+		boxes = append(boxes, cliBox{
+			InstanceID: box.InstanceID,
+			Address:    app.Aws.boxAddress(box),
+			PublicIP:   box.PublicIP,
+			PrivateIP:  box.PrivateIP,
+			IPv6:       box.IPv6,
+		})
+	}
+	return boxes, nil
+}
+
+var errSettingUsage = errors.New("use settings set key=value, keys as in settings get (e.g. aws.region)")
+
+// settings by their JSON keys, as saved
+func settingsMap(app *applicationMain) (map[string]any, error) {
+	raw, err := json.Marshal(app)
+	if err != nil {
+		return nil, err
+	}
+	settings := map[string]any{}
+	err = json.Unmarshal(raw, &settings)
+	return settings, err
+}
+
+// secrets only show when asked for by key
+func maskSecrets(settings map[string]any) {
+	for key, value := range settings {
+		switch value := value.(type) {
+		case map[string]any:
+			maskSecrets(value)
+		case string:
+			if value != "" && slices.Contains(secretSettings, key) {
+				settings[key] = "****"
+			}
+		}
+	}
+}
+
+// parent map of a dotted key like aws.region
+func settingParent(settings map[string]any, key string) (map[string]any, string, error) {
+	path := strings.Split(strings.ToLower(key), ".")
+	parent := settings
+	for _, name := range path[:len(path)-1] {
+		child, ok := parent[name].(map[string]any)
+		if !ok {
+			return nil, "", fmt.Errorf("unknown setting %s: %w", key, errSettingUsage)
+		}
+		parent = child
+	}
+	name := path[len(path)-1]
+	if _, ok := parent[name]; !ok {
+		return nil, "", fmt.Errorf("unknown setting %s: %w", key, errSettingUsage)
+	}
+	return parent, name, nil
+}
+
+func getSettings(app *applicationMain, keys []string) (any, error) {
+	settings, err := settingsMap(app)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		maskSecrets(settings)
+		return settings, nil
+	}
+	if len(keys) > 1 {
+		return nil, fmt.Errorf("settings get takes one key: %w", errSettingUsage)
+	}
+
+	parent, name, err := settingParent(settings, keys[0])
+	if err != nil {
+		return nil, err
+	}
+	if value, ok := parent[name].(string); ok {
+		return value, nil
+	}
+	return parent[name], nil
+}
+
+// key=value pairs, values parsed as the setting's type, JSON for lists and maps
+func setSettings(app *applicationMain, pairs []string) error {
+	if len(pairs) == 0 {
+		return errSettingUsage
+	}
+	settings, err := settingsMap(app)
+	if err != nil {
+		return err
+	}
+
+	for _, pair := range pairs {
+		key, value, found := strings.Cut(pair, "=")
+		if !found {
+			return fmt.Errorf("%s: %w", pair, errSettingUsage)
+		}
+		parent, name, err := settingParent(settings, key)
+		if err != nil {
+			return err
+		}
+		switch parent[name].(type) {
+		case string:
+			parent[name] = value
+		case bool:
+			parent[name], err = strconv.ParseBool(value)
+		case float64:
+			parent[name], err = strconv.ParseFloat(value, 64)
+		default:
+			var parsed any
+			err = json.Unmarshal([]byte(value), &parsed)
+			parent[name] = parsed
+		}
+		if err != nil {
+			return fmt.Errorf("bad value for %s: %s: %w", key, err, errSettingUsage)
+		}
+	}
+
+	raw, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, app)
+}
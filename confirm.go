@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// menu actions that ask to type the batch or region first, unless listed in
+// the SkipConfirm setting
+const (
+	confirmDelete      = "delete"
+	confirmDeleteBoxes = "delete-boxes"
+	confirmScaleDown   = "scale-down"
+	confirmManifest    = "manifest-apply"
+	confirmStop        = "stop" // hibernate too
+	confirmForceUnlock = "force-unlock"
+)
+
+// files and boxes listed by name before the rest are only counted
+const confirmFiles = 8
+
+func needsConfirm(app *applicationMain, action string) bool {
+	return !slices.Contains(app.SkipConfirm, action)
+}
+
+// returned by the jobs planning an action that removes or stops something:
+// what it does, and the job doing it once phrase is typed. An empty phrase
+// means nothing to confirm and run starts right away. result is shown instead
+// when the action could not be planned
+type confirmMsg struct {
+	title   string      // what the action does, over the summary
+	summary [][2]string // label and what happens to it
+	phrase  string
+	run     job
+	// the plan was made on, run gets them whatever the menu's settings are by then
+	settings *applicationMain
+	job      int // id of the planning job, its entry keeps the plan until confirmed
+	result   string
+}
+
+// summary of what goes and a prompt, enter goes ahead once the phrase is typed
+type confirmDialog struct {
+	confirmMsg
+	input    textinput.Model
+	mismatch bool
+}
+
+func newConfirmDialog(msg confirmMsg) confirmDialog {
+	input := textinput.New()
+	input.Placeholder = msg.phrase
+	input.CharLimit = 100
+	input.Width = 50
+	input.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor))
+	input.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textInputColor))
+	input.Focus()
+	return confirmDialog{confirmMsg: msg, input: input}
+}
+
+// confirmed = enter with the phrase typed, cancelled = esc
+func (d *confirmDialog) update(msg tea.KeyMsg) (confirmed, cancelled bool, cmd tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		d.mismatch = d.input.Value() != d.phrase
+		return !d.mismatch, false, nil
+	case "esc":
+		return false, true, nil
+	}
+	d.mismatch = false
+	d.input, cmd = d.input.Update(msg)
+	return false, false, cmd
+}
+
+func (d confirmDialog) view() string {
+	promptStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor)).Bold(true)
+	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor)).Width(13)
+	warnStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(textJobOutcomeFront)).Bold(true)
+	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(textErrorColorFront)).Background(lipgloss.Color(textErrorColorBack))
+	helpText := lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render("enter: go ahead   esc: cancel")
+
+	summary := strings.Builder{}
+	for _, row := range d.summary {
+		summary.WriteString(itemStyle.Render(labelStyle.Render(row[0]) + row[1]))
+		summary.WriteString("\n")
+	}
+
+	prompt := warnStyle.Render(fmt.Sprintf("Type '%s' to go ahead, nothing changes otherwise:", d.phrase))
+	mismatch := ""
+	if d.mismatch {
+		mismatch = "\n" + errorStyle.Render(fmt.Sprintf("That is not '%s'", d.phrase)) + "\n"
+	}
+	return fmt.Sprintf("\n\n%s\n\n%s\n%s\n%s\n%s\n%s",
+		promptStyle.Render(d.title+":"), summary.String(), prompt, d.input.View(), mismatch, helpText)
+}
+
+// what the confirm dialog lists for a delete
+func (p deletePlan) summary() [][2]string {
+	perBatch := []string{}
+	for _, batchT := range slices.Sorted(maps.Keys(p.perBatch)) {
+		perBatch = append(perBatch, fmt.Sprintf("%s %d", batchOrAll(batchT), p.perBatch[batchT]))
+	}
+	boxes := fmt.Sprintf("%d", p.boxes())
+	if len(perBatch) > 0 {
+		boxes = fmt.Sprintf("%s (%s)", boxes, strings.Join(perBatch, ", "))
+	}
+	keyPair := "kept"
+	if p.keyPair != "" {
+		keyPair = fmt.Sprintf("%s deleted with its .pem", p.keyPair)
+	}
+	files := "none"
+	if len(p.files) > 0 {
+		files = fmt.Sprintf("%d in %s: %s", len(p.files), p.folder, shortList(p.files))
+	}
+
+	return [][2]string{
+		{"Boxes", boxes},
+		{"Elastic IPs", fmt.Sprintf("%d released", p.elasticIPs)},
+		{"Key pair", keyPair},
+		{"Firewall", p.firewall},
+		{"Local files", files},
+	}
+}
+
+// the first confirmFiles names, the rest only counted
+func shortList(names []string) string {
+	list := strings.Join(names[:min(len(names), confirmFiles)], ", ")
+	if len(names) > confirmFiles {
+		list += fmt.Sprintf(" and %d more", len(names)-confirmFiles)
+	}
+	return list
+}
+
+// runs direct right away when the action is in SkipConfirm, plan for the
+// operator to confirm otherwise
+func (m *MenuList) startConfirmed(action string, plan, direct job) tea.Cmd {
+	if !needsConfirm(m.app, action) {
+		return m.startJob(direct)
+	}
+	return m.startJob(plan)
+}
+
+func (m *MenuList) startDelete(only boxList) tea.Cmd {
+	action := confirmDelete
+	if only.picked() {
+		action = confirmDeleteBoxes
+	}
+	return m.startConfirmed(action, backgroundJobPlanDelete(only), backgroundJobDeleteBox(only, nil))
+}
+
+// the dialog for a plan, or its job right away when there is nothing to confirm
+func (m *MenuList) openConfirm(msg confirmMsg) tea.Cmd {
+	if msg.phrase == "" {
+		return m.runConfirmed(msg)
+	}
+	m.confirm = newConfirmDialog(msg)
+	m.state = StateConfirm
+	return textinput.Blink
+}
+
+// queues the planned job on the plan's settings, a plan is confirmed once
+func (m *MenuList) runConfirmed(msg confirmMsg) tea.Cmd {
+	run := msg.run
+	if e := m.jobs.entry(msg.job); e != nil {
+		e.confirm = nil
+		e.result = "Confirmed"
+		if msg.phrase != "" {
+			run.plan = &e.job
+		}
+	}
+	return m.startJobOn(msg.settings, run)
+}
+
+func (m *MenuList) updateConfirm(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		// cursor blink
+		var cmd tea.Cmd
+		m.confirm.input, cmd = m.confirm.input.Update(msg)
+		return m, cmd
+	}
+	if keyMsg.String() == "ctrl+c" {
+		return m, tea.Quit
+	}
+
+	confirmed, cancelled, cmd := m.confirm.update(keyMsg)
+	switch {
+	case cancelled:
+		m.state = m.prevMenuState
+		m.updateListItems()
+		return m, nil
+	case confirmed:
+		return m, m.runConfirmed(m.confirm.confirmMsg)
+	}
+	return m, cmd
+}
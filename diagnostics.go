@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+type boxDiagnostics struct {
+	InstanceID     string
+	ConsoleOutput  string
+	ScreenshotFile string
+}
+
+// box can be an instance ID or one of its IPs
+func (a *AWS) findBoxID(client ec2API, box string) (string, error) {
+	ctx := a.jobContext()
+
+	if strings.HasPrefix(box, "i-") {
+		return box, nil
+	}
+
+	for _, filterName := range []string{"ip-address", "private-ip-address"} {
+		resp, err := client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+			Filters: a.ownedFilters(types.Filter{
+				Name:   aws.String(filterName),
+				Values: []string{box},
+			}),
+		})
+		if err != nil {
+			return "", err
+		}
+		for _, reservation := range resp.Reservations {
+			for _, instance := range reservation.Instances {
+				return *instance.InstanceId, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("no AUTO-BOX box found for %s", box)
+}
+
+// console output + screenshot, the screenshot is saved as PNG in the region folder
+func (a *AWS) diagnoseEC2Instance(client ec2API, box string) (boxDiagnostics, error) {
+	ctx := a.jobContext()
+
+	instanceID, err := a.findBoxID(client, box)
+	if err != nil {
+		return boxDiagnostics{}, err
+	}
+	diag := boxDiagnostics{InstanceID: instanceID}
+
+	consoleResp, err := client.GetConsoleOutput(ctx, &ec2.GetConsoleOutputInput{
+		InstanceId: aws.String(instanceID),
+		Latest:     aws.Bool(true),
+	})
+	if err != nil {
+		// Latest is only supported on Nitro instances
+		consoleResp, err = client.GetConsoleOutput(ctx, &ec2.GetConsoleOutputInput{
+			InstanceId: aws.String(instanceID),
+		})
+	}
+	if err != nil {
+		return diag, err
+	}
+	if consoleResp.Output != nil {
+		output, err := base64.StdEncoding.DecodeString(*consoleResp.Output)
+		if err != nil {
+			return diag, err
+		}
+		diag.ConsoleOutput = string(output)
+	}
+
+	screenshotResp, err := client.GetConsoleScreenshot(ctx, &ec2.GetConsoleScreenshotInput{
+		InstanceId: aws.String(instanceID),
+		WakeUp:     aws.Bool(true),
+	})
+	if err != nil {
+		return diag, err
+	}
+	if screenshotResp.ImageData == nil {
+		return diag, nil
+	}
+
+	// EC2 hands back a JPG
+	imageData, err := base64.StdEncoding.DecodeString(*screenshotResp.ImageData)
+	if err != nil {
+		return diag, err
+	}
+	screenshot, err := jpeg.Decode(bytes.NewReader(imageData))
+	if err != nil {
+		return diag, err
+	}
+
+	scriptsFolder := fmt.Sprintf("./%s", a.Region)
+	err = os.MkdirAll(scriptsFolder, 0755)
+	if err != nil {
+		return diag, err
+	}
+	fileName, err := filepath.Abs(filepath.Join(scriptsFolder, fmt.Sprintf("%s-screenshot.png", instanceID)))
+	if err != nil {
+		return diag, err
+	}
+	file, err := os.Create(fileName)
+	if err != nil {
+		return diag, err
+	}
+	defer file.Close()
+	err = png.Encode(file, screenshot)
+	if err != nil {
+		return diag, err
+	}
+	diag.ScreenshotFile = fileName
+
+	return diag, nil
+}
+
+func lastLines(text string, count int) string {
+	lines := strings.Split(strings.TrimRight(strings.ReplaceAll(text, "\r\n", "\n"), "\n"), "\n")
+	if len(lines) > count {
+		lines = lines[len(lines)-count:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestFindBoxID(t *testing.T) {
+	client := newFakeEC2()
+	instanceID := client.addInstance("web", types.InstanceStateNameRunning)
+	instance := client.instance(instanceID)
+
+	tests := []struct {
+		name    string
+		box     string
+		want    string
+		wantErr bool
+	}{
+		{"instance ID", "i-0abc", "i-0abc", false},
+		{"public IP", *instance.PublicIpAddress, instanceID, false},
+		{"private IP", *instance.PrivateIpAddress, instanceID, false},
+		{"unknown IP", "192.0.2.1", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := testAWS().findBoxID(client, tt.box)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %t", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("findBoxID = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiagnoseEC2Instance(t *testing.T) {
+	screenshot := bytes.Buffer{}
+	err := jpeg.Encode(&screenshot, image.NewRGBA(image.Rect(0, 0, 8, 8)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		xenConsole     bool
+		screenshot     bool
+		wantConsole    int
+		wantScreenshot bool
+	}{
+		{"nitro box", false, true, 1, true},
+		{"xen box falls back to the full console", true, true, 2, true},
+		{"no screenshot", false, false, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chdirTemp(t)
+			client := newFakeEC2()
+			instanceID := client.addInstance("web", types.InstanceStateNameRunning)
+			client.xenConsole = tt.xenConsole
+			client.consoleOutput = map[string]string{instanceID: "EC2Launch: Windows is Ready"}
+			if tt.screenshot {
+				client.screenshots = map[string][]byte{instanceID: screenshot.Bytes()}
+			}
+
+			diag, err := testAWS().diagnoseEC2Instance(client, instanceID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diag.ConsoleOutput != "EC2Launch: Windows is Ready" {
+				t.Errorf("console output = %q", diag.ConsoleOutput)
+			}
+			if got := client.callCount("GetConsoleOutput"); got != tt.wantConsole {
+				t.Errorf("GetConsoleOutput called %d times, want %d", got, tt.wantConsole)
+			}
+			if gotScreenshot := diag.ScreenshotFile != ""; gotScreenshot != tt.wantScreenshot {
+				t.Fatalf("screenshot saved = %t, want %t", gotScreenshot, tt.wantScreenshot)
+			}
+			if tt.wantScreenshot {
+				_, err := os.Stat(diag.ScreenshotFile)
+				if err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+}
+
+func TestLastLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		count int
+		want  string
+	}{
+		{"fewer lines", "one\ntwo\n", 5, "one\ntwo"},
+		{"tail", "one\ntwo\nthree\nfour", 2, "three\nfour"},
+		{"windows line endings", "one\r\ntwo\r\nthree\r\n", 2, "two\nthree"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lastLines(tt.text, tt.count); got != tt.want {
+				t.Errorf("lastLines = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	digitalAPI      = "https://api.digitalocean.com/v2"
+	digitalBoxTag   = "AUTO-BOX"
+	digitalFirewall = "fwAutoBox"
+)
+
+// DigitalOcean tags take letters, digits, colons, dashes and underscores
+var digitalTagPattern = regexp.MustCompile(`^[A-Za-z0-9:_-]+$`)
+
+// droplet actions of the POWER menu, droplets can't hibernate
+var digitalPowerActions = map[string]string{
+	"stop":   "power_off",
+	"start":  "power_on",
+	"reboot": "reboot",
+}
+
+// how often a droplet action is checked until it is done
+var digitalActionPoll = 3 * time.Second
+
+type Digital struct {
+	ApiToken string `json:"apitoken"`
+	Region   string `json:"region"`
+	Size     string `json:"size"`
+	Image    string `json:"image"`
+	// Endpoint points the client at a local mock of the API
+	Endpoint string `json:"endpoint"`
+
+	// ctx of the job the settings run for, its calls stop with it
+	ctx context.Context
+}
+
+type digitalDroplet struct {
+	ID       int      `json:"id"`
+	Name     string   `json:"name"`
+	Status   string   `json:"status"`
+	SizeSlug string   `json:"size_slug"`
+	Created  string   `json:"created_at"`
+	Tags     []string `json:"tags"`
+	Networks struct {
+		V4 []struct {
+			IPAddress string `json:"ip_address"`
+			Type      string `json:"type"`
+		} `json:"v4"`
+	} `json:"networks"`
+}
+
+// public IPv4 of the droplet, empty while it is still being created
+func (d digitalDroplet) publicIP() string {
+	for _, network := range d.Networks.V4 {
+		if network.Type == "public" {
+			return network.IPAddress
+		}
+	}
+	return ""
+}
+
+type digitalFirewallRule struct {
+	Protocol     string         `json:"protocol"`
+	Ports        string         `json:"ports"`
+	Sources      map[string]any `json:"sources,omitempty"`
+	Destinations map[string]any `json:"destinations,omitempty"`
+}
+
+type digitalFirewallSpec struct {
+	ID            string                `json:"id,omitempty"`
+	Name          string                `json:"name"`
+	InboundRules  []digitalFirewallRule `json:"inbound_rules"`
+	OutboundRules []digitalFirewallRule `json:"outbound_rules"`
+	Tags          []string              `json:"tags"`
+}
+
+// droplets of batchT carry this tag next to AUTO-BOX, every AUTO-BOX droplet
+// when there is no batch
+func digitalBatchTag(batchT string) (string, error) {
+	if batchT == "" {
+		return digitalBoxTag, nil
+	}
+	tag := "AUTO-BOX-BATCH:" + batchT
+	if !digitalTagPattern.MatchString(tag) {
+		return "", fmt.Errorf("batch %s can only have letters, digits, colons, dashes and underscores on DigitalOcean", batchT)
+	}
+	return tag, nil
+}
+
+// ctx of the job, calls outside one run to the end
+func (d *Digital) jobContext() context.Context {
+	if d.ctx == nil {
+		return context.Background()
+	}
+	return d.ctx
+}
+
+// sends body as JSON and decodes the answer into out, either may be nil
+func (d *Digital) call(method, path string, body, out any) error {
+	ctx, cancel := context.WithTimeout(d.jobContext(), 30*time.Second)
+	defer cancel()
+
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, cmp.Or(d.Endpoint, digitalAPI)+path, payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+d.ApiToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		failure := struct {
+			Message string `json:"message"`
+		}{}
+		json.Unmarshal(data, &failure)
+		return fmt.Errorf("DigitalOcean %s %s: %s %s", method, path, resp.Status, failure.Message)
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// droplets carrying tag, every page of them
+func (d *Digital) taggedDroplets(tag string) ([]digitalDroplet, error) {
+	droplets := []digitalDroplet{}
+	for page := 1; ; page++ {
+		resp := struct {
+			Droplets []digitalDroplet `json:"droplets"`
+			Links    struct {
+				Pages struct {
+					Next string `json:"next"`
+				} `json:"pages"`
+			} `json:"links"`
+		}{}
+		err := d.call(http.MethodGet, fmt.Sprintf("/droplets?tag_name=%s&per_page=200&page=%d", url.QueryEscape(tag), page), nil, &resp)
+		if err != nil {
+			return nil, err
+		}
+		droplets = append(droplets, resp.Droplets...)
+		if resp.Links.Pages.Next == "" {
+			return droplets, nil
+		}
+	}
+}
+
+func (d *Digital) findFirewall() (*digitalFirewallSpec, error) {
+	resp := struct {
+		Firewalls []digitalFirewallSpec `json:"firewalls"`
+	}{}
+	err := d.call(http.MethodGet, "/firewalls?per_page=200", nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+	for _, firewall := range resp.Firewalls {
+		if firewall.Name == digitalFirewall {
+			return &firewall, nil
+		}
+	}
+	return nil, nil
+}
+
+// firewall of every AUTO-BOX droplet, the ports of the AWS security group
+func (d *Digital) createFirewall() error {
+	existing, err := d.findFirewall()
+	if err != nil || existing != nil {
+		return err
+	}
+
+	firewall := digitalFirewallSpec{Name: digitalFirewall, Tags: []string{digitalBoxTag}}
+	for _, rule := range defaultSecurityRules {
+		firewall.InboundRules = append(firewall.InboundRules, digitalFirewallRule{
+			Protocol: rule.Protocol,
+			Ports:    fmt.Sprint(rule.Port),
+			Sources:  map[string]any{"addresses": []string{rule.Cidr}},
+		})
+	}
+	for _, protocol := range []string{"tcp", "udp"} {
+		firewall.OutboundRules = append(firewall.OutboundRules, digitalFirewallRule{
+			Protocol:     protocol,
+			Ports:        "all",
+			Destinations: map[string]any{"addresses": []string{"0.0.0.0/0", "::/0"}},
+		})
+	}
+	return d.call(http.MethodPost, "/firewalls", firewall, nil)
+}
+
+func (d *Digital) deleteFirewall() error {
+	existing, err := d.findFirewall()
+	if err != nil || existing == nil {
+		return err
+	}
+	return d.call(http.MethodDelete, "/firewalls/"+existing.ID, nil, nil)
+}
+
+// launches one droplet into batchT
+func (d *Digital) createBox(batchT string) error {
+	tag, err := digitalBatchTag(batchT)
+	if err != nil {
+		return err
+	}
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	name := fmt.Sprintf("autobox-%s-%s", cmp.Or(strings.ToLower(batchT), "all"), hex.EncodeToString(suffix))
+	name = strings.NewReplacer(":", "-", "_", "-").Replace(name)
+
+	droplet := map[string]any{
+		"name":   name,
+		"region": d.Region,
+		"size":   d.Size,
+		"image":  d.Image,
+		"tags":   []string{digitalBoxTag, tag},
+	}
+	return d.call(http.MethodPost, "/droplets", droplet, nil)
+}
+
+// public IPs of the droplets of batchT, every AUTO-BOX droplet when there is no batch
+func (d *Digital) compileIPaddressesDigital(batchT string) ([]string, error) {
+	tag, err := digitalBatchTag(batchT)
+	if err != nil {
+		return nil, err
+	}
+	droplets, err := d.taggedDroplets(tag)
+	if err != nil {
+		return nil, err
+	}
+	ips := []string{}
+	for _, droplet := range droplets {
+		if ip := droplet.publicIP(); ip != "" {
+			ips = append(ips, ip)
+		}
+	}
+	return ips, nil
+}
+
+// launches or deletes droplets of batchT until it has desired, the newest go
+// first. launched and deleted count what was done before an error
+func (d *Digital) scaleBatch(batchT string, desired int, progress progressFunc) (launched, deleted int, err error) {
+	tag, err := digitalBatchTag(batchT)
+	if err != nil {
+		return 0, 0, err
+	}
+	droplets, err := d.taggedDroplets(tag)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if len(droplets) < desired {
+		err = d.createFirewall()
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	progress.total(max(desired-len(droplets), len(droplets)-desired))
+	for i := range desired - len(droplets) {
+		step := fmt.Sprintf("%s droplet %d", batchT, len(droplets)+i+1)
+		progress.start(step)
+		err = d.createBox(batchT)
+		progress.finish(step, "", err)
+		if err != nil {
+			return launched, 0, err
+		}
+		launched++
+	}
+
+	// created_at is RFC 3339 so string order is time order
+	slices.SortFunc(droplets, func(a, b digitalDroplet) int {
+		return cmp.Or(cmp.Compare(b.Created, a.Created), cmp.Compare(b.ID, a.ID))
+	})
+	for _, droplet := range droplets[:max(len(droplets)-desired, 0)] {
+		progress.start(droplet.Name)
+		err = d.call(http.MethodDelete, fmt.Sprintf("/droplets/%d", droplet.ID), nil, nil)
+		progress.finish(droplet.Name, "deleted", err)
+		if err != nil {
+			return 0, deleted, fmt.Errorf("%s: %w", droplet.Name, err)
+		}
+		deleted++
+	}
+	return launched, deleted, nil
+}
+
+// deletes the droplets of batchT, every AUTO-BOX droplet when there is no batch
+func (d *Digital) deleteBox(batchT string) error {
+	tag, err := digitalBatchTag(batchT)
+	if err != nil {
+		return err
+	}
+	return d.call(http.MethodDelete, "/droplets?tag_name="+url.QueryEscape(tag), nil, nil)
+}
+
+// droplets of batchT the power action applies to: the ones at addresses
+// (public IP or droplet ID) when given, else the active ones, the off ones to start
+func (d *Digital) powerTargets(batchT, action string, addresses []string) ([]digitalDroplet, error) {
+	tag, err := digitalBatchTag(batchT)
+	if err != nil {
+		return nil, err
+	}
+	droplets, err := d.taggedDroplets(tag)
+	if err != nil {
+		return nil, err
+	}
+	status := "active"
+	if action == "start" {
+		status = "off"
+	}
+	targets := []digitalDroplet{}
+	for _, droplet := range droplets {
+		if len(addresses) > 0 {
+			if slices.Contains(addresses, droplet.publicIP()) || slices.Contains(addresses, strconv.Itoa(droplet.ID)) {
+				targets = append(targets, droplet)
+			}
+			continue
+		}
+		if droplet.Status == status {
+			targets = append(targets, droplet)
+		}
+	}
+	return targets, nil
+}
+
+// runs the power action (stop, start or reboot) on each droplet and waits for
+// it, the errors name the droplets they are about. progress gets a step per droplet
+func (d *Digital) powerDroplets(droplets []digitalDroplet, action string, progress progressFunc) error {
+	actionType, ok := digitalPowerActions[action]
+	if !ok {
+		return fmt.Errorf("%s is not available for DigitalOcean, use stop, start or reboot", action)
+	}
+	progress.total(len(droplets))
+	errs := []error{}
+	for _, droplet := range droplets {
+		progress.start(droplet.Name)
+		err := d.dropletAction(droplet.ID, actionType)
+		progress.finish(droplet.Name, action, err)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", droplet.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (d *Digital) dropletAction(dropletID int, actionType string) error {
+	resp := struct {
+		Action struct {
+			ID     int    `json:"id"`
+			Status string `json:"status"`
+		} `json:"action"`
+	}{}
+	err := d.call(http.MethodPost, fmt.Sprintf("/droplets/%d/actions", dropletID), map[string]string{"type": actionType}, &resp)
+	if err != nil {
+		return err
+	}
+	for resp.Action.Status == "in-progress" {
+		select {
+		case <-time.After(digitalActionPoll):
+		case <-d.jobContext().Done():
+			return d.jobContext().Err()
+		}
+		err = d.call(http.MethodGet, fmt.Sprintf("/actions/%d", resp.Action.ID), nil, &resp)
+		if err != nil {
+			return err
+		}
+	}
+	if resp.Action.Status == "errored" {
+		return fmt.Errorf("%s failed", actionType)
+	}
+	return nil
+}
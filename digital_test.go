@@ -0,0 +1,292 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// in-memory DigitalOcean API: droplets by tag and one list of firewalls
+type fakeDigital struct {
+	mu        sync.Mutex
+	droplets  []map[string]any
+	firewalls []map[string]any
+	nextID    int
+	requests  []string
+	actions   []string // droplet id and action type
+}
+
+func newFakeDigital(t *testing.T) (*fakeDigital, *Digital) {
+	t.Helper()
+
+	fake := &fakeDigital{}
+	server := httptest.NewServer(http.HandlerFunc(fake.serve))
+	t.Cleanup(server.Close)
+	return fake, &Digital{ApiToken: "token", Region: "nyc3", Size: "s-1vcpu-1gb", Image: "ubuntu-24-04-x64", Endpoint: server.URL}
+}
+
+func dropletTags(droplet map[string]any) []string {
+	tags := []string{}
+	for _, tag := range droplet["tags"].([]any) {
+		tags = append(tags, tag.(string))
+	}
+	return tags
+}
+
+func (f *fakeDigital) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Unable to authenticate you"})
+		return
+	}
+	body := map[string]any{}
+	json.NewDecoder(r.Body).Decode(&body)
+	tag := r.URL.Query().Get("tag_name")
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/droplets":
+		droplets := []map[string]any{}
+		for _, droplet := range f.droplets {
+			if slices.Contains(dropletTags(droplet), tag) {
+				droplets = append(droplets, droplet)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"droplets": droplets})
+	case r.Method == http.MethodPost && r.URL.Path == "/droplets":
+		f.nextID++
+		body["id"] = f.nextID
+		body["status"] = "active"
+		body["size_slug"] = body["size"]
+		body["created_at"] = time.Date(2026, 1, 1, 0, 0, f.nextID, 0, time.UTC).Format(time.RFC3339)
+		body["networks"] = map[string]any{"v4": []map[string]any{
+			{"ip_address": "10.10.0." + strings.Repeat("1", f.nextID), "type": "private"},
+			{"ip_address": "203.0.113." + strings.Repeat("1", f.nextID), "type": "public"},
+		}}
+		f.droplets = append(f.droplets, body)
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]any{"droplet": body})
+	case r.Method == http.MethodDelete && r.URL.Path == "/droplets":
+		f.droplets = slices.DeleteFunc(f.droplets, func(droplet map[string]any) bool {
+			return slices.Contains(dropletTags(droplet), tag)
+		})
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/droplets/"):
+		f.droplets = slices.DeleteFunc(f.droplets, func(droplet map[string]any) bool {
+			return fmt.Sprint(droplet["id"]) == strings.TrimPrefix(r.URL.Path, "/droplets/")
+		})
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/droplets/") && strings.HasSuffix(r.URL.Path, "/actions"):
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/droplets/"), "/actions")
+		status := map[any]string{"power_off": "off", "power_on": "active", "reboot": "active"}[body["type"]]
+		for _, droplet := range f.droplets {
+			if fmt.Sprint(droplet["id"]) == id && status != "" {
+				droplet["status"] = status
+				f.actions = append(f.actions, id+" "+body["type"].(string))
+			}
+		}
+		// the first action stays in progress until checked once
+		actionStatus := "completed"
+		if len(f.actions) == 1 {
+			actionStatus = "in-progress"
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]any{"action": map[string]any{"id": len(f.actions), "status": actionStatus}})
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/actions/"):
+		json.NewEncoder(w).Encode(map[string]any{"action": map[string]any{"id": 1, "status": "completed"}})
+	case r.Method == http.MethodGet && r.URL.Path == "/firewalls":
+		json.NewEncoder(w).Encode(map[string]any{"firewalls": f.firewalls})
+	case r.Method == http.MethodPost && r.URL.Path == "/firewalls":
+		body["id"] = "fw-1"
+		f.firewalls = append(f.firewalls, body)
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/firewalls/"):
+		f.firewalls = slices.DeleteFunc(f.firewalls, func(firewall map[string]any) bool {
+			return firewall["id"] == strings.TrimPrefix(r.URL.Path, "/firewalls/")
+		})
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "not found"})
+	}
+}
+
+func TestDigitalBatches(t *testing.T) {
+	fake, d := newFakeDigital(t)
+
+	for _, batchT := range []string{"web", "web", "db"} {
+		if err := d.createBox(batchT); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, droplet := range fake.droplets {
+		if !strings.HasPrefix(droplet["name"].(string), "autobox-") || !slices.Contains(dropletTags(droplet), digitalBoxTag) {
+			t.Errorf("droplet %v is not an AUTO-BOX droplet", droplet)
+		}
+	}
+
+	web, err := d.compileIPaddressesDigital("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(web, []string{"203.0.113.1", "203.0.113.11"}) {
+		t.Errorf("batch web = %v, want the public IPs of its 2 droplets", web)
+	}
+	all, err := d.compileIPaddressesDigital("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 {
+		t.Errorf("no batch = %v, want every droplet", all)
+	}
+
+	if err := d.deleteBox("web"); err != nil {
+		t.Fatal(err)
+	}
+	all, _ = d.compileIPaddressesDigital("")
+	if !slices.Equal(all, []string{"203.0.113.111"}) {
+		t.Errorf("after deleting web = %v, want the db droplet only", all)
+	}
+}
+
+func TestDigitalBatchTag(t *testing.T) {
+	tests := []struct {
+		batchT  string
+		want    string
+		wantErr bool
+	}{
+		{"", "AUTO-BOX", false},
+		{"web", "AUTO-BOX-BATCH:web", false},
+		{"web_prod-2", "AUTO-BOX-BATCH:web_prod-2", false},
+		{"web prod", "", true},
+		{"web/prod", "", true},
+	}
+	for _, tt := range tests {
+		got, err := digitalBatchTag(tt.batchT)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("digitalBatchTag(%q) = %q, %v, want %q, error %v", tt.batchT, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestDigitalFirewall(t *testing.T) {
+	fake, d := newFakeDigital(t)
+
+	for range 2 {
+		if err := d.createFirewall(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(fake.firewalls) != 1 {
+		t.Fatalf("got %d firewalls, want the existing one reused", len(fake.firewalls))
+	}
+	if len(fake.firewalls[0]["inbound_rules"].([]any)) != len(defaultSecurityRules) {
+		t.Errorf("inbound rules = %v, want the ports of the security group", fake.firewalls[0]["inbound_rules"])
+	}
+
+	if err := d.deleteFirewall(); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.firewalls) != 0 {
+		t.Errorf("firewall not deleted: %v", fake.firewalls)
+	}
+	// nothing left to delete
+	if err := d.deleteFirewall(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDigitalPower(t *testing.T) {
+	fake, d := newFakeDigital(t)
+	poll := digitalActionPoll
+	digitalActionPoll = time.Millisecond
+	t.Cleanup(func() { digitalActionPoll = poll })
+	for _, batchT := range []string{"web", "web", "db"} {
+		if err := d.createBox(batchT); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stopping, err := d.powerTargets("web", "stop", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stopping) != 2 {
+		t.Fatalf("stop takes down %d droplets, want the 2 of web", len(stopping))
+	}
+	if err := d.powerDroplets(stopping, "stop", nil); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(fake.actions, []string{"1 power_off", "2 power_off"}) {
+		t.Errorf("actions = %v, want web powered off", fake.actions)
+	}
+
+	// stopped droplets are the ones to start, picked ones only when given
+	starting, _ := d.powerTargets("web", "start", nil)
+	if len(starting) != 2 {
+		t.Errorf("start brings up %d droplets, want the 2 stopped", len(starting))
+	}
+	picked, _ := d.powerTargets("web", "reboot", []string{"203.0.113.11"})
+	if len(picked) != 1 || picked[0].ID != 2 {
+		t.Errorf("picked %+v, want the droplet at 203.0.113.11", picked)
+	}
+
+	if err := d.powerDroplets(picked, "hibernate", nil); err == nil {
+		t.Error("droplet hibernated")
+	}
+}
+
+func TestDigitalScaleBatch(t *testing.T) {
+	fake, d := newFakeDigital(t)
+	for _, batchT := range []string{"web", "db"} {
+		if err := d.createBox(batchT); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	launched, deleted, err := d.scaleBatch("web", 3, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if launched != 2 || deleted != 0 {
+		t.Errorf("scaling up launched %d, deleted %d, want 2 launched", launched, deleted)
+	}
+	if len(fake.firewalls) != 1 {
+		t.Errorf("%d firewalls after scaling up, want the shared one", len(fake.firewalls))
+	}
+
+	// the newest droplets go first, the other batch is left alone
+	launched, deleted, err = d.scaleBatch("web", 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if launched != 0 || deleted != 2 {
+		t.Errorf("scaling down launched %d, deleted %d, want 2 deleted", launched, deleted)
+	}
+	ids := []string{}
+	for _, droplet := range fake.droplets {
+		ids = append(ids, fmt.Sprint(droplet["id"]))
+	}
+	if !slices.Equal(ids, []string{"1", "2"}) {
+		t.Errorf("droplets left %v, want the first web and db", ids)
+	}
+}
+
+func TestDigitalAPIError(t *testing.T) {
+	_, d := newFakeDigital(t)
+	d.ApiToken = "wrong"
+
+	_, err := d.compileIPaddressesDigital("web")
+	if err == nil || !strings.Contains(err.Error(), "Unable to authenticate you") {
+		t.Errorf("got %v, want the API's message", err)
+	}
+}
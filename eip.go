@@ -0,0 +1,137 @@
+package main
+
+import (
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// gives every box a tagged Elastic IP so its address survives stop/start
+func (a *AWS) allocateElasticIPs(client ec2API, instanceIDs []string, batchT string) ([]EC2InstanceIP, error) {
+	ctx := a.jobContext()
+
+	// an instance has to be running before an address can be associated
+	waiter := ec2.NewInstanceRunningWaiter(client)
+	resp, err := waiter.WaitForOutput(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: instanceIDs,
+	}, stateWaitTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	boxes := []EC2InstanceIP{}
+	for _, reservation := range resp.Reservations {
+		for _, instance := range reservation.Instances {
+			address, err := client.AllocateAddress(ctx, &ec2.AllocateAddressInput{
+				Domain: types.DomainTypeVpc,
+				TagSpecifications: []types.TagSpecification{
+					{
+						ResourceType: types.ResourceTypeElasticIp,
+						Tags: a.ownedTags(
+							types.Tag{
+								Key:   aws.String("BatchTag"),
+								Value: aws.String(batchT),
+							},
+							types.Tag{
+								Key:   aws.String("Name"),
+								Value: instance.InstanceId,
+							},
+						),
+					},
+				},
+			})
+			if err != nil {
+				return boxes, err
+			}
+
+			_, err = client.AssociateAddress(ctx, &ec2.AssociateAddressInput{
+				AllocationId: address.AllocationId,
+				InstanceId:   instance.InstanceId,
+			})
+			if err != nil {
+				return boxes, err
+			}
+
+			box := instanceIPs(instance)
+			box.PublicIP = *address.PublicIp
+			boxes = append(boxes, box)
+		}
+	}
+
+	return boxes, nil
+}
+
+func (a *AWS) taggedElasticIPs(client ec2API, batchT string) ([]types.Address, error) {
+	ctx := a.jobContext()
+
+	filters := a.ownedFilters()
+	if batchT != "" {
+		filters = append(filters, types.Filter{
+			Name:   aws.String("tag:BatchTag"),
+			Values: []string{batchT},
+		})
+	}
+
+	resp, err := client.DescribeAddresses(ctx, &ec2.DescribeAddressesInput{
+		Filters: filters,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Addresses, nil
+}
+
+// releases the batch's Elastic IPs, only the ones of instanceIDs when given
+func (a *AWS) releaseElasticIPs(client ec2API, batchT string, instanceIDs []string) error {
+	ctx := a.jobContext()
+	if len(instanceIDs) == 0 {
+		if err := a.checkDeleteAll(batchT); err != nil {
+			return err
+		}
+	}
+
+	addresses, err := a.taggedElasticIPs(client, batchT)
+	if err != nil {
+		return err
+	}
+
+	for _, address := range addresses {
+		if len(instanceIDs) > 0 && !slices.Contains(instanceIDs, aws.ToString(address.InstanceId)) {
+			continue
+		}
+		if address.AssociationId != nil {
+			_, err := client.DisassociateAddress(ctx, &ec2.DisassociateAddressInput{
+				AssociationId: address.AssociationId,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		_, err := client.ReleaseAddress(ctx, &ec2.ReleaseAddressInput{
+			AllocationId: address.AllocationId,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// AUTO-BOX Elastic IPs not attached to anything, these are still billed
+func (a *AWS) idleElasticIPs(client ec2API) ([]string, error) {
+	addresses, err := a.taggedElasticIPs(client, "")
+	if err != nil {
+		return nil, err
+	}
+
+	idle := []string{}
+	for _, address := range addresses {
+		if address.AssociationId == nil {
+			idle = append(idle, aws.ToString(address.PublicIp))
+		}
+	}
+	return idle, nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestAllocateElasticIPs(t *testing.T) {
+	client := newFakeEC2()
+	first := client.addInstance("web", types.InstanceStateNameRunning)
+	second := client.addInstance("web", types.InstanceStateNameRunning)
+
+	boxes, err := testAWS().allocateElasticIPs(client, []string{first, second}, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(boxes) != 2 || len(client.addresses) != 2 {
+		t.Fatalf("%d boxes and %d addresses, want 2 each", len(boxes), len(client.addresses))
+	}
+	for i, box := range boxes {
+		address := client.addresses[i]
+		if box.PublicIP != aws.ToString(address.PublicIp) || aws.ToString(address.InstanceId) != box.InstanceID {
+			t.Errorf("box %+v not associated with %s", box, aws.ToString(address.PublicIp))
+		}
+		if batchT, _ := tagLookup(address.Tags, "BatchTag"); batchT != "web" {
+			t.Errorf("address tagged with batch %q, want web", batchT)
+		}
+	}
+}
+
+func TestReleaseElasticIPs(t *testing.T) {
+	tests := []struct {
+		name        string
+		workspace   string
+		batchT      string
+		instanceIDs func(web []string) []string
+		wantLeft    []string
+		wantErr     bool
+	}{
+		{"whole batch", "", "web", func(web []string) []string { return nil }, []string{"db", "idle"}, false},
+		{"only the given boxes", "", "web", func(web []string) []string { return web[:1] }, []string{"web", "db", "idle"}, false},
+		{"every batch of the workspace", "alice", "", func(web []string) []string { return nil }, nil, false},
+		{"every batch needs a workspace", "", "", func(web []string) []string { return nil }, []string{"web", "web", "db", "idle"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeEC2()
+			a := testAWS()
+			a.Workspace = tt.workspace
+			web := []string{
+				client.addInstance("web", types.InstanceStateNameRunning),
+				client.addInstance("web", types.InstanceStateNameRunning),
+			}
+			db := client.addInstance("db", types.InstanceStateNameRunning)
+			_, err := a.allocateElasticIPs(client, web, "web")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = a.allocateElasticIPs(client, []string{db}, "db")
+			if err != nil {
+				t.Fatal(err)
+			}
+			// allocated but never associated
+			client.addresses = append(client.addresses, types.Address{
+				AllocationId: aws.String("eipalloc-idle"),
+				PublicIp:     aws.String("3.0.0.99"),
+				Tags:         a.ownedTags(types.Tag{Key: aws.String("BatchTag"), Value: aws.String("idle")}),
+			})
+
+			err = a.releaseElasticIPs(client, tt.batchT, tt.instanceIDs(web))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %t", err, tt.wantErr)
+			}
+			var left []string
+			for _, address := range client.addresses {
+				batchT, _ := tagLookup(address.Tags, "BatchTag")
+				left = append(left, batchT)
+			}
+			if !slices.Equal(left, tt.wantLeft) {
+				t.Errorf("addresses left %v, want %v", left, tt.wantLeft)
+			}
+		})
+	}
+}
+
+func TestIdleElasticIPs(t *testing.T) {
+	client := newFakeEC2()
+	a := testAWS()
+	instanceID := client.addInstance("web", types.InstanceStateNameRunning)
+	_, err := a.allocateElasticIPs(client, []string{instanceID}, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.addresses = append(client.addresses,
+		types.Address{
+			AllocationId: aws.String("eipalloc-idle"),
+			PublicIp:     aws.String("3.0.0.99"),
+			Tags:         []types.Tag{{Key: aws.String("AUTO-BOX"), Value: aws.String("true")}},
+		},
+		types.Address{
+			AllocationId: aws.String("eipalloc-notours"),
+			PublicIp:     aws.String("3.0.0.100"),
+		},
+	)
+
+	idle, err := a.idleElasticIPs(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(idle, []string{"3.0.0.99"}) {
+		t.Errorf("idleElasticIPs = %v, want [3.0.0.99]", idle)
+	}
+}
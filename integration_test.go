@@ -0,0 +1,156 @@
+//go:build integration
+
+// Integration tests against a local AWS emulator (LocalStack or moto):
+//
+//	docker run -d -p 4566:4566 localstack/localstack
+//	AUTOBOX_TEST_ENDPOINT=http://localhost:4566 go test -tags integration ./aws/
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+)
+
+func integrationAWS(t *testing.T) *AWS {
+	t.Helper()
+
+	endpoint := os.Getenv("AUTOBOX_TEST_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("AUTOBOX_TEST_ENDPOINT not set, skipping emulator tests")
+	}
+	amiID := os.Getenv("AUTOBOX_TEST_AMI")
+	if amiID == "" {
+		// one of the images the emulators ship with
+		amiID = "ami-03cf127a"
+	}
+
+	chdirTemp(t)
+
+	return &AWS{
+		Region:         "us-east-1",
+		PemKeyFileName: "autobox-integration",
+		Workspace:      "integration",
+		AmiID:          amiID,
+		InstanceType:   "t3.micro",
+		Key:            "test",
+		Secret:         "test",
+		Endpoint:       endpoint,
+	}
+}
+
+func integrationClient(t *testing.T, a *AWS) *ec2.Client {
+	t.Helper()
+
+	client, err := a.createEc2Client()
+	if err != nil {
+		t.Fatalf("createEc2Client: %s", err)
+	}
+	return client
+}
+
+func TestIntegrationPEMFile(t *testing.T) {
+	a := integrationAWS(t)
+	client := integrationClient(t, a)
+
+	err := a.createPEMFile(client)
+	if err != nil {
+		t.Fatalf("createPEMFile: %s", err)
+	}
+	t.Cleanup(func() { a.deletePEMFile(client) })
+
+	_, err = os.Stat(filepath.Join(a.Region, a.keyPairName()+".pem"))
+	if err != nil {
+		t.Fatalf("PEM file not written: %s", err)
+	}
+
+	// second call finds the existing key pair
+	err = a.createPEMFile(client)
+	if err != nil {
+		t.Fatalf("createPEMFile on existing key: %s", err)
+	}
+	_, err = a.loadPEMKey()
+	if err != nil {
+		t.Fatalf("loadPEMKey: %s", err)
+	}
+}
+
+func TestIntegrationSecurityGroup(t *testing.T) {
+	a := integrationAWS(t)
+	client := integrationClient(t, a)
+
+	sgID, err := a.createSecurityGroup("sgAutoBoxIntegration", "integration test", client)
+	if err != nil {
+		t.Fatalf("createSecurityGroup: %s", err)
+	}
+	again, err := a.createSecurityGroup("sgAutoBoxIntegration", "integration test", client)
+	if err != nil {
+		t.Fatalf("createSecurityGroup on existing group: %s", err)
+	}
+	if again != sgID {
+		t.Errorf("existing group returned %s, want %s", again, sgID)
+	}
+}
+
+func TestIntegrationDeployDelete(t *testing.T) {
+	a := integrationAWS(t)
+	client := integrationClient(t, a)
+
+	err := a.createPEMFile(client)
+	if err != nil {
+		t.Fatalf("createPEMFile: %s", err)
+	}
+	t.Cleanup(func() { a.deletePEMFile(client) })
+	sgID, err := a.createSecurityGroup("sgAutoBox", "integration test", client)
+	if err != nil {
+		t.Fatalf("createSecurityGroup: %s", err)
+	}
+
+	for _, batchT := range []string{"it-one", "it-one", "it-two"} {
+		_, err := a.createEC2Instance(sgID, client, batchT, "")
+		if err != nil {
+			t.Fatalf("createEC2Instance %s: %s", batchT, err)
+		}
+	}
+
+	one, err := a.batchInstanceIDs(client, "it-one", "pending", "running")
+	if err != nil {
+		t.Fatalf("batchInstanceIDs: %s", err)
+	}
+	if len(one) != 2 {
+		t.Errorf("batch it-one has %d boxes, want 2", len(one))
+	}
+	_, boxes, err := a.compileIPaddressesAws(client, "it-two")
+	if err != nil {
+		t.Fatalf("compileIPaddressesAws: %s", err)
+	}
+	if len(boxes) != 1 {
+		t.Errorf("batch it-two has %d boxes, want 1", len(boxes))
+	}
+
+	err = a.deleteEC2Instances(client, "it-one")
+	if err != nil {
+		t.Fatalf("deleteEC2Instances it-one: %s", err)
+	}
+	left, err := a.batchInstanceIDs(client, "", "pending", "running")
+	if err != nil {
+		t.Fatalf("batchInstanceIDs: %s", err)
+	}
+	if len(left) != 1 {
+		t.Errorf("%d boxes left after deleting it-one, want 1", len(left))
+	}
+
+	err = a.deleteEC2Instances(client, "")
+	if err != nil {
+		t.Fatalf("deleteEC2Instances all: %s", err)
+	}
+	left, err = a.batchInstanceIDs(client, "", "pending", "running")
+	if err != nil {
+		t.Fatalf("batchInstanceIDs: %s", err)
+	}
+	if len(left) != 0 {
+		t.Errorf("%d boxes left after deleting all, want 0", len(left))
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// a live AUTO-BOX box as the inventory screen lists it
+type inventoryBox struct {
+	EC2InstanceIP
+	Batch          string
+	State          string
+	InstanceType   string
+	Zone           string
+	ImageID        string
+	KeyName        string
+	Launched       time.Time
+	SecurityGroups []string
+	Tags           map[string]string
+}
+
+// live boxes of every batch in the region, in the workspace when one is set
+func (a *AWS) inventory(client ec2API) ([]inventoryBox, error) {
+	instances, err := a.batchInstances(client, "", "pending", "running", "stopping", "stopped")
+	if err != nil {
+		return nil, err
+	}
+
+	boxes := []inventoryBox{}
+	for _, instance := range instances {
+		box := inventoryBox{
+			EC2InstanceIP: instanceIPs(instance),
+			Batch:         instanceTag(instance, "BatchTag"),
+			State:         string(instance.State.Name),
+			InstanceType:  string(instance.InstanceType),
+			ImageID:       aws.ToString(instance.ImageId),
+			KeyName:       aws.ToString(instance.KeyName),
+			Launched:      aws.ToTime(instance.LaunchTime),
+			Tags:          map[string]string{},
+		}
+		if instance.Placement != nil {
+			box.Zone = aws.ToString(instance.Placement.AvailabilityZone)
+		}
+		for _, group := range instance.SecurityGroups {
+			box.SecurityGroups = append(box.SecurityGroups, aws.ToString(group.GroupName))
+		}
+		for _, tag := range instance.Tags {
+			box.Tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+		boxes = append(boxes, box)
+	}
+	return boxes, nil
+}
+
+// time since the box last started, stopped boxes are not up
+func (b inventoryBox) uptime(now time.Time) time.Duration {
+	if b.State != "running" || b.Launched.IsZero() {
+		return 0
+	}
+	return now.Sub(b.Launched)
+}
+
+// what the box cost since it last started at the HourlyRates of its type,
+// false when no rate is set for it
+func (a *AWS) boxCost(box inventoryBox, now time.Time) (float64, bool) {
+	rate, ok := a.HourlyRates[box.InstanceType]
+	if !ok {
+		return 0, false
+	}
+	return rate * box.uptime(now).Hours(), true
+}
+
+// HourlyRates from "t3.micro=0.0104 t3.medium=0.0416", none when blank
+func parseHourlyRates(input string) (map[string]float64, error) {
+	rates := map[string]float64{}
+	for _, field := range strings.Fields(input) {
+		instanceType, price, found := strings.Cut(field, "=")
+		rate, err := strconv.ParseFloat(price, 64)
+		if !found || instanceType == "" || err != nil || rate < 0 {
+			return nil, fmt.Errorf("%s is not TYPE=PRICE, e.g. t3.micro=0.0104", field)
+		}
+		rates[instanceType] = rate
+	}
+	return rates, nil
+}
+
+// HourlyRates the way parseHourlyRates takes them
+func formatHourlyRates(rates map[string]float64) string {
+	fields := []string{}
+	for _, instanceType := range slices.Sorted(maps.Keys(rates)) {
+		fields = append(fields, instanceType+"="+strconv.FormatFloat(rates[instanceType], 'f', -1, 64))
+	}
+	return strings.Join(fields, " ")
+}
+
+// tag keys of the box, sorted for the detail pane
+func (b inventoryBox) tagKeys() []string {
+	return slices.Sorted(maps.Keys(b.Tags))
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestInventory(t *testing.T) {
+	client := newFakeEC2()
+	web := client.addInstance("web", types.InstanceStateNameRunning, "Name", "web-1")
+	db := client.addInstance("db", types.InstanceStateNameStopped)
+	client.addInstance("web", types.InstanceStateNameTerminated)
+	client.instance(web).Placement = &types.Placement{AvailabilityZone: aws.String("us-east-1a")}
+	client.instance(web).SecurityGroups = []types.GroupIdentifier{{GroupName: aws.String("sgAutoBox")}}
+
+	boxes, err := testAWS().inventory(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []string{}
+	for _, box := range boxes {
+		ids = append(ids, box.InstanceID)
+	}
+	if !slices.Equal(ids, []string{web, db}) {
+		t.Fatalf("inventory = %v, want the live boxes %v", ids, []string{web, db})
+	}
+
+	box := boxes[0]
+	if box.Batch != "web" || box.State != "running" || box.InstanceType != "t3.micro" || box.Zone != "us-east-1a" {
+		t.Errorf("box = %+v", box)
+	}
+	if box.PublicIP == "" || box.PrivateIP == "" || box.Launched.IsZero() {
+		t.Errorf("box addresses or launch time missing: %+v", box)
+	}
+	if !slices.Equal(box.SecurityGroups, []string{"sgAutoBox"}) {
+		t.Errorf("security groups = %v", box.SecurityGroups)
+	}
+	if !slices.Equal(box.tagKeys(), []string{"AUTO-BOX", "BatchTag", "Name"}) {
+		t.Errorf("tag keys = %v", box.tagKeys())
+	}
+}
+
+func TestBoxCost(t *testing.T) {
+	launched := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := launched.Add(10 * time.Hour)
+	a := testAWS()
+	a.HourlyRates = map[string]float64{"t3.micro": 0.01}
+
+	tests := []struct {
+		name       string
+		box        inventoryBox
+		wantCost   float64
+		wantPriced bool
+	}{
+		{"running", inventoryBox{State: "running", InstanceType: "t3.micro", Launched: launched}, 0.1, true},
+		{"stopped is not up", inventoryBox{State: "stopped", InstanceType: "t3.micro", Launched: launched}, 0, true},
+		{"no rate for the type", inventoryBox{State: "running", InstanceType: "m5.large", Launched: launched}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cost, priced := a.boxCost(tt.box, now)
+			if priced != tt.wantPriced || cost < tt.wantCost-1e-9 || cost > tt.wantCost+1e-9 {
+				t.Errorf("boxCost = %v, %t, want %v, %t", cost, priced, tt.wantCost, tt.wantPriced)
+			}
+		})
+	}
+}
+
+func TestParseHourlyRates(t *testing.T) {
+	rates, err := parseHourlyRates(" t3.micro=0.0104  t3.medium=0.0416 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rates["t3.micro"] != 0.0104 || rates["t3.medium"] != 0.0416 {
+		t.Errorf("rates = %v", rates)
+	}
+	if got := formatHourlyRates(rates); got != "t3.medium=0.0416 t3.micro=0.0104" {
+		t.Errorf("formatHourlyRates = %q", got)
+	}
+
+	rates, err = parseHourlyRates("")
+	if err != nil || len(rates) != 0 {
+		t.Errorf("blank = %v, %v, want no rates", rates, err)
+	}
+	for _, input := range []string{"t3.micro", "t3.micro=cheap", "=0.01", "t3.micro=-1"} {
+		if _, err := parseHourlyRates(input); err == nil {
+			t.Errorf("parseHourlyRates(%q) took it", input)
+		}
+	}
+}
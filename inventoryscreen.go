@@ -0,0 +1,517 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// the table lists the boxes again this often while it is on screen
+const inventoryRefresh = 30 * time.Second
+
+const inventoryHeight = 12
+
+// columns of the inventory table, sortBy indexes them
+var inventoryColumns = []table.Column{
+	{Title: "ID", Width: 22},
+	{Title: "Batch", Width: 12},
+	{Title: "State", Width: 9},
+	{Title: "Type", Width: 11},
+	{Title: "Public IP", Width: 15},
+	{Title: "Private IP", Width: 15},
+	{Title: "Uptime", Width: 8},
+	{Title: "Cost", Width: 8},
+}
+
+// a box with the uptime and cost it had when listed
+type inventoryRow struct {
+	box    inventoryBox
+	uptime time.Duration
+	cost   float64
+	priced bool
+}
+
+// returned by the listing, gen tells the current listing from a stale one
+type inventoryMsg struct {
+	gen  int
+	rows []inventoryRow
+	err  error
+	at   time.Time
+}
+
+// time to list again, dropped when gen is not the current listing
+type inventoryTickMsg struct {
+	gen int
+}
+
+// kept between visits so sorting and the batch filter stay as they were
+type inventoryScreen struct {
+	table     table.Model
+	rows      []inventoryRow // as listed
+	shown     []inventoryRow // filtered and sorted, as in the table
+	sortBy    int
+	desc      bool
+	filter    textinput.Model
+	filtering bool
+	gen       int
+	loading   bool
+	updated   time.Time
+	err       error
+	marked    map[string]bool // by rowKey
+}
+
+func newInventoryScreen() inventoryScreen {
+	styles := table.DefaultStyles()
+	styles.Header = styles.Header.Foreground(lipgloss.Color(textPromptColor)).
+		BorderStyle(lipgloss.NormalBorder()).BorderBottom(true).BorderForeground(lipgloss.Color("241"))
+	styles.Selected = styles.Selected.Foreground(lipgloss.Color("170"))
+
+	filter := textinput.New()
+	filter.Prompt = "batch: "
+	filter.Placeholder = "part of a batch tag"
+	filter.CharLimit = 50
+	filter.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor))
+	filter.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textInputColor))
+
+	// letters are for the actions, paging keeps its other keys
+	keys := table.DefaultKeyMap()
+	keys.PageUp = key.NewBinding(key.WithKeys("pgup"))
+	keys.PageDown = key.NewBinding(key.WithKeys("pgdown"))
+	keys.HalfPageUp = key.NewBinding(key.WithKeys("ctrl+u"))
+	keys.HalfPageDown = key.NewBinding(key.WithKeys("ctrl+d"))
+
+	return inventoryScreen{
+		table: table.New(
+			table.WithColumns(inventoryColumns),
+			table.WithHeight(inventoryHeight),
+			table.WithFocused(true),
+			table.WithStyles(styles),
+			table.WithKeyMap(keys),
+		),
+		filter: filter,
+		marked: map[string]bool{},
+	}
+}
+
+// DigitalOcean boxes have no ID, they go by address
+func rowKey(row inventoryRow) string {
+	return cmp.Or(row.box.InstanceID, row.box.PublicIP)
+}
+
+// lists the boxes off the bubbletea goroutine on a copy of the settings
+func (s *inventoryScreen) load(app *applicationMain) tea.Cmd {
+	s.gen++
+	s.loading = true
+	gen, snapshot := s.gen, snapshotApp(app)
+	return func() tea.Msg {
+		now := time.Now()
+		rows, err := listInventory(snapshot, now)
+		return inventoryMsg{gen: gen, rows: rows, err: err, at: now}
+	}
+}
+
+// takes the current listing and schedules the next one
+func (s *inventoryScreen) listed(msg inventoryMsg) tea.Cmd {
+	if msg.gen != s.gen {
+		return nil
+	}
+	s.loading = false
+	s.updated, s.err = msg.at, msg.err
+	if msg.err == nil {
+		s.rows = msg.rows
+		// marks of boxes that are gone go with them
+		listed := map[string]bool{}
+		for _, row := range s.rows {
+			listed[rowKey(row)] = true
+		}
+		maps.DeleteFunc(s.marked, func(key string, _ bool) bool {
+			return !listed[key]
+		})
+	}
+	s.apply()
+	return tea.Tick(inventoryRefresh, func(time.Time) tea.Msg {
+		return inventoryTickMsg{gen: msg.gen}
+	})
+}
+
+// filters and sorts the rows into the table, the cursor stays on its box
+func (s *inventoryScreen) apply() {
+	selected := ""
+	if row, ok := s.selected(); ok {
+		selected = rowKey(row)
+	}
+
+	batch := strings.ToLower(strings.TrimSpace(s.filter.Value()))
+	s.shown = []inventoryRow{}
+	for _, row := range s.rows {
+		if strings.Contains(strings.ToLower(row.box.Batch), batch) {
+			s.shown = append(s.shown, row)
+		}
+	}
+	slices.SortStableFunc(s.shown, func(a, b inventoryRow) int {
+		if s.desc {
+			return compareInventory(b, a, s.sortBy)
+		}
+		return compareInventory(a, b, s.sortBy)
+	})
+
+	columns := slices.Clone(inventoryColumns)
+	mark := "▲"
+	if s.desc {
+		mark = "▼"
+	}
+	columns[s.sortBy].Title += " " + mark
+	s.table.SetColumns(columns)
+
+	rows := []table.Row{}
+	cursor := 0
+	for i, row := range s.shown {
+		if rowKey(row) == selected {
+			cursor = i
+		}
+		mark := "  "
+		if s.marked[rowKey(row)] {
+			mark = "✓ "
+		}
+		rows = append(rows, table.Row{
+			mark + cmp.Or(row.box.InstanceID, "-"),
+			row.box.Batch,
+			cmp.Or(row.box.State, "-"),
+			cmp.Or(row.box.InstanceType, "-"),
+			cmp.Or(row.box.PublicIP, "-"),
+			cmp.Or(row.box.PrivateIP, "-"),
+			formatUptime(row.uptime),
+			formatCost(row),
+		})
+	}
+	s.table.SetRows(rows)
+	s.table.SetCursor(cursor)
+}
+
+func (s inventoryScreen) selected() (inventoryRow, bool) {
+	cursor := s.table.Cursor()
+	if cursor < 0 || cursor >= len(s.shown) {
+		return inventoryRow{}, false
+	}
+	return s.shown[cursor], true
+}
+
+// marked boxes the filter shows
+func (s inventoryScreen) markedShown() boxList {
+	marked := boxList{}
+	for _, row := range s.shown {
+		if s.marked[rowKey(row)] {
+			marked = append(marked, rowKey(row))
+		}
+	}
+	return marked
+}
+
+// marked boxes as shown, or the one under the cursor when none are marked
+func (s inventoryScreen) picked() boxList {
+	picked := s.markedShown()
+	if len(picked) == 0 {
+		if row, ok := s.selected(); ok {
+			picked = append(picked, rowKey(row))
+		}
+	}
+	return picked
+}
+
+func (s *inventoryScreen) toggleMark() {
+	row, ok := s.selected()
+	if !ok {
+		return
+	}
+	if s.marked[rowKey(row)] {
+		delete(s.marked, rowKey(row))
+	} else {
+		s.marked[rowKey(row)] = true
+	}
+}
+
+// marks every box shown, or clears the marks when they all are
+func (s *inventoryScreen) toggleAll() {
+	all := true
+	for _, row := range s.shown {
+		all = all && s.marked[rowKey(row)]
+	}
+	for _, row := range s.shown {
+		if all {
+			delete(s.marked, rowKey(row))
+		} else {
+			s.marked[rowKey(row)] = true
+		}
+	}
+}
+
+func compareInventory(a, b inventoryRow, column int) int {
+	switch column {
+	case 1:
+		return cmp.Compare(a.box.Batch, b.box.Batch)
+	case 2:
+		return cmp.Compare(a.box.State, b.box.State)
+	case 3:
+		return cmp.Compare(a.box.InstanceType, b.box.InstanceType)
+	case 4:
+		return compareAddress(a.box.PublicIP, b.box.PublicIP)
+	case 5:
+		return compareAddress(a.box.PrivateIP, b.box.PrivateIP)
+	case 6:
+		return cmp.Compare(a.uptime, b.uptime)
+	case 7:
+		return cmp.Compare(a.cost, b.cost)
+	}
+	return cmp.Compare(a.box.InstanceID, b.box.InstanceID)
+}
+
+// 10.0.0.9 before 10.0.0.10, boxes without an address first
+func compareAddress(a, b string) int {
+	addrA, errA := netip.ParseAddr(a)
+	addrB, errB := netip.ParseAddr(b)
+	if errA != nil || errB != nil {
+		return cmp.Compare(a, b)
+	}
+	return addrA.Compare(addrB)
+}
+
+func formatUptime(uptime time.Duration) string {
+	switch {
+	case uptime <= 0:
+		return "-"
+	case uptime < time.Hour:
+		return fmt.Sprintf("%dm", int(uptime.Minutes()))
+	case uptime < 24*time.Hour:
+		return fmt.Sprintf("%dh%02dm", int(uptime.Hours()), int(uptime.Minutes())%60)
+	}
+	return fmt.Sprintf("%dd%02dh", int(uptime.Hours())/24, int(uptime.Hours())%24)
+}
+
+func formatCost(row inventoryRow) string {
+	if !row.priced {
+		return "-"
+	}
+	return fmt.Sprintf("$%.2f", row.cost)
+}
+
+// boxes of every batch in the region, DigitalOcean lists the addresses of
+// the batch only
+func listInventory(app *applicationMain, now time.Time) ([]inventoryRow, error) {
+	rows := []inventoryRow{}
+
+	if app.Provider == "digital" {
+		ips, err := app.Digital.compileIPaddressesDigital(app.BatchTag)
+		if err != nil {
+			return nil, fmt.Errorf("error compiling IP addresses:\n%w", err)
+		}
+		for _, ip := range ips {
+			rows = append(rows, inventoryRow{box: inventoryBox{
+				EC2InstanceIP: EC2InstanceIP{PublicIP: ip},
+				Batch:         app.BatchTag,
+			}})
+		}
+		return rows, nil
+	}
+
+	pepa, err := app.Aws.createEc2Client()
+	if err != nil {
+		return nil, fmt.Errorf("error getting AWS credentials:\n%w", err)
+	}
+	boxes, err := app.Aws.inventory(pepa)
+	if err != nil {
+		return nil, fmt.Errorf("error listing boxes:\n%w", err)
+	}
+	for _, box := range boxes {
+		cost, priced := app.Aws.boxCost(box, now)
+		rows = append(rows, inventoryRow{box: box, uptime: box.uptime(now), cost: cost, priced: priced})
+	}
+	return rows, nil
+}
+
+func (m *MenuList) openInventory() tea.Cmd {
+	m.state = StateInventory
+	m.inventory.filtering = false
+	m.inventory.filter.Blur()
+	return m.inventory.load(m.app)
+}
+
+func (m *MenuList) updateInventory(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	s := &m.inventory
+
+	if s.filtering {
+		switch keyMsg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "esc":
+			s.filter.SetValue("")
+			fallthrough
+		case "enter":
+			s.filtering = false
+			s.filter.Blur()
+			s.apply()
+			return m, nil
+		}
+		var cmd tea.Cmd
+		s.filter, cmd = s.filter.Update(keyMsg)
+		s.apply()
+		return m, cmd
+	}
+
+	switch keyMsg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "esc", "q":
+		m.state = StateMainMenu
+		m.prevMenuState = StateMainMenu
+		return m, nil
+	case "/":
+		s.filtering = true
+		return m, s.filter.Focus()
+	case "s":
+		s.sortBy = (s.sortBy + 1) % len(inventoryColumns)
+		s.apply()
+		return m, nil
+	case "S":
+		s.desc = !s.desc
+		s.apply()
+		return m, nil
+	case "r":
+		return m, s.load(m.app)
+	case " ", "x":
+		s.toggleMark()
+		s.apply()
+		return m, nil
+	case "a":
+		s.toggleAll()
+		s.apply()
+		return m, nil
+	case "D", "b", "p", "u", "v":
+		return m, m.runOnPicked(keyMsg.String())
+	}
+	var cmd tea.Cmd
+	s.table, cmd = s.table.Update(keyMsg)
+	return m, cmd
+}
+
+// queues the action of key on the picked boxes and stays on the table
+func (m *MenuList) runOnPicked(action string) tea.Cmd {
+	picked := m.inventory.picked()
+	if !picked.picked() {
+		return nil
+	}
+
+	clear(m.inventory.marked)
+	m.inventory.apply()
+	m.prevState = StateInventory
+	m.prevMenuState = StateInventory
+
+	var j job
+	switch action {
+	case "D":
+		return m.startDelete(picked)
+	case "b":
+		j = backgroundJobPowerBoxes("reboot " + strings.Join(picked, ","))
+	case "p":
+		j = backgroundJobPS1scripts(picked)
+	case "u":
+		j = backgroundJobRunPostURL(picked)
+	case "v":
+		j = backgroundJobVerifyVNC(picked)
+	}
+	return m.startJob(j)
+}
+
+func (m MenuList) viewInventory() string {
+	s := m.inventory
+	promptStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor)).Bold(true)
+	detailStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(textErrorColorFront)).Background(lipgloss.Color(textErrorColorBack))
+	helpText := detailStyle.Render("↑/↓: select   s: sort by next column   S: reverse   /: filter by batch   r: refresh   esc: back\n" +
+		"space: mark   a: mark all   on the marked boxes, or the one selected:   D: delete   b: reboot   p: create scripts   u: run URLs   v: VNC verify")
+
+	region := m.app.Aws.Region
+	if m.app.Provider == "digital" {
+		region = m.app.Digital.Region
+	}
+	status := fmt.Sprintf("%d boxes", len(s.shown))
+	if len(s.shown) != len(s.rows) {
+		status = fmt.Sprintf("%d of %d boxes", len(s.shown), len(s.rows))
+	}
+	if marked := len(s.markedShown()); marked > 0 {
+		status += fmt.Sprintf(", %d marked", marked)
+	}
+	switch {
+	case s.loading:
+		status += "   listing..."
+	case !s.updated.IsZero():
+		status += "   updated " + s.updated.Format("15:04:05")
+	}
+	title := fmt.Sprintf("%s  %s %s   %s", promptStyle.Render("INVENTORY"), m.app.Provider, region, detailStyle.Render(status))
+
+	view := strings.Builder{}
+	view.WriteString("\n\n" + title + "\n" + m.viewJobsStatus() + "\n")
+	if s.err != nil {
+		view.WriteString(errorStyle.Render(oneLine(s.err)) + "\n\n")
+	}
+	if s.filtering || s.filter.Value() != "" {
+		view.WriteString(s.filter.View() + "\n\n")
+	}
+	view.WriteString(s.table.View() + "\n\n")
+	// Cost is uptime times the rate of the type, "-" for a type without one
+	if m.app.Provider == "aws" && len(m.app.Aws.HourlyRates) == 0 {
+		view.WriteString(detailStyle.Render("Cost: no prices yet, set them with Set Hourly Rates in the menu") + "\n")
+	}
+	if row, ok := s.selected(); ok {
+		view.WriteString(m.viewInventoryDetail(row) + "\n")
+	}
+	view.WriteString(helpText)
+	return view.String()
+}
+
+// detail pane of the box under the cursor
+func (m MenuList) viewInventoryDetail(row inventoryRow) string {
+	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor))
+	box := row.box
+	if box.InstanceID == "" {
+		return itemStyle.Render(fmt.Sprintf("%s %s   %s", labelStyle.Render("Address"), box.PublicIP,
+			lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render("DigitalOcean boxes are listed by address only"))) + "\n"
+	}
+
+	field := func(label, value string) string {
+		return fmt.Sprintf("%s %s", labelStyle.Render(label), cmp.Or(value, "-"))
+	}
+	launched := "-"
+	if !box.Launched.IsZero() {
+		launched = box.Launched.Local().Format("2006-01-02 15:04")
+	}
+	tags := []string{}
+	for _, key := range box.tagKeys() {
+		tags = append(tags, key+"="+box.Tags[key])
+	}
+
+	lines := []string{
+		fmt.Sprintf("%s   %s   %s", field("Box", box.InstanceID), field("Name", box.Tags["Name"]), field("Launched", launched)),
+		fmt.Sprintf("%s   %s   %s", field("Zone", box.Zone), field("Image", box.ImageID), field("Key pair", box.KeyName)),
+		fmt.Sprintf("%s   %s", field("IPv6", box.IPv6), field("Security groups", strings.Join(box.SecurityGroups, ", "))),
+		field("Tags", strings.Join(tags, "  ")),
+	}
+	detail := strings.Builder{}
+	for _, line := range lines {
+		detail.WriteString(itemStyle.Render(line) + "\n")
+	}
+	return detail.String()
+}
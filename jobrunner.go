@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Jobs run off the bubbletea goroutine on their own copy of the settings and
+// never see the model. The runner queues them and runs one at a time, their
+// progress and result come back as messages tagged with the job id, so
+// nothing on screen changes outside Update.
+
+// a background job, title is what the menu calls it while it runs
+type job struct {
+	title string
+	color string
+	// the menu waits on the spinner for it instead of queueing it, for the
+	// lookups a picker needs
+	wait bool
+	run  func(ctx context.Context, app *applicationMain, progress progressFunc) tea.Msg
+	// the plan the operator confirmed the job on, re-runs plan and confirm again
+	plan *job
+}
+
+// sent when job id ends, msg is what its run returned
+type jobDoneMsg struct {
+	id  int
+	msg tea.Msg
+}
+
+// progressMsg of job id on its way to the menu, ch is where the next
+// message of the job comes from
+type progressEvent struct {
+	progressMsg
+	id int
+	ch <-chan tea.Msg
+}
+
+type jobStatus int
+
+const (
+	jobQueued jobStatus = iota
+	jobRunning
+	jobDone
+	jobFailed
+	jobCancelled
+)
+
+func (s jobStatus) String() string {
+	return [...]string{"queued", "running", "done", "failed", "cancelled"}[s]
+}
+
+// a job on the jobs screen, only touched from Update
+type jobEntry struct {
+	id         int
+	job        job
+	settings   *applicationMain // copy taken when queued, every run gets its own copy of it
+	status     jobStatus
+	cancelling bool
+	started    time.Time
+	finished   time.Time
+	checklist  jobChecklist
+	log        []string
+	result     string
+	picker     *pickerListMsg // boxes to pick, for lookups
+	confirm    *confirmMsg    // action to confirm, for plans
+	cancel     context.CancelFunc
+}
+
+// time the job ran, or has been running
+func (e *jobEntry) elapsed(now time.Time) time.Duration {
+	if e.finished.IsZero() {
+		return now.Sub(e.started).Truncate(time.Second)
+	}
+	return e.finished.Sub(e.started).Truncate(time.Second)
+}
+
+func (e *jobEntry) logf(format string, args ...any) {
+	e.log = append(e.log, time.Now().Format("15:04:05 ")+fmt.Sprintf(format, args...))
+}
+
+// starts e on a copy of its settings. Its progress and then its result are
+// read off one channel by waitForJob a message at a time, so the result
+// never overtakes the last steps
+func (e *jobEntry) start() tea.Cmd {
+	ctx, cancel := context.WithCancel(context.Background())
+	e.status, e.started, e.cancel = jobRunning, time.Now(), cancel
+	e.logf("started")
+
+	id, j, app := e.id, e.job, snapshotApp(e.settings)
+	app.withContext(ctx)
+	ch := make(chan tea.Msg, 16)
+	run := func() tea.Msg {
+		defer cancel()
+		result := j.run(ctx, app, func(msg progressMsg) {
+			ch <- progressEvent{progressMsg: msg, id: id, ch: ch}
+		})
+		ch <- jobDoneMsg{id: id, msg: result}
+		close(ch)
+		return nil
+	}
+	return tea.Batch(run, waitForJob(ch))
+}
+
+type jobRunner struct {
+	lastID  int
+	entries []*jobEntry // oldest first
+	waiting int         // id of the job the menu waits on, 0 when none
+}
+
+// queues j on a copy of app, a job the menu waits on starts right away
+func (r *jobRunner) add(app *applicationMain, j job) (*jobEntry, tea.Cmd) {
+	r.lastID++
+	e := &jobEntry{id: r.lastID, job: j, settings: snapshotApp(app)}
+	r.entries = append(r.entries, e)
+	if j.wait {
+		r.waiting = e.id
+		return e, e.start()
+	}
+	return e, r.next()
+}
+
+// queues job id again with the settings it was queued with
+func (r *jobRunner) rerun(id int) (*jobEntry, tea.Cmd) {
+	e := r.entry(id)
+	if e == nil {
+		return nil, nil
+	}
+	if e.job.plan != nil {
+		return r.add(e.settings, *e.job.plan)
+	}
+	return r.add(e.settings, e.job)
+}
+
+// starts the oldest queued job unless one is running
+func (r *jobRunner) next() tea.Cmd {
+	var oldest *jobEntry
+	for _, e := range r.entries {
+		switch {
+		case e.job.wait:
+		case e.status == jobRunning:
+			return nil
+		case e.status == jobQueued && oldest == nil:
+			oldest = e
+		}
+	}
+	if oldest == nil {
+		return nil
+	}
+	return oldest.start()
+}
+
+// a queued job is dropped, a running one stops before its next step
+func (r *jobRunner) cancel(id int) {
+	e := r.entry(id)
+	if e == nil {
+		return
+	}
+	switch e.status {
+	case jobQueued:
+		e.status, e.finished = jobCancelled, time.Now()
+		e.logf("cancelled")
+	case jobRunning:
+		e.cancelling = true
+		e.cancel()
+		e.logf("cancelling")
+	}
+}
+
+func (r *jobRunner) progress(event progressEvent) tea.Cmd {
+	if e := r.entry(event.id); e != nil {
+		e.checklist.update(event.progressMsg)
+		if line := progressLine(event.progressMsg); line != "" {
+			e.logf("%s", line)
+		}
+	}
+	return waitForJob(event.ch)
+}
+
+// records how job id ended and starts the next queued job, waited is true
+// when the menu was waiting on it
+func (r *jobRunner) done(msg jobDoneMsg) (e *jobEntry, waited bool, next tea.Cmd) {
+	e = r.entry(msg.id)
+	if e == nil || e.status != jobRunning {
+		return nil, false, nil
+	}
+	e.status, e.finished = jobDone, time.Now()
+	cancelled := false
+	switch result := msg.msg.(type) {
+	case backgroundJobMsg:
+		e.result = result.result
+		cancelled = result.cancelled
+		if result.failed {
+			e.status = jobFailed
+		}
+	case pickerListMsg:
+		e.result = result.result
+		if result.result == "" {
+			e.picker = &result
+			e.result = fmt.Sprintf("%d boxes to pick from, press enter", len(result.items))
+		}
+	case confirmMsg:
+		e.result = result.result
+		if result.result == "" {
+			result.job = e.id
+			e.confirm = &result
+			e.result = "Waiting for confirmation, press enter"
+			if result.phrase == "" {
+				e.result = "Nothing to confirm, press enter to go ahead"
+			}
+		}
+	}
+	// a job that ran to the end anyway keeps its result
+	switch {
+	case e.cancelling && cancelled:
+		e.status = jobCancelled
+		e.logf("%s", e.status)
+	case e.cancelling:
+		e.logf("finished (cancel ignored), %s", e.status)
+	default:
+		e.logf("%s", e.status)
+	}
+
+	waited = e.id == r.waiting
+	if waited {
+		r.waiting = 0
+	}
+	return e, waited, r.next()
+}
+
+func (r *jobRunner) entry(id int) *jobEntry {
+	for _, e := range r.entries {
+		if e.id == id {
+			return e
+		}
+	}
+	return nil
+}
+
+func (r *jobRunner) counts() (running, queued int) {
+	for _, e := range r.entries {
+		switch e.status {
+		case jobRunning:
+			running++
+		case jobQueued:
+			queued++
+		}
+	}
+	return running, queued
+}
+
+func waitForJob(ch <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-ch
+	}
+}
+
+// the AWS and DigitalOcean calls of app stop with ctx
+func (app *applicationMain) withContext(ctx context.Context) {
+	app.Aws.ctx = ctx
+	app.Digital.ctx = ctx
+}
+
+// jobs get their own copy of the settings, what they change comes back in
+// backgroundJobMsg.settings
+func snapshotApp(app *applicationMain) *applicationMain {
+	snapshot := *app
+	snapshot.SkipConfirm = slices.Clone(app.SkipConfirm)
+	snapshot.Aws = app.Aws.clone()
+	return &snapshot
+}
+
+// queues j and goes back to the menu, or shows the spinner when the menu
+// waits for j
+func (m *MenuList) startJob(j job) tea.Cmd {
+	return m.startJobOn(m.app, j)
+}
+
+// startJob on settings other than the menu's
+func (m *MenuList) startJobOn(app *applicationMain, j job) tea.Cmd {
+	e, cmd := m.jobs.add(app, j)
+	if j.wait {
+		m.spinner.Style = lipgloss.NewStyle().Foreground(lipgloss.Color(j.color))
+		m.state = StateSpinner
+	} else {
+		m.state = m.prevMenuState
+		m.jobNotice = fmt.Sprintf("Job #%d queued: %s", e.id, j.title)
+	}
+	return tea.Batch(m.spinner.Tick, cmd)
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/exp/teatest"
+)
+
+func testMenu() MenuList {
+	return newMenuList(&applicationMain{Provider: "digital", BatchTag: "web"})
+}
+
+func waitForOutput(t *testing.T, tm *teatest.TestModel, want ...string) {
+	t.Helper()
+	teatest.WaitFor(t, tm.Output(), func(out []byte) bool {
+		for _, w := range want {
+			if !bytes.Contains(out, []byte(w)) {
+				return false
+			}
+		}
+		return true
+	}, teatest.WithDuration(5*time.Second), teatest.WithCheckInterval(10*time.Millisecond))
+}
+
+// runs the job cmd started and returns how it ended
+func runJob(t *testing.T, cmd tea.Cmd) jobDoneMsg {
+	t.Helper()
+	batch, ok := cmd().(tea.BatchMsg)
+	if !ok || len(batch) != 2 {
+		t.Fatalf("cmd does not start a job: %v", batch)
+	}
+	go batch[0]()
+	wait := batch[1]
+	for {
+		switch msg := wait().(type) {
+		case progressEvent:
+			wait = waitForJob(msg.ch)
+		case jobDoneMsg:
+			return msg
+		default:
+			t.Fatalf("unexpected message %T from the job", msg)
+		}
+	}
+}
+
+// run with -race: the job works on its copy of the settings while the
+// spinner ticks, and only the result message reaches the model
+func TestJobSpinnerFlow(t *testing.T) {
+	release := make(chan struct{})
+	j := job{
+		title: "Testing the runner",
+		color: "82",
+		wait:  true,
+		run: func(_ context.Context, app *applicationMain, progress progressFunc) tea.Msg {
+			progress.total(2)
+			progress.start("first step")
+			<-release
+			progress.finish("first step", "3 boxes", nil)
+			progress.finish("second step", "", errors.New("no luck"))
+
+			app.BatchTag = "changed on the copy"
+			return backgroundJobMsg{
+				result:   "job done",
+				settings: func(app *applicationMain) { app.URL = "https://example.com" },
+			}
+		},
+	}
+
+	m := testMenu()
+	cmd := m.startJob(j)
+
+	tm := teatest.NewTestModel(t, m, teatest.WithInitialTermSize(100, 30))
+	tm.Send(cmd())
+	waitForOutput(t, tm, "Testing the runner", "first step", "0/2")
+
+	close(release)
+	waitForOutput(t, tm, "job done")
+	tm.Send(tea.KeyMsg{Type: tea.KeyCtrlC})
+
+	final := tm.FinalModel(t, teatest.WithFinalTimeout(5*time.Second)).(*MenuList)
+	if final.state != StateResultDisplay {
+		t.Errorf("state = %d, want the result", final.state)
+	}
+	if final.jobs.waiting != 0 {
+		t.Errorf("menu still waiting on job #%d", final.jobs.waiting)
+	}
+	if final.app.BatchTag != "web" {
+		t.Errorf("job changed the menu's settings: BatchTag = %s", final.app.BatchTag)
+	}
+	if final.app.URL != "https://example.com" {
+		t.Errorf("URL = %q, settings of the job not applied", final.app.URL)
+	}
+}
+
+// the menu stays usable while queued jobs run one at a time
+func TestJobQueueFlow(t *testing.T) {
+	release := make(chan struct{})
+	first := job{
+		title: "First job",
+		color: "82",
+		run: func(_ context.Context, _ *applicationMain, progress progressFunc) tea.Msg {
+			progress.start("waiting")
+			<-release
+			progress.finish("waiting", "", nil)
+			return backgroundJobMsg{result: "first result"}
+		},
+	}
+	second := job{
+		title: "Second job",
+		color: "82",
+		run: func(context.Context, *applicationMain, progressFunc) tea.Msg {
+			return backgroundJobMsg{result: "second result", failed: true}
+		},
+	}
+
+	m := testMenu()
+	cmds := []tea.Cmd{m.startJob(first), m.startJob(second)}
+	if m.state != StateMainMenu {
+		t.Fatalf("state = %d after queueing, want the menu", m.state)
+	}
+
+	tm := teatest.NewTestModel(t, m, teatest.WithInitialTermSize(100, 40))
+	for _, cmd := range cmds {
+		tm.Send(cmd())
+	}
+	waitForOutput(t, tm, "Jobs: 1 running, 1 queued")
+
+	// JOBS is the one before Save Settings
+	tm.Send(tea.KeyMsg{Type: tea.KeyEnd})
+	tm.Send(tea.KeyMsg{Type: tea.KeyUp})
+	tm.Send(tea.KeyMsg{Type: tea.KeyEnter})
+	waitForOutput(t, tm, "First job", "Second job", "c: cancel")
+
+	tm.Send(tea.KeyMsg{Type: tea.KeyDown})
+	close(release)
+	waitForOutput(t, tm, "first result")
+	tm.Send(tea.KeyMsg{Type: tea.KeyCtrlC})
+
+	final := tm.FinalModel(t, teatest.WithFinalTimeout(5*time.Second)).(*MenuList)
+	if final.state != StateJobs {
+		t.Errorf("state = %d, want the jobs screen", final.state)
+	}
+	statuses := []jobStatus{}
+	for _, e := range final.jobs.entries {
+		statuses = append(statuses, e.status)
+	}
+	if len(statuses) != 2 || statuses[0] != jobDone || statuses[1] != jobFailed {
+		t.Errorf("statuses = %v, want [done failed]", statuses)
+	}
+}
+
+func TestJobRunnerQueue(t *testing.T) {
+	app := &applicationMain{Provider: "digital"}
+	block := func(ctx context.Context, _ *applicationMain, _ progressFunc) tea.Msg {
+		<-ctx.Done()
+		return jobResultMsg("stopped", ctx.Err())
+	}
+	quick := func(context.Context, *applicationMain, progressFunc) tea.Msg {
+		return backgroundJobMsg{result: "quick"}
+	}
+
+	var r jobRunner
+	first, run := r.add(app, job{title: "first", run: block})
+	second, next := r.add(app, job{title: "second", run: quick})
+	third, _ := r.add(app, job{title: "third", run: quick})
+	if next != nil || second.status != jobQueued {
+		t.Fatalf("second job %s while the first runs", second.status)
+	}
+
+	r.cancel(third.id)
+	if third.status != jobCancelled {
+		t.Errorf("cancelled queued job is %s", third.status)
+	}
+	r.cancel(first.id)
+	e, waited, next := r.done(runJob(t, run))
+	if e != first || e.status != jobCancelled || waited {
+		t.Errorf("first job ended %s, waited %t, want cancelled", e.status, waited)
+	}
+	if second.status != jobRunning || next == nil {
+		t.Fatalf("second job %s after the first ended, want running", second.status)
+	}
+
+	e, _, next = r.done(runJob(t, next))
+	if e != second || e.status != jobDone || e.result != "quick" {
+		t.Errorf("second job ended %s with %q", e.status, e.result)
+	}
+	if next != nil {
+		t.Error("cancelled third job started")
+	}
+
+	again, cmd := r.rerun(second.id)
+	if again.id != 4 || again.status != jobRunning || cmd == nil {
+		t.Errorf("re-run = #%d %s, want #4 running", again.id, again.status)
+	}
+	if _, _, next := r.done(jobDoneMsg{id: second.id}); next != nil {
+		t.Error("result of a finished job taken again")
+	}
+}
+
+func TestSnapshotApp(t *testing.T) {
+	app := &applicationMain{Provider: "aws", SkipConfirm: []string{confirmDelete}}
+	snapshot := snapshotApp(app)
+	snapshot.SkipConfirm[0] = confirmStop
+	if app.SkipConfirm[0] != confirmDelete {
+		t.Errorf("snapshot shares SkipConfirm with the menu: %v", app.SkipConfirm)
+	}
+}
+
+// a job that finishes its work after the cancel keeps its result
+func TestJobRunnerCancelIgnored(t *testing.T) {
+	app := &applicationMain{Provider: "digital"}
+	finish := func(ctx context.Context, _ *applicationMain, _ progressFunc) tea.Msg {
+		<-ctx.Done()
+		return backgroundJobMsg{result: "deleted 2 boxes"}
+	}
+
+	var r jobRunner
+	e, run := r.add(app, job{title: "finish", run: finish})
+	r.cancel(e.id)
+	r.done(runJob(t, run))
+	if e.status != jobDone || e.result != "deleted 2 boxes" {
+		t.Errorf("job ended %s with %q, want done with its result", e.status, e.result)
+	}
+	if last := e.log[len(e.log)-1]; !strings.Contains(last, "finished (cancel ignored)") {
+		t.Errorf("last log line %q, want the cancel ignored", last)
+	}
+}
+
+// a confirmed delete runs on the settings it was planned on, once
+func TestConfirmRunsOnPlan(t *testing.T) {
+	m := testMenu()
+	plan := job{
+		title: "Checking what the delete removes",
+		wait:  true,
+		run: func(_ context.Context, app *applicationMain, _ progressFunc) tea.Msg {
+			return confirmMsg{
+				title:    "DELETE Boxes will remove",
+				summary:  deletePlan{perBatch: map[string]int{"web": 2}}.summary(),
+				phrase:   "web",
+				run:      backgroundJobDeleteBox(nil, []string{"10.0.0.1", "10.0.0.2"}),
+				settings: app,
+			}
+		},
+	}
+	planning, run := m.jobs.add(m.app, plan)
+	m.jobs.done(runJob(t, run))
+	m.app.BatchTag = ""
+
+	m.state = StateJobs
+	m.updateJobs(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.state != StateConfirm {
+		t.Fatalf("state = %d, want the confirm dialog", m.state)
+	}
+	m.updateConfirm(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("web")})
+	m.updateConfirm(tea.KeyMsg{Type: tea.KeyEnter})
+
+	deleting := m.jobs.entries[len(m.jobs.entries)-1]
+	if deleting == planning || deleting.settings.BatchTag != "web" {
+		t.Errorf("delete queued on BatchTag %q, want the planned web", deleting.settings.BatchTag)
+	}
+	if planning.confirm != nil {
+		t.Error("plan still confirmable after use")
+	}
+
+	// a re-run of the delete plans it again and asks for the phrase again
+	m.jobs.done(jobDoneMsg{id: deleting.id, msg: backgroundJobMsg{result: "deleted"}})
+	again, cmd := m.jobs.rerun(deleting.id)
+	if again.job.title != plan.title || cmd == nil {
+		t.Fatalf("re-run queued %q, want the plan", again.job.title)
+	}
+	m.jobs.done(runJob(t, cmd))
+	if again.confirm == nil || again.confirm.phrase != "web" {
+		t.Errorf("re-run plan = %+v, want the delete to confirm again", again.confirm)
+	}
+}
+
+// a plan with nothing destructive in it runs without the dialog
+func TestConfirmNothingToConfirm(t *testing.T) {
+	m := testMenu()
+	m.state = StateMainMenu
+	scale := job{
+		title: "Scaling web to 3 Boxes",
+		run:   func(context.Context, *applicationMain, progressFunc) tea.Msg { return nil },
+	}
+
+	m.openConfirm(confirmMsg{run: scale, settings: m.app})
+	if m.state == StateConfirm {
+		t.Fatal("confirm dialog opened for a plan with no phrase")
+	}
+	queued := m.jobs.entries[len(m.jobs.entries)-1]
+	if queued.job.title != scale.title {
+		t.Errorf("queued %q, want %q", queued.job.title, scale.title)
+	}
+}
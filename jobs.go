@@ -0,0 +1,920 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+)
+
+// Job bodies shared by the menu and the CLI. They return the text shown to the
+// operator and an error when any step failed, the text is still worth showing then.
+// Steps and boxes are reported to progress as they start and finish, a cancelled
+// ctx stops them before the next step.
+
+// boxes a job is limited to, by instance ID or address. Empty means the whole
+// batch, picked boxes may be in any batch
+type boxList []string
+
+func (l boxList) picked() bool {
+	return len(l) > 0
+}
+
+// true when the box known by one of keys is on the list, or there is no list
+func (l boxList) has(keys ...string) bool {
+	if !l.picked() {
+		return true
+	}
+	for _, key := range keys {
+		if key != "" && slices.Contains(l, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// batch to look the boxes up in, every batch when boxes are picked
+func (l boxList) lookupBatch(batchT string) string {
+	if l.picked() {
+		return ""
+	}
+	return batchT
+}
+
+// picked boxes get an ssh config of their own so the batch's stays whole
+func (l boxList) sshConfigName(batchT string) string {
+	if l.picked() {
+		return "picked"
+	}
+	return batchT
+}
+
+func scriptsFolder(app *applicationMain) string {
+	if app.Provider == "aws" {
+		return fmt.Sprintf("./%s", app.Aws.Region)
+	}
+	return fmt.Sprintf("./%s", app.Digital.Region)
+}
+
+// launches NumberBoxes boxes into the current batch
+func deployBoxes(ctx context.Context, app *applicationMain, progress progressFunc) (string, error) {
+	errs := []error{}
+
+	if app.Provider == "digital" {
+		progress.total(app.NumberBoxes + 1)
+		progress.start("firewall")
+		err := app.Digital.createFirewall()
+		progress.finish("firewall", "", err)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error creating firewall:\n%w", err))
+		}
+		for i := 1; i <= app.NumberBoxes; i++ {
+			if ctx.Err() != nil {
+				errs = append(errs, cancelledError(ctx, i-1, app.NumberBoxes))
+				break
+			}
+			step := fmt.Sprintf("box %d", i)
+			progress.start(step)
+			err := app.Digital.createBox(app.BatchTag)
+			progress.finish(step, "", err)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("error creating box:\n%w", err))
+			}
+		}
+		return fmt.Sprintf("%d - Boxes created!", app.NumberBoxes), errors.Join(errs...)
+	}
+
+	steps := app.NumberBoxes + 4
+	if app.Aws.ElasticIPs {
+		steps++
+	}
+	progress.total(steps)
+
+	pepa, err := app.Aws.createEc2Client()
+	if err != nil {
+		return "", fmt.Errorf("error getting AWS credentials:\n%w", err)
+	}
+	progress.start("key pair")
+	err = app.Aws.createPEMFile(pepa)
+	progress.finish("key pair", app.Aws.keyPairName(), err)
+	if err != nil {
+		return "", fmt.Errorf("error creating PEM:\n%w", err)
+	}
+	progress.start("security group")
+	sgAuto, err := app.Aws.createSecurityGroup("sgAutoBox", "pepita stuff", pepa)
+	progress.finish("security group", sgAuto, err)
+	if err != nil {
+		return "", fmt.Errorf("error creating Security Group:\n%w", err)
+	}
+	progress.start("batch lock")
+	err = app.Aws.acquireLock(pepa, sgAuto, app.BatchTag)
+	progress.finish("batch lock", batchOrAll(app.BatchTag), err)
+	if err != nil {
+		return "", fmt.Errorf("not deploying, %w", err)
+	}
+	defer app.Aws.releaseLock(pepa, sgAuto, app.BatchTag)
+
+	progress.start("subnets & placement")
+	subnetIDs, err := app.Aws.launchSubnets(pepa, app.NumberBoxes, 0)
+	if err == nil {
+		err = app.Aws.ensurePlacementGroup(pepa)
+	}
+	progress.finish("subnets & placement", "", err)
+	if err != nil {
+		return "", fmt.Errorf("error preparing placement:\n%w", err)
+	}
+
+	instanceIDs := []string{}
+	for i, subnetID := range subnetIDs {
+		if ctx.Err() != nil {
+			errs = append(errs, cancelledError(ctx, i, len(subnetIDs)))
+			break
+		}
+		step := fmt.Sprintf("box %d", i+1)
+		progress.start(step)
+		instanceID, err := app.Aws.createEC2Instance(sgAuto, pepa, app.BatchTag, subnetID)
+		progress.finish(step, instanceID, err)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error creating box:\n%w", err))
+			continue
+		}
+		instanceIDs = append(instanceIDs, instanceID)
+	}
+	if app.Aws.ElasticIPs && len(instanceIDs) > 0 {
+		progress.start("Elastic IPs")
+		_, err := app.Aws.allocateElasticIPs(pepa, instanceIDs, app.BatchTag)
+		progress.finish("Elastic IPs", "", err)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error allocating Elastic IPs:\n%w", err))
+		}
+	}
+
+	result := fmt.Sprintf("%d - Boxes created!%s", len(instanceIDs), idleElasticIPsWarning(app, pepa))
+	return result, errors.Join(errs...)
+}
+
+// terminates the batch (every box when no batch) and clears its scripts, or
+// only the picked boxes. planned are the boxes the operator confirmed, nothing
+// is deleted when the live ones differ. nil skips the check
+func deleteBoxes(ctx context.Context, app *applicationMain, only boxList, planned []string, progress progressFunc) (string, error) {
+	if only.picked() {
+		return deletePickedBoxes(ctx, app, only, planned, progress)
+	}
+	result := "Boxes & Related Resources Deleted!"
+	errs := []error{}
+
+	if app.Provider == "digital" {
+		if ctx.Err() != nil {
+			return "", cancelledError(ctx, 0, 1)
+		}
+		steps := 3
+		if app.BatchTag != "" {
+			steps--
+		}
+		progress.total(steps)
+		if planned != nil {
+			ips, err := app.Digital.compileIPaddressesDigital(app.BatchTag)
+			if err != nil {
+				return "", fmt.Errorf("error compiling IP addresses:\n%w", err)
+			}
+			if err = matchesPlan(planned, ips); err != nil {
+				return "", err
+			}
+		}
+		progress.start("droplets")
+		err := app.Digital.deleteBox(app.BatchTag)
+		progress.finish("droplets", "", err)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error deleting droplets\n%w", err))
+		}
+		// the firewall covers the droplets of every batch
+		if app.BatchTag == "" {
+			progress.start("firewall")
+			err = app.Digital.deleteFirewall()
+			progress.finish("firewall", "", err)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("error deleting firewall\n%w", err))
+			}
+		}
+	} else { //aws
+		if err := app.Aws.checkDeleteAll(app.BatchTag); err != nil {
+			return "", fmt.Errorf("not deleting, %w", err)
+		}
+		steps := 5
+		if app.BatchTag == "" {
+			steps++
+		}
+		progress.total(steps)
+
+		pepa, err := app.Aws.createEc2Client()
+		if err != nil {
+			return "", fmt.Errorf("error getting AWS credentials:\n%w", err)
+		}
+		sgAuto, err := app.Aws.createSecurityGroup("sgAutoBox", "pepita stuff", pepa)
+		if err != nil {
+			return "", fmt.Errorf("error creating Security Group:\n%w", err)
+		}
+		progress.start("batch lock")
+		err = app.Aws.acquireLock(pepa, sgAuto, app.BatchTag)
+		progress.finish("batch lock", batchOrAll(app.BatchTag), err)
+		if err != nil {
+			return "", fmt.Errorf("not deleting, %w", err)
+		}
+		defer app.Aws.releaseLock(pepa, sgAuto, app.BatchTag)
+		if ctx.Err() != nil {
+			return "", cancelledError(ctx, 0, 1)
+		}
+		if planned != nil {
+			targets, err := deleteTargetsAws(app, pepa, only)
+			if err != nil {
+				return "", err
+			}
+			if err = matchesPlan(planned, targets.instanceIDs); err != nil {
+				return "", err
+			}
+		}
+
+		progress.start("Elastic IPs")
+		err = app.Aws.releaseElasticIPs(pepa, app.BatchTag, nil)
+		progress.finish("Elastic IPs", "released", err)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		progress.start("boxes")
+		err = app.Aws.deleteEC2Instances(pepa, app.BatchTag)
+		progress.finish("boxes", "terminated", err)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		result = fmt.Sprintf("%s%s", result, idleElasticIPsWarning(app, pepa))
+		if app.BatchTag == "" {
+			progress.start("key pair")
+			deleted, err := app.Aws.deletePEMFile(pepa)
+			keyPair := app.Aws.keyPairName()
+			if !deleted {
+				keyPair = fmt.Sprintf("%s kept, not in the workspace", keyPair)
+			}
+			progress.finish("key pair", keyPair, err)
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	progress.start("scripts")
+	folder := scriptsFolder(app)
+	err := clearScripts(folder, isStaleFile(app, nil, nil))
+	progress.finish("scripts", folder, err)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// terminates the picked boxes whatever their batch, with each of their batches
+// locked, and clears their scripts. The key pair and security group stay
+func deletePickedBoxes(ctx context.Context, app *applicationMain, only boxList, planned []string, progress progressFunc) (string, error) {
+	if app.Provider == "digital" {
+		return "", errors.New("deleting single droplets is only available for AWS, delete the batch instead")
+	}
+
+	pepa, err := app.Aws.createEc2Client()
+	if err != nil {
+		return "", fmt.Errorf("error getting AWS credentials:\n%w", err)
+	}
+	targets, err := deleteTargetsAws(app, pepa, only)
+	if err != nil {
+		return "", err
+	}
+	instanceIDs, batches := targets.instanceIDs, targets.batches
+	if len(instanceIDs) == 0 {
+		return "", fmt.Errorf("none of the picked boxes is live: %s", strings.Join(only, ", "))
+	}
+	if err = matchesPlan(planned, instanceIDs); err != nil {
+		return "", err
+	}
+	progress.total(len(batches) + 3)
+
+	sgAuto, err := app.Aws.createSecurityGroup("sgAutoBox", "pepita stuff", pepa)
+	if err != nil {
+		return "", fmt.Errorf("error creating Security Group:\n%w", err)
+	}
+	for _, batchT := range batches {
+		step := fmt.Sprintf("batch lock %s", batchOrAll(batchT))
+		progress.start(step)
+		err = app.Aws.acquireLock(pepa, sgAuto, batchT)
+		progress.finish(step, "", err)
+		if err != nil {
+			return "", fmt.Errorf("not deleting, %w", err)
+		}
+		defer app.Aws.releaseLock(pepa, sgAuto, batchT)
+	}
+	if ctx.Err() != nil {
+		return "", cancelledError(ctx, 0, len(instanceIDs))
+	}
+
+	errs := []error{}
+	progress.start("Elastic IPs")
+	for _, batchT := range batches {
+		err = errors.Join(err, app.Aws.releaseElasticIPs(pepa, batchT, instanceIDs))
+	}
+	progress.finish("Elastic IPs", "released", err)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	progress.start("boxes")
+	err = app.Aws.terminateEC2Instances(pepa, instanceIDs)
+	progress.finish("boxes", fmt.Sprintf("%d terminated", len(instanceIDs)), err)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	progress.start("scripts")
+	folder := scriptsFolder(app)
+	err = clearScripts(folder, isStaleFile(app, only, targets.addresses))
+	progress.finish("scripts", folder, err)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	result := fmt.Sprintf("%d - Boxes Deleted!%s", len(instanceIDs), idleElasticIPsWarning(app, pepa))
+	return result, errors.Join(errs...)
+}
+
+// takes the lock of each batch, in order, for jobs changing several. release
+// drops the locks taken, none are left behind when one is held by somebody else
+func lockBatches(app *applicationMain, pepa *ec2.Client, batches ...string) (release func(), err error) {
+	sgAuto, err := app.Aws.createSecurityGroup("sgAutoBox", "pepita stuff", pepa)
+	if err != nil {
+		return nil, fmt.Errorf("error creating Security Group:\n%w", err)
+	}
+	locked := []string{}
+	release = func() {
+		for _, batchT := range locked {
+			app.Aws.releaseLock(pepa, sgAuto, batchT)
+		}
+	}
+	for _, batchT := range slices.Compact(slices.Sorted(slices.Values(batches))) {
+		err = app.Aws.acquireLock(pepa, sgAuto, batchT)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		locked = append(locked, batchT)
+	}
+	return release, nil
+}
+
+// live boxes a delete takes: every box of the batch, or the picked ones
+type deleteTargets struct {
+	instanceIDs []string
+	addresses   []string
+	batches     []string // as found
+	perBatch    map[string]int
+}
+
+func deleteTargetsAws(app *applicationMain, pepa *ec2.Client, only boxList) (deleteTargets, error) {
+	instances, err := app.Aws.batchInstances(pepa, only.lookupBatch(app.BatchTag), "pending", "running", "stopping", "stopped")
+	if err != nil {
+		return deleteTargets{}, fmt.Errorf("error listing boxes:\n%w", err)
+	}
+
+	targets := deleteTargets{perBatch: map[string]int{}}
+	for _, instance := range instances {
+		box := instanceIPs(instance)
+		address := app.Aws.boxAddress(box)
+		if !only.has(box.InstanceID, address) {
+			continue
+		}
+		targets.instanceIDs = append(targets.instanceIDs, box.InstanceID)
+		if address != "" {
+			targets.addresses = append(targets.addresses, address)
+		}
+		batchT := instanceTag(instance, "BatchTag")
+		if targets.perBatch[batchT] == 0 {
+			targets.batches = append(targets.batches, batchT)
+		}
+		targets.perBatch[batchT]++
+	}
+	return targets, nil
+}
+
+// the live boxes must be the planned ones, in any order
+func matchesPlan(planned, live []string) error {
+	if planned == nil || slices.Equal(slices.Sorted(slices.Values(planned)), slices.Sorted(slices.Values(live))) {
+		return nil
+	}
+	return fmt.Errorf("not deleting, the boxes changed since the summary (%d planned, %d live now), delete again to see what goes", len(planned), len(live))
+}
+
+// local files a delete clears: the scripts of the batch, every script and the
+// key pair's .pem when no batch, the scripts of the picked boxes by their
+// addresses
+func isStaleFile(app *applicationMain, only boxList, addresses []string) func(name string) bool {
+	return func(name string) bool {
+		ext := filepath.Ext(name)
+		switch {
+		case only.picked():
+			return isBoxScript(name, "", only, addresses)
+		case app.BatchTag == "":
+			return ext == ".ps1" || name == app.Aws.keyPairName()+".pem"
+		}
+		return isBoxScript(name, app.BatchTag, only, nil)
+	}
+}
+
+// files of folder that match, by name
+func matchingFiles(folder string, match func(name string) bool) ([]string, error) {
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() && match(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// removes the files of folder stale says are no longer needed
+func clearScripts(folder string, stale func(name string) bool) error {
+	names, err := matchingFiles(folder, stale)
+	if err != nil {
+		return fmt.Errorf("failed to clear scripts folder\n%w", err)
+	}
+	errs := []error{}
+	for _, name := range names {
+		err = os.Remove(filepath.Join(folder, name))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to clear scripts folder\n%w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// what deleteBoxes will remove, for the operator to confirm first
+type deletePlan struct {
+	perBatch   map[string]int // live boxes per batch
+	boxIDs     []string       // instance IDs, droplet IPs on DigitalOcean. Never nil
+	elasticIPs int
+	keyPair    string // deleted along with its .pem, empty when it stays
+	firewall   string
+	folder     string
+	files      []string
+	// typed to confirm: the batch, or the region when the delete is not
+	// limited to one batch
+	phrase string
+}
+
+func (p deletePlan) boxes() int {
+	total := 0
+	for _, count := range p.perBatch {
+		total += count
+	}
+	return total
+}
+
+func planDelete(app *applicationMain, only boxList) (deletePlan, error) {
+	plan := deletePlan{folder: scriptsFolder(app)}
+
+	if app.Provider == "digital" {
+		if only.picked() {
+			return plan, errors.New("deleting single droplets is only available for AWS, delete the batch instead")
+		}
+		ips, err := app.Digital.compileIPaddressesDigital(app.BatchTag)
+		if err != nil {
+			return plan, fmt.Errorf("error compiling IP addresses:\n%w", err)
+		}
+		plan.perBatch = map[string]int{app.BatchTag: len(ips)}
+		plan.boxIDs = append([]string{}, ips...)
+		plan.firewall = "DigitalOcean firewall deleted"
+		if app.BatchTag != "" {
+			plan.firewall = "DigitalOcean firewall kept"
+		}
+		plan.phrase = cmp.Or(app.BatchTag, app.Digital.Region)
+		plan.files, err = plannedFiles(plan.folder, isStaleFile(app, only, nil))
+		return plan, err
+	}
+
+	if !only.picked() {
+		if err := app.Aws.checkDeleteAll(app.BatchTag); err != nil {
+			return plan, err
+		}
+	}
+	pepa, err := app.Aws.createEc2Client()
+	if err != nil {
+		return plan, fmt.Errorf("error getting AWS credentials:\n%w", err)
+	}
+	targets, err := deleteTargetsAws(app, pepa, only)
+	if err != nil {
+		return plan, err
+	}
+	plan.perBatch = targets.perBatch
+	plan.boxIDs = append([]string{}, targets.instanceIDs...)
+
+	addresses, err := app.Aws.taggedElasticIPs(pepa, only.lookupBatch(app.BatchTag))
+	if err != nil {
+		return plan, fmt.Errorf("error listing Elastic IPs:\n%w", err)
+	}
+	for _, address := range addresses {
+		if !only.picked() || slices.Contains(targets.instanceIDs, aws.ToString(address.InstanceId)) {
+			plan.elasticIPs++
+		}
+	}
+
+	if !only.picked() && app.BatchTag == "" {
+		owned, err := app.Aws.ownsKeyPair(pepa)
+		if err != nil {
+			return plan, fmt.Errorf("error looking up the key pair:\n%w", err)
+		}
+		if owned {
+			plan.keyPair = app.Aws.keyPairName()
+		}
+	}
+	plan.firewall = fmt.Sprintf("%s security group kept", app.Aws.scopedName("sgAutoBox"))
+	plan.phrase = app.Aws.Region
+	if len(targets.batches) == 1 && targets.batches[0] != "" && (only.picked() || app.BatchTag != "") {
+		plan.phrase = targets.batches[0]
+	}
+	plan.files, err = plannedFiles(plan.folder, isStaleFile(app, only, targets.addresses))
+	return plan, err
+}
+
+// no scripts folder yet means no files to remove
+func plannedFiles(folder string, stale func(name string) bool) ([]string, error) {
+	files, err := matchingFiles(folder, stale)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return files, err
+}
+
+// writes a post launch script per box of the batch or per picked box, plus the
+// ssh config on AWS
+func createPostScripts(ctx context.Context, app *applicationMain, only boxList, progress progressFunc) (string, error) {
+	result := "Created Post Launch scripts"
+	errs := []error{}
+
+	if app.Provider == "digital" {
+		ips, err := pickedIPsDigital(app, only)
+		if err != nil {
+			return "", err
+		}
+		progress.total(len(ips))
+		for i, ip := range ips {
+			if ctx.Err() != nil {
+				errs = append(errs, cancelledError(ctx, i, len(ips)))
+				break
+			}
+			progress.start(ip)
+			err := app.createPostSCRIPT(ip, "")
+			progress.finish(ip, "script", err)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("error creating post script\n%w", err))
+			}
+		}
+		return result, errors.Join(errs...)
+	}
+
+	pepa, err := app.Aws.createEc2Client()
+	if err != nil {
+		return "", fmt.Errorf("error getting AWS credentials:\n%w", err)
+	}
+	boxes, err := pickedBoxesAws(app, pepa, only)
+	if err != nil {
+		return "", err
+	}
+	progress.total(len(boxes) + 1)
+
+	// the scripts run ssh -F with it
+	progress.start("ssh config")
+	sshConfig, err := writeBoxesSSHConfig(app, pepa, only, boxes)
+	progress.finish("ssh config", sshConfig, err)
+	if sshConfig == "" {
+		return "", err
+	}
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	// private boxes get their scripts on the private IP, reached through the bastion
+	for i, box := range boxes {
+		if ctx.Err() != nil {
+			return result, errors.Join(append(errs, cancelledError(ctx, i, len(boxes)))...)
+		}
+		address := app.Aws.boxAddress(box)
+		if address == "" {
+			continue
+		}
+		progress.start(box.InstanceID)
+		err := app.createPostSCRIPT(address, sshConfig)
+		progress.finish(box.InstanceID, address, err)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error creating post script\n%w", err))
+		}
+	}
+
+	result = fmt.Sprintf("%s\n\n%s", result, strings.Join(app.Aws.sshCommands(boxes, sshConfig), "\n"))
+	return result, errors.Join(errs...)
+}
+
+// writes the ssh config of the boxes for the post launch scripts to run ssh -F
+// with, private boxes jump through the bastion. The operator's own ssh config
+// is left alone. The path is set whenever the file was written, even with an
+// error finding the bastion
+func writeBoxesSSHConfig(app *applicationMain, pepa ec2API, only boxList, boxes []EC2InstanceIP) (string, error) {
+	errs := []error{}
+	bastion, err := app.Aws.resolveBastion(pepa, app.BatchTag)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error finding bastion\n%w", err))
+	}
+	sshConfig, err := app.Aws.writeSSHConfig(boxes, bastion, only.sshConfigName(app.BatchTag))
+	if err != nil {
+		return "", errors.Join(append(errs, fmt.Errorf("error creating ssh config\n%w", err))...)
+	}
+	return sshConfig, errors.Join(errs...)
+}
+
+// ssh config of every box of the batch, for scripts written a few at a time
+func writeBatchSSHConfig(app *applicationMain, pepa ec2API) (string, error) {
+	_, boxes, err := app.Aws.compileIPaddressesAws(pepa, app.BatchTag)
+	if err != nil {
+		return "", fmt.Errorf("error compiling IP addresses:\n%w", err)
+	}
+	return writeBoxesSSHConfig(app, pepa, nil, boxes)
+}
+
+// runs the post launch scripts of the batch, or of the picked boxes, side by side
+func runPostURLs(ctx context.Context, app *applicationMain, only boxList, progress progressFunc) (string, error) {
+	var wg sync.WaitGroup
+	folder := scriptsFolder(app)
+
+	files, err := os.ReadDir(folder)
+	if err != nil {
+		return "", fmt.Errorf("error executing scripts:\n%w", err)
+	}
+	addresses, err := pickedAddresses(app, only)
+	if err != nil {
+		return "", err
+	}
+	if app.Provider != "digital" {
+		// the scripts run ssh -F with the config of their boxes, whose public
+		// IPs and bastion change when they restart
+		pepa, err := app.Aws.createEc2Client()
+		if err != nil {
+			return "", fmt.Errorf("error getting AWS credentials:\n%w", err)
+		}
+		boxes, err := pickedBoxesAws(app, pepa, only)
+		if err != nil {
+			return "", err
+		}
+		_, err = writeBoxesSSHConfig(app, pepa, only, boxes)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	scripts := []string{}
+	for _, file := range files {
+		if filepath.Ext(file.Name()) == ".ps1" && isBoxScript(file.Name(), app.BatchTag, only, addresses) {
+			scripts = append(scripts, file.Name())
+		}
+	}
+	progress.total(len(scripts))
+
+	var mu sync.Mutex
+	errs := []error{}
+	started := 0
+	for _, script := range scripts {
+		if ctx.Err() != nil {
+			break
+		}
+		started++
+		wg.Add(1)
+		progress.start(script)
+		go func() {
+			defer wg.Done()
+			scriptPath, _ := filepath.Abs(filepath.Join(folder, script))
+			err := app.runPS1file(scriptPath, script)
+			progress.finish(script, "", err)
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+	if started < len(scripts) {
+		errs = append(errs, cancelledError(ctx, started, len(scripts)))
+	}
+	return "Finished Executing Post Launch Scripts", errors.Join(errs...)
+}
+
+// opens TightVNC on every box of the batch, or every picked box, that has a
+// post launch script
+func verifyBoxes(ctx context.Context, app *applicationMain, only boxList, progress progressFunc) (string, error) {
+	result := "Verified mofo!"
+	errs := []error{}
+	files, _ := os.ReadDir(scriptsFolder(app))
+
+	if app.Provider == "digital" {
+		ips, err := pickedIPsDigital(app, only)
+		if err != nil {
+			return "", err
+		}
+		progress.total(len(ips))
+		for i, ip := range ips {
+			if ctx.Err() != nil {
+				errs = append(errs, cancelledError(ctx, i, len(ips)))
+				break
+			}
+			for _, file := range files {
+				if isBoxScript(file.Name(), app.BatchTag, only, []string{ip}) {
+					progress.start(ip)
+					err := app.runVNC(ip)
+					progress.finish(ip, "TightVNC", err)
+					if err != nil {
+						errs = append(errs, fmt.Errorf("error running TightVNC\n%w", err))
+					}
+					// once per box
+					break
+				}
+			}
+		}
+		return result, errors.Join(errs...)
+	}
+
+	pepa, err := app.Aws.createEc2Client()
+	if err != nil {
+		return "", fmt.Errorf("error getting AWS credentials:\n%w", err)
+	}
+	boxes, err := pickedBoxesAws(app, pepa, only)
+	if err != nil {
+		return "", err
+	}
+	// private boxes are verified through a tunnel over the bastion, public ones
+	// still are when it cannot be found
+	bastion, err := app.Aws.resolveBastion(pepa, app.BatchTag)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error finding bastion\n%w", err))
+	}
+	sshConfig, err := app.Aws.writeSSHConfig(boxes, bastion, only.sshConfigName(app.BatchTag))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("error creating ssh config\n%w", err))
+	}
+	progress.total(len(boxes))
+	for i, box := range boxes {
+		if ctx.Err() != nil {
+			errs = append(errs, cancelledError(ctx, i, len(boxes)))
+			break
+		}
+		ip := app.Aws.boxAddress(box)
+		if ip == "" {
+			continue
+		}
+		for _, file := range files {
+			if isBoxScript(file.Name(), app.BatchTag, only, []string{ip}) {
+				progress.start(box.InstanceID)
+				err := runVNCAws(app, box, bastion, sshConfig, only.sshConfigName(app.BatchTag), 15901+i)
+				progress.finish(box.InstanceID, ip, err)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("error running TightVNC\n%w", err))
+				}
+				// once per box
+				break
+			}
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// boxes without a public IP are verified through an ssh tunnel over the bastion
+func runVNCAws(app *applicationMain, box EC2InstanceIP, bastion, sshConfig, batchT string, localPort int) error {
+	if box.PublicIP == "" && bastion == "" && app.Aws.Bastion != "" {
+		return fmt.Errorf("%s is private and the bastion was not found", box.InstanceID)
+	}
+	if box.PublicIP != "" || bastion == "" {
+		return app.runVNC(app.Aws.boxAddress(box))
+	}
+
+	tunnel, err := app.Aws.openVNCTunnel(sshConfig, batchT, box, localPort)
+	if err != nil {
+		return err
+	}
+	defer tunnel.Process.Kill()
+	time.Sleep(2 * time.Second)
+
+	// TightVNC takes host::port
+	return app.runVNC(fmt.Sprintf("127.0.0.1::%d", localPort))
+}
+
+// boxes of the batch, or the picked ones of any batch
+func pickedBoxesAws(app *applicationMain, pepa *ec2.Client, only boxList) ([]EC2InstanceIP, error) {
+	_, boxes, err := app.Aws.compileIPaddressesAws(pepa, only.lookupBatch(app.BatchTag))
+	if err != nil {
+		return nil, fmt.Errorf("error compiling IP addresses:\n%w", err)
+	}
+	return slices.DeleteFunc(boxes, func(box EC2InstanceIP) bool {
+		return !only.has(box.InstanceID, app.Aws.boxAddress(box))
+	}), nil
+}
+
+// DigitalOcean boxes are picked by IP
+func pickedIPsDigital(app *applicationMain, only boxList) ([]string, error) {
+	ips, err := app.Digital.compileIPaddressesDigital(only.lookupBatch(app.BatchTag))
+	if err != nil {
+		return nil, fmt.Errorf("error compiling IP addresses:\n%w", err)
+	}
+	return slices.DeleteFunc(ips, func(ip string) bool {
+		return !only.has(ip)
+	}), nil
+}
+
+// addresses of the picked boxes, nil when none are picked
+func pickedAddresses(app *applicationMain, only boxList) ([]string, error) {
+	if !only.picked() {
+		return nil, nil
+	}
+	if app.Provider == "digital" {
+		return pickedIPsDigital(app, only)
+	}
+
+	pepa, err := app.Aws.createEc2Client()
+	if err != nil {
+		return nil, fmt.Errorf("error getting AWS credentials:\n%w", err)
+	}
+	boxes, err := pickedBoxesAws(app, pepa, only)
+	if err != nil {
+		return nil, err
+	}
+	addresses := []string{}
+	for _, box := range boxes {
+		if address := app.Aws.boxAddress(box); address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses, nil
+}
+
+// script files are named after the batch and the address of their box, nil
+// addresses take every script of the batch. Scripts of picked boxes go by the
+// address alone
+func isBoxScript(name, batchT string, only boxList, addresses []string) bool {
+	scriptBatch, scriptAddress, ok := splitPostScriptName(name)
+	if !ok {
+		return false
+	}
+	if !only.picked() {
+		if batchT != "" && scriptBatch != batchT {
+			return false
+		}
+		if addresses == nil {
+			return true
+		}
+	}
+	return slices.Contains(addresses, scriptAddress)
+}
+
+// batch and address of a script named by postScriptName. Addresses have no
+// underscore, so the batch is all before the last one: web-prod_10.0.0.1.ps1
+// is batch web-prod, not web
+func splitPostScriptName(name string) (batchT, address string, ok bool) {
+	base, ok := strings.CutSuffix(name, ".ps1")
+	if !ok {
+		return "", "", false
+	}
+	i := strings.LastIndex(base, "_")
+	return base[:max(i, 0)], base[i+1:], true
+}
+
+func cancelledError(ctx context.Context, done, total int) error {
+	return fmt.Errorf("stopped after %d of %d: %w", done, total, ctx.Err())
+}
+
+// menu result for a job, errors first
+func jobResultMsg(result string, err error) backgroundJobMsg {
+	if err != nil {
+		result = strings.TrimSpace(fmt.Sprintf("%s\n\n%s", err, result))
+	}
+	return backgroundJobMsg{result: result, failed: err != nil, cancelled: errors.Is(err, context.Canceled)}
+}
+
+// menu result for a job that stopped at err
+func jobFailedMsg(result string, err error) backgroundJobMsg {
+	return backgroundJobMsg{result: result, failed: true, cancelled: errors.Is(err, context.Canceled)}
+}
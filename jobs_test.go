@@ -0,0 +1,136 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// a script belongs to its batch and address exactly, not to those that start
+// or end the same
+func TestIsBoxScript(t *testing.T) {
+	tests := []struct {
+		name      string
+		batchT    string
+		addresses []string
+		want      bool
+	}{
+		{"web_10.0.0.1.ps1", "web", nil, true},
+		{"web-prod_10.0.0.1.ps1", "web", nil, false},
+		{"web_prod_10.0.0.1.ps1", "web", nil, false},
+		{"web-prod_10.0.0.1.ps1", "web-prod", nil, true},
+		{"web_prod_10.0.0.1.ps1", "web_prod", nil, true},
+		{"web2_10.0.0.1.ps1", "web", nil, false},
+		{"web_10.0.0.1.ps1", "web-prod", nil, false},
+		{"web_10.0.0.1.ps1", "web", []string{"10.0.0.1"}, true},
+		{"web_10.0.0.12.ps1", "web", []string{"10.0.0.1"}, false},
+		{"web_11.2.3.45.ps1", "web", []string{"1.2.3.4"}, false},
+		{"web_2600-1f18--1.ipv6-literal.net.ps1", "web", []string{"2600-1f18--1.ipv6-literal.net"}, true},
+		{"10.0.0.1.ps1", "", []string{"10.0.0.1"}, true},
+		{"web-prod_10.0.0.1.ps1", "", nil, true},
+		{"web_10.0.0.1.txt", "web", nil, false},
+	}
+	for _, tt := range tests {
+		if got := isBoxScript(tt.name, tt.batchT, nil, tt.addresses); got != tt.want {
+			t.Errorf("isBoxScript(%q, %q, %v) = %t, want %t", tt.name, tt.batchT, tt.addresses, got, tt.want)
+		}
+	}
+}
+
+// batches web and web-prod side by side, each only touches its own scripts
+func TestBatchScriptsWebAndWebProd(t *testing.T) {
+	app := &applicationMain{Provider: "aws", BatchTag: "web"}
+	stale := isStaleFile(app, nil, nil)
+	for name, want := range map[string]bool{
+		"web_10.0.0.1.ps1":      true,
+		"web-prod_10.0.0.1.ps1": false,
+		"web-prod_10.0.0.2.ps1": false,
+	} {
+		if got := stale(name); got != want {
+			t.Errorf("batch web: stale(%q) = %t, want %t", name, got, want)
+		}
+	}
+
+	app.BatchTag = "web-prod"
+	for name, want := range map[string]bool{
+		"web_10.0.0.1.ps1":      false,
+		"web-prod_10.0.0.1.ps1": true,
+	} {
+		if got := stale(name); got != want {
+			t.Errorf("batch web-prod: stale(%q) = %t, want %t", name, got, want)
+		}
+	}
+}
+
+// the scripts of a picked box are its own, not those of boxes whose address
+// starts the same
+func TestIsStaleFile(t *testing.T) {
+	app := &applicationMain{Provider: "aws", BatchTag: "web"}
+	only := boxList{"i-1"}
+	stale := isStaleFile(app, only, []string{"10.0.0.1"})
+	for name, want := range map[string]bool{
+		"web_10.0.0.1.ps1":   true,
+		"web_10.0.0.12.ps1":  false,
+		"web_10.0.0.100.ps1": false,
+		"web_10.0.0.1.txt":   false,
+	} {
+		if got := stale(name); got != want {
+			t.Errorf("stale(%q) = %t, want %t", name, got, want)
+		}
+	}
+}
+
+// private boxes are reached through the ssh config of the batch, the
+// operator's own ssh config is never written to
+func TestPostScriptsLeaveUserSSHConfig(t *testing.T) {
+	chdirTemp(t)
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	userConfig := filepath.Join(home, ".ssh", "config")
+	err := os.MkdirAll(filepath.Dir(userConfig), 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := "Host old\n  User me\n"
+	err = os.WriteFile(userConfig, []byte(before), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := newFakeEC2()
+	client.addInstance("web", types.InstanceStateNameRunning, bastionRoleTag, "bastion")
+	private := client.addInstance("web", types.InstanceStateNameRunning)
+	client.instance(private).PublicIpAddress = nil
+	app := &applicationMain{Provider: "aws", BatchTag: "web", Aws: *testAWS()}
+	app.Aws.Bastion = "auto"
+
+	sshConfig, err := writeBatchSSHConfig(app, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := *client.instance(private).PrivateIpAddress
+	err = app.createPostSCRIPT(address, sshConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := os.ReadFile(userConfig)
+	if err != nil || string(after) != before {
+		t.Errorf("~/.ssh/config = %q, %v, want it unchanged", after, err)
+	}
+	script, err := os.ReadFile(filepath.Join(scriptsFolder(app), postScriptName("web", address)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(script), `ssh -F "`+sshConfig+`" `+address) {
+		t.Errorf("script does not run ssh -F with the batch config:\n%s", script)
+	}
+	config, err := os.ReadFile(sshConfig)
+	if err != nil || !strings.Contains(string(config), "ProxyJump "+app.Aws.bastionAlias("web")) {
+		t.Errorf("ssh config does not jump through the bastion: %v\n%s", err, config)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// log lines of the selected job shown under the list
+const jobLogLines = 12
+
+// jobs newest first, as the screen lists them
+func (r jobRunner) newestFirst() []*jobEntry {
+	entries := slices.Clone(r.entries)
+	slices.Reverse(entries)
+	return entries
+}
+
+func (m *MenuList) updateJobs(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	entries := m.jobs.newestFirst()
+	var selected *jobEntry
+	if m.jobCursor < len(entries) {
+		selected = entries[m.jobCursor]
+	}
+
+	switch keyMsg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+	case "up", "k":
+		if m.jobCursor > 0 {
+			m.jobCursor--
+		}
+	case "down", "j":
+		if m.jobCursor < len(entries)-1 {
+			m.jobCursor++
+		}
+	case "c":
+		if selected != nil {
+			m.jobs.cancel(selected.id)
+		}
+	case "r":
+		if selected == nil {
+			return m, nil
+		}
+		e, cmd := m.jobs.rerun(selected.id)
+		m.jobCursor = 0
+		if e.job.wait {
+			m.prevMenuState = StateJobs
+			m.spinner.Style = lipgloss.NewStyle().Foreground(lipgloss.Color(e.job.color))
+			m.state = StateSpinner
+		}
+		return m, tea.Batch(m.spinner.Tick, cmd)
+	case "enter":
+		// boxes a finished lookup found, or the action it planned
+		switch {
+		case selected == nil:
+		case selected.picker != nil:
+			m.prevMenuState = StateJobs
+			m.picker = newBoxPicker(selected.picker.title, selected.picker.items)
+			m.pickerAction = selected.picker.action
+			m.pickerTarget = selected.picker.target
+			m.state = StatePicker
+		case selected.confirm != nil:
+			m.prevMenuState = StateJobs
+			return m, m.openConfirm(*selected.confirm)
+		}
+	case "esc", "q":
+		m.state = StateMainMenu
+		m.prevMenuState = StateMainMenu
+	}
+	return m, nil
+}
+
+func (m MenuList) viewJobs() string {
+	promptStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor)).Bold(true)
+	detailStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	helpText := detailStyle.Render("↑/↓: select   c: cancel   r: re-run   enter: pick boxes or confirm   esc: back")
+
+	entries := m.jobs.newestFirst()
+	if len(entries) == 0 {
+		return fmt.Sprintf("\n\n%s\n\n%s\n\n%s", promptStyle.Render("JOBS"), itemStyle.Render("No jobs yet"), helpText)
+	}
+
+	now := time.Now()
+	rows := strings.Builder{}
+	for i, e := range entries {
+		elapsed := "-"
+		if !e.started.IsZero() {
+			elapsed = e.elapsed(now).String()
+		}
+		row := fmt.Sprintf("#%-3d %s %6s  %s", e.id, m.jobStatusView(e), elapsed, lipgloss.NewStyle().Foreground(lipgloss.Color(e.job.color)).Render(e.job.title))
+		if i == m.jobCursor {
+			rows.WriteString(selectedItemStyle.Render("> " + row))
+		} else {
+			rows.WriteString(itemStyle.Render(row))
+		}
+		rows.WriteString("\n")
+	}
+
+	selected := entries[min(m.jobCursor, len(entries)-1)]
+	detail := strings.Builder{}
+	if selected.status == jobRunning {
+		detail.WriteString(selected.checklist.view(m.spinner.View()))
+	}
+	log := selected.log[max(0, len(selected.log)-jobLogLines):]
+	for _, line := range log {
+		detail.WriteString(itemStyle.Render(detailStyle.Render(line)))
+		detail.WriteString("\n")
+	}
+	if selected.result != "" {
+		detail.WriteString("\n")
+		detail.WriteString(itemStyle.Render(lipgloss.NewStyle().Foreground(lipgloss.Color(textResultJob)).Render(selected.result)))
+		detail.WriteString("\n")
+	}
+
+	return fmt.Sprintf("\n\n%s\n\n%s\n%s\n\n%s\n%s",
+		promptStyle.Render("JOBS"), rows.String(), promptStyle.Render(fmt.Sprintf("#%d %s", selected.id, selected.job.title)), detail.String(), helpText)
+}
+
+func (m MenuList) jobStatusView(e *jobEntry) string {
+	status := fmt.Sprintf("%-10s", e.status)
+	if e.cancelling && e.status == jobRunning {
+		status = fmt.Sprintf("%-10s", "cancelling")
+	}
+	switch e.status {
+	case jobRunning:
+		return m.spinner.View() + " " + status
+	case jobDone:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("82")).Render("✓ " + status)
+	case jobFailed, jobCancelled:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color(textErrorColorBack)).Render("✗ " + status)
+	}
+	return "  " + status
+}
+
+// line above the menu while jobs run, or about the last one that ended
+func (m MenuList) viewJobsStatus() string {
+	running, queued := m.jobs.counts()
+	line := m.jobNotice
+	if running+queued > 0 {
+		line = fmt.Sprintf("%s Jobs: %d running, %d queued   %s", m.spinner.View(), running, queued, m.jobNotice)
+	}
+	if line == "" {
+		return ""
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(textJobOutcomeFront)).Render(line) + "\n"
+}
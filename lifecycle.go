@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+const stateWaitTimeout = 15 * time.Minute
+
+// AUTO-BOX instances of the batch (all batches when batchT is empty) in the given states
+func (a *AWS) batchInstances(client ec2API, batchT string, states ...string) ([]types.Instance, error) {
+	ctx := a.jobContext()
+
+	filters := a.ownedFilters()
+	if batchT != "" {
+		filters = append(filters, types.Filter{
+			Name:   aws.String("tag:BatchTag"),
+			Values: []string{batchT},
+		})
+	}
+	if len(states) > 0 {
+		filters = append(filters, types.Filter{
+			Name:   aws.String("instance-state-name"),
+			Values: states,
+		})
+	}
+
+	var instances []types.Instance
+	paginator := ec2.NewDescribeInstancesPaginator(client, &ec2.DescribeInstancesInput{
+		Filters: filters,
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, reservation := range resp.Reservations {
+			instances = append(instances, reservation.Instances...)
+		}
+	}
+
+	return instances, nil
+}
+
+func (a *AWS) batchInstanceIDs(client ec2API, batchT string, states ...string) ([]string, error) {
+	instances, err := a.batchInstances(client, batchT, states...)
+	if err != nil {
+		return nil, err
+	}
+
+	var instanceIDs []string
+	for _, instance := range instances {
+		instanceIDs = append(instanceIDs, *instance.InstanceId)
+	}
+	return instanceIDs, nil
+}
+
+// hibernates the instances launched with hibernation configured, the rest get a
+// regular stop. notHibernated lists those when asked to hibernate
+func (a *AWS) stopEC2Instances(client ec2API, instanceIDs []string, hibernate bool) (notHibernated []string, err error) {
+	ctx := a.jobContext()
+
+	if len(instanceIDs) == 0 {
+		return nil, fmt.Errorf("no boxes to stop")
+	}
+
+	hibernateIDs := []string{}
+	stopIDs := instanceIDs
+	if hibernate {
+		resp, err := client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+			InstanceIds: instanceIDs,
+		})
+		if err != nil {
+			return nil, err
+		}
+		stopIDs = []string{}
+		for _, reservation := range resp.Reservations {
+			for _, instance := range reservation.Instances {
+				if instance.HibernationOptions != nil && aws.ToBool(instance.HibernationOptions.Configured) {
+					hibernateIDs = append(hibernateIDs, *instance.InstanceId)
+				} else {
+					stopIDs = append(stopIDs, *instance.InstanceId)
+				}
+			}
+		}
+	}
+
+	if hibernate {
+		notHibernated = stopIDs
+	}
+
+	if len(hibernateIDs) > 0 {
+		_, err := client.StopInstances(ctx, &ec2.StopInstancesInput{
+			InstanceIds: hibernateIDs,
+			Hibernate:   aws.Bool(true),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(stopIDs) > 0 {
+		_, err := client.StopInstances(ctx, &ec2.StopInstancesInput{
+			InstanceIds: stopIDs,
+		})
+		if err != nil {
+			return notHibernated, err
+		}
+	}
+
+	waiter := ec2.NewInstanceStoppedWaiter(client)
+	return notHibernated, waiter.Wait(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: instanceIDs,
+	}, stateWaitTimeout)
+}
+
+func (a *AWS) startEC2Instances(client ec2API, instanceIDs []string) error {
+	ctx := a.jobContext()
+
+	if len(instanceIDs) == 0 {
+		return fmt.Errorf("no boxes to start")
+	}
+
+	_, err := client.StartInstances(ctx, &ec2.StartInstancesInput{
+		InstanceIds: instanceIDs,
+	})
+	if err != nil {
+		return err
+	}
+
+	waiter := ec2.NewInstanceRunningWaiter(client)
+	return waiter.Wait(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: instanceIDs,
+	}, stateWaitTimeout)
+}
+
+// reboot keeps the instance running, wait for the status checks to pass again
+func (a *AWS) rebootEC2Instances(client ec2API, instanceIDs []string) error {
+	ctx := a.jobContext()
+
+	if len(instanceIDs) == 0 {
+		return fmt.Errorf("no boxes to reboot")
+	}
+
+	_, err := client.RebootInstances(ctx, &ec2.RebootInstancesInput{
+		InstanceIds: instanceIDs,
+	})
+	if err != nil {
+		return err
+	}
+
+	waiter := ec2.NewInstanceStatusOkWaiter(client)
+	return waiter.Wait(ctx, &ec2.DescribeInstanceStatusInput{
+		InstanceIds: instanceIDs,
+	}, stateWaitTimeout)
+}
+
+// terminates the given boxes only, deleteEC2Instances takes the whole batch.
+// Their persistent spot requests are cancelled first, AWS would launch the
+// boxes again otherwise
+func (a *AWS) terminateEC2Instances(client ec2API, instanceIDs []string) error {
+	ctx := a.jobContext()
+
+	if len(instanceIDs) == 0 {
+		return fmt.Errorf("no boxes to terminate")
+	}
+
+	resp, err := client.DescribeSpotInstanceRequests(ctx, &ec2.DescribeSpotInstanceRequestsInput{
+		Filters: []types.Filter{
+			{
+				Name:   aws.String("instance-id"),
+				Values: instanceIDs,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	requestIDs := []string{}
+	for _, request := range resp.SpotInstanceRequests {
+		requestIDs = append(requestIDs, aws.ToString(request.SpotInstanceRequestId))
+	}
+	if len(requestIDs) > 0 {
+		_, err = client.CancelSpotInstanceRequests(ctx, &ec2.CancelSpotInstanceRequestsInput{
+			SpotInstanceRequestIds: requestIDs,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = client.TerminateInstances(ctx, &ec2.TerminateInstancesInput{
+		InstanceIds: instanceIDs,
+	})
+	return err
+}
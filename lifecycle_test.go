@@ -0,0 +1,209 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestBatchInstanceIDs(t *testing.T) {
+	client := newFakeEC2()
+	webRunning := client.addInstance("web", types.InstanceStateNameRunning)
+	webStopped := client.addInstance("web", types.InstanceStateNameStopped)
+	dbRunning := client.addInstance("db", types.InstanceStateNameRunning)
+	client.addInstance("db", types.InstanceStateNameTerminated)
+
+	tests := []struct {
+		name   string
+		batchT string
+		states []string
+		want   []string
+	}{
+		{"batch in every state", "web", nil, []string{webRunning, webStopped}},
+		{"batch running", "web", []string{"running"}, []string{webRunning}},
+		{"all batches running", "", []string{"running"}, []string{webRunning, dbRunning}},
+		{"all batches live", "", []string{"pending", "running", "stopping", "stopped"}, []string{webRunning, webStopped, dbRunning}},
+		{"unknown batch", "nope", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := testAWS().batchInstanceIDs(client, tt.batchT, tt.states...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("batchInstanceIDs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStopEC2Instances(t *testing.T) {
+	tests := []struct {
+		name          string
+		hibernate     bool
+		wantStopCalls int
+		wantHibernate bool
+	}{
+		{"stop", false, 1, false},
+		{"hibernate only the configured box", true, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeEC2()
+			configured := client.addInstance("web", types.InstanceStateNameRunning)
+			plain := client.addInstance("web", types.InstanceStateNameRunning)
+			client.instance(configured).HibernationOptions = &types.HibernationOptions{Configured: aws.Bool(true)}
+
+			notHibernated, err := testAWS().stopEC2Instances(client, []string{configured, plain}, tt.hibernate)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantNotHibernated := []string{}
+			if tt.hibernate {
+				wantNotHibernated = []string{plain}
+			}
+			if !slices.Equal(notHibernated, wantNotHibernated) {
+				t.Errorf("not hibernated %v, want %v", notHibernated, wantNotHibernated)
+			}
+			if len(client.stopInputs) != tt.wantStopCalls {
+				t.Fatalf("StopInstances called %d times, want %d", len(client.stopInputs), tt.wantStopCalls)
+			}
+			var hibernated []string
+			for _, input := range client.stopInputs {
+				if aws.ToBool(input.Hibernate) {
+					hibernated = append(hibernated, input.InstanceIds...)
+				}
+			}
+			if tt.wantHibernate && !slices.Equal(hibernated, []string{configured}) {
+				t.Errorf("hibernated %v, want %v", hibernated, []string{configured})
+			}
+			if !tt.wantHibernate && len(hibernated) > 0 {
+				t.Errorf("hibernated %v, want none", hibernated)
+			}
+			for _, instanceID := range []string{configured, plain} {
+				if state := client.instance(instanceID).State.Name; state != types.InstanceStateNameStopped {
+					t.Errorf("%s is %s, want stopped", instanceID, state)
+				}
+			}
+		})
+	}
+}
+
+// boxes launched the way createEC2Instance launches them stop and hibernate,
+// a one-time spot box is refused like EC2 refuses it
+func TestStopDeployedBoxes(t *testing.T) {
+	client := newFakeEC2()
+	client.images = testImages()
+	a := testAWS()
+	plain, err := a.createEC2Instance("sg-1", client, "web", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Hibernation = true
+	hibernating, err := a.createEC2Instance("sg-1", client, "web", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notHibernated, err := a.stopEC2Instances(client, []string{plain, hibernating}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(notHibernated, []string{plain}) {
+		t.Errorf("not hibernated %v, want %v", notHibernated, []string{plain})
+	}
+	for _, instanceID := range []string{plain, hibernating} {
+		if state := client.instance(instanceID).State.Name; state != types.InstanceStateNameStopped {
+			t.Errorf("%s is %s, want stopped", instanceID, state)
+		}
+	}
+
+	client.spotRequests[0].Type = types.SpotInstanceTypeOneTime
+	if _, err := a.stopEC2Instances(client, []string{plain}, false); err == nil {
+		t.Error("one-time spot box stopped")
+	}
+}
+
+func TestPowerNoBoxes(t *testing.T) {
+	a := testAWS()
+	client := newFakeEC2()
+
+	tests := []struct {
+		name string
+		run  func() error
+	}{
+		{"stop", func() error {
+			_, err := a.stopEC2Instances(client, nil, false)
+			return err
+		}},
+		{"start", func() error { return a.startEC2Instances(client, nil) }},
+		{"reboot", func() error { return a.rebootEC2Instances(client, nil) }},
+		{"terminate", func() error { return a.terminateEC2Instances(client, nil) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.run() == nil {
+				t.Error("expected an error for an empty box list")
+			}
+		})
+	}
+	if len(client.calls) != 0 {
+		t.Errorf("EC2 called with no boxes: %v", client.calls)
+	}
+}
+
+func TestStartRebootEC2Instances(t *testing.T) {
+	a := testAWS()
+	client := newFakeEC2()
+	instanceID := client.addInstance("web", types.InstanceStateNameStopped)
+
+	err := a.startEC2Instances(client, []string{instanceID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state := client.instance(instanceID).State.Name; state != types.InstanceStateNameRunning {
+		t.Errorf("box is %s after start, want running", state)
+	}
+
+	err = a.rebootEC2Instances(client, []string{instanceID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.callCount("RebootInstances") != 1 || client.callCount("DescribeInstanceStatus") == 0 {
+		t.Errorf("reboot did not wait for the status checks: %v", client.calls)
+	}
+}
+
+func TestTerminateEC2Instances(t *testing.T) {
+	client := newFakeEC2()
+	client.images = testImages()
+	picked, err := testAWS().createEC2Instance("sg-1", client, "web", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, err := testAWS().createEC2Instance("sg-1", client, "web", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = testAWS().terminateEC2Instances(client, []string{picked})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a persistent request left open launches the box again
+	for _, request := range client.spotRequests {
+		cancelled := request.State == types.SpotInstanceStateCancelled
+		if cancelled != (aws.ToString(request.InstanceId) == picked) {
+			t.Errorf("spot request of %s is %s", aws.ToString(request.InstanceId), request.State)
+		}
+	}
+	if state := client.instance(picked).State.Name; state != types.InstanceStateNameTerminated {
+		t.Errorf("picked box is %s, want terminated", state)
+	}
+	if state := client.instance(kept).State.Name; state != types.InstanceStateNameRunning {
+		t.Errorf("box of the same batch is %s, want running", state)
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// Locks are tags on the AUTO-BOX security group, one per workspace and batch:
+// AUTO-BOX-LOCK:<workspace>/<batch> = "<holder> <since> <expires>".
+// An empty batch locks every batch of the workspace (DELETE all).
+const (
+	lockTagPrefix = "AUTO-BOX-LOCK:"
+	lockTTL       = 2 * time.Hour
+)
+
+type batchLock struct {
+	Workspace string
+	Batch     string
+	Holder    string
+	Since     time.Time
+	Expires   time.Time
+}
+
+// returned while another operator holds a lock on the batch
+type lockedError struct {
+	Lock batchLock
+}
+
+func (e *lockedError) Error() string {
+	return fmt.Sprintf("batch '%s' locked by %s since %s (expires %s)\nFORCE Unlock the batch if they are gone",
+		e.Lock.batchName(), e.Lock.Holder, e.Lock.Since.Local().Format("2006-01-02 15:04"), e.Lock.Expires.Local().Format("15:04"))
+}
+
+func (l batchLock) batchName() string {
+	if l.Batch == "" {
+		return "ALL"
+	}
+	return l.Batch
+}
+
+func (l batchLock) tagKey() string {
+	return lockTagPrefix + l.Workspace + "/" + l.Batch
+}
+
+func (l batchLock) tagValue() string {
+	return fmt.Sprintf("%s %s %s", l.Holder, l.Since.UTC().Format(time.RFC3339), l.Expires.UTC().Format(time.RFC3339))
+}
+
+// same batch, or one of them locks the whole workspace. A lock without a
+// workspace on every batch covers all workspaces, like DELETE without one does
+func (l batchLock) conflicts(other batchLock) bool {
+	if l.Workspace == other.Workspace {
+		return l.Batch == other.Batch || l.Batch == "" || other.Batch == ""
+	}
+	return (l.Workspace == "" && l.Batch == "") || (other.Workspace == "" && other.Batch == "")
+}
+
+func parseLockTag(tag types.Tag) (batchLock, bool) {
+	key := aws.ToString(tag.Key)
+	if !strings.HasPrefix(key, lockTagPrefix) {
+		return batchLock{}, false
+	}
+	workspace, batchT, found := strings.Cut(strings.TrimPrefix(key, lockTagPrefix), "/")
+	if !found {
+		return batchLock{}, false
+	}
+
+	// holder may contain spaces, the two times are always the last fields
+	fields := strings.Fields(aws.ToString(tag.Value))
+	if len(fields) < 3 {
+		return batchLock{}, false
+	}
+	since, err := time.Parse(time.RFC3339, fields[len(fields)-2])
+	if err != nil {
+		return batchLock{}, false
+	}
+	expires, err := time.Parse(time.RFC3339, fields[len(fields)-1])
+	if err != nil {
+		return batchLock{}, false
+	}
+	return batchLock{
+		Workspace: workspace,
+		Batch:     batchT,
+		Holder:    strings.Join(fields[:len(fields)-2], " "),
+		Since:     since,
+		Expires:   expires,
+	}, true
+}
+
+// LockHolder names the operator in lock messages, user@host when empty
+func (a *AWS) lockHolder() string {
+	if a.LockHolder != "" {
+		return a.LockHolder
+	}
+	name := "unknown"
+	if current, err := user.Current(); err == nil {
+		// DOMAIN\user on Windows
+		name = current.Username[strings.LastIndex(current.Username, `\`)+1:]
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		return name
+	}
+	return name + "@" + host
+}
+
+// all locks on the security group, expired ones included. Not stopped with
+// the job, a cancelled job still releases its lock
+func (a *AWS) batchLocks(client ec2API, sgID string) ([]batchLock, error) {
+	resp, err := client.DescribeSecurityGroups(context.Background(), &ec2.DescribeSecurityGroupsInput{
+		GroupIds: []string{sgID},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	locks := []batchLock{}
+	for _, group := range resp.SecurityGroups {
+		for _, tag := range group.Tags {
+			if lock, ok := parseLockTag(tag); ok {
+				locks = append(locks, lock)
+			}
+		}
+	}
+	return locks, nil
+}
+
+// first live lock of somebody else that conflicts with want, the older one
+// wins when two operators tagged at the same time
+func blockingLock(locks []batchLock, want batchLock, now time.Time) (batchLock, bool) {
+	for _, lock := range locks {
+		if lock.Holder == want.Holder || !lock.Expires.After(now) || !lock.conflicts(want) {
+			continue
+		}
+		if lock.Since.After(want.Since) || (lock.Since.Equal(want.Since) && lock.Holder > want.Holder) {
+			continue
+		}
+		return lock, true
+	}
+	return batchLock{}, false
+}
+
+// takes the advisory lock on the batch for deploy/delete/scale, returns a
+// *lockedError while another operator holds it. Taking it again refreshes it.
+func (a *AWS) acquireLock(client ec2API, sgID, batchT string) error {
+	ctx := a.jobContext()
+	now := time.Now().UTC().Truncate(time.Second)
+	want := batchLock{
+		Workspace: a.Workspace,
+		Batch:     batchT,
+		Holder:    a.lockHolder(),
+		Since:     now,
+		Expires:   now.Add(lockTTL),
+	}
+
+	locks, err := a.batchLocks(client, sgID)
+	if err != nil {
+		return err
+	}
+	if lock, blocked := blockingLock(locks, want, now); blocked {
+		return &lockedError{Lock: lock}
+	}
+
+	_, err = client.CreateTags(ctx, &ec2.CreateTagsInput{
+		Resources: []string{sgID},
+		Tags: []types.Tag{
+			{
+				Key:   aws.String(want.tagKey()),
+				Value: aws.String(want.tagValue()),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// somebody may have tagged between the read and the write, read again
+	locks, err = a.batchLocks(client, sgID)
+	if err != nil {
+		return err
+	}
+	for _, lock := range locks {
+		if lock.tagKey() == want.tagKey() && lock.Holder != want.Holder {
+			return &lockedError{Lock: lock}
+		}
+	}
+	if lock, blocked := blockingLock(locks, want, now); blocked {
+		_ = a.releaseLock(client, sgID, batchT)
+		return &lockedError{Lock: lock}
+	}
+	return nil
+}
+
+// drops the lock on the batch if this operator holds it
+func (a *AWS) releaseLock(client ec2API, sgID, batchT string) error {
+	want := batchLock{Workspace: a.Workspace, Batch: batchT}
+	locks, err := a.batchLocks(client, sgID)
+	if err != nil {
+		return err
+	}
+	for _, lock := range locks {
+		if lock.tagKey() == want.tagKey() && lock.Holder == a.lockHolder() {
+			return deleteLockTag(client, sgID, lock)
+		}
+	}
+	return nil
+}
+
+// drops the lock on the batch whoever holds it, found is false when there was none
+func (a *AWS) forceUnlock(client ec2API, sgID, batchT string) (lock batchLock, found bool, err error) {
+	want := batchLock{Workspace: a.Workspace, Batch: batchT}
+	locks, err := a.batchLocks(client, sgID)
+	if err != nil {
+		return batchLock{}, false, err
+	}
+	for _, lock := range locks {
+		if lock.tagKey() == want.tagKey() {
+			return lock, true, deleteLockTag(client, sgID, lock)
+		}
+	}
+	return batchLock{}, false, nil
+}
+
+// not stopped with the job either
+func deleteLockTag(client ec2API, sgID string, lock batchLock) error {
+	_, err := client.DeleteTags(context.Background(), &ec2.DeleteTagsInput{
+		Resources: []string{sgID},
+		Tags: []types.Tag{
+			{
+				Key:   aws.String(lock.tagKey()),
+				Value: aws.String(lock.tagValue()),
+			},
+		},
+	})
+	return err
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// security group with the lock already tagged on it
+func lockedGroup(t *testing.T, client *fakeEC2, held ...batchLock) string {
+	t.Helper()
+
+	sgID, err := testAWS().createSecurityGroup("sgAutoBox", "test", client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags := client.resourceTags(sgID)
+	for _, lock := range held {
+		*tags = append(*tags, types.Tag{Key: aws.String(lock.tagKey()), Value: aws.String(lock.tagValue())})
+	}
+	return sgID
+}
+
+func TestAcquireLock(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	held := func(workspace, batchT, holder string, expires time.Duration) batchLock {
+		return batchLock{Workspace: workspace, Batch: batchT, Holder: holder, Since: now.Add(-time.Minute), Expires: now.Add(expires)}
+	}
+
+	tests := []struct {
+		name       string
+		workspace  string
+		batchT     string
+		held       []batchLock
+		wantLocked bool
+	}{
+		{"free", "", "web", nil, false},
+		{"other batch", "", "web", []batchLock{held("", "db", "bob", time.Hour)}, false},
+		{"same batch", "", "web", []batchLock{held("", "web", "bob", time.Hour)}, true},
+		{"my own lock", "", "web", []batchLock{held("", "web", "alice", time.Hour)}, false},
+		{"expired", "", "web", []batchLock{held("", "web", "bob", -time.Minute)}, false},
+		{"all batches held", "", "web", []batchLock{held("", "", "bob", time.Hour)}, true},
+		{"deleting all while a batch is held", "", "", []batchLock{held("", "web", "bob", time.Hour)}, true},
+		{"other workspace", "team-a", "web", []batchLock{held("team-b", "web", "bob", time.Hour)}, false},
+		{"no workspace deleting all", "team-a", "web", []batchLock{held("", "", "bob", time.Hour)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeEC2()
+			sgID := lockedGroup(t, client, tt.held...)
+			a := testAWS()
+			a.Workspace = tt.workspace
+			a.LockHolder = "alice"
+
+			err := a.acquireLock(client, sgID, tt.batchT)
+			var locked *lockedError
+			if errors.As(err, &locked) != tt.wantLocked {
+				t.Fatalf("err = %v, want locked %t", err, tt.wantLocked)
+			}
+			if tt.wantLocked {
+				if locked.Lock.Holder != "bob" {
+					t.Errorf("locked by %s, want bob", locked.Lock.Holder)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			locks, err := a.batchLocks(client, sgID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mine := 0
+			for _, lock := range locks {
+				if lock.Holder == "alice" && lock.Batch == tt.batchT && lock.Workspace == tt.workspace {
+					mine++
+					if !lock.Expires.After(time.Now()) {
+						t.Errorf("lock expires %s, already past", lock.Expires)
+					}
+				}
+			}
+			if mine != 1 {
+				t.Errorf("%d locks held by alice, want 1: %+v", mine, locks)
+			}
+		})
+	}
+}
+
+func TestReleaseLock(t *testing.T) {
+	client := newFakeEC2()
+	sgID := lockedGroup(t, client)
+	alice, bob := testAWS(), testAWS()
+	alice.LockHolder, bob.LockHolder = "alice", "bob"
+
+	err := alice.acquireLock(client, sgID, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// only the holder releases
+	err = bob.releaseLock(client, sgID, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = bob.acquireLock(client, sgID, "web")
+	if !errors.As(err, new(*lockedError)) {
+		t.Fatalf("bob took alice's lock: %v", err)
+	}
+
+	err = alice.releaseLock(client, sgID, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = bob.acquireLock(client, sgID, "web")
+	if err != nil {
+		t.Fatalf("lock not released: %v", err)
+	}
+}
+
+func TestForceUnlock(t *testing.T) {
+	client := newFakeEC2()
+	sgID := lockedGroup(t, client)
+	alice, bob := testAWS(), testAWS()
+	alice.LockHolder, bob.LockHolder = "alice", "bob"
+
+	err := alice.acquireLock(client, sgID, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock, found, err := bob.forceUnlock(client, sgID, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found || lock.Holder != "alice" {
+		t.Errorf("forceUnlock = %+v, %t, want alice's lock", lock, found)
+	}
+	_, found, err = bob.forceUnlock(client, sgID, "web")
+	if err != nil || found {
+		t.Errorf("second forceUnlock found %t, err %v", found, err)
+	}
+	err = bob.acquireLock(client, sgID, "web")
+	if err != nil {
+		t.Fatalf("lock still held after force unlock: %v", err)
+	}
+}
+
+func TestParseLockTag(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    string
+		value  string
+		want   batchLock
+		wantOK bool
+	}{
+		{"batch", "AUTO-BOX-LOCK:/web", "alice@pc 2026-01-02T10:00:00Z 2026-01-02T12:00:00Z",
+			batchLock{Batch: "web", Holder: "alice@pc", Since: time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC), Expires: time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)}, true},
+		{"holder with spaces", "AUTO-BOX-LOCK:team-a/", "Jane Doe@pc 2026-01-02T10:00:00Z 2026-01-02T12:00:00Z",
+			batchLock{Workspace: "team-a", Holder: "Jane Doe@pc", Since: time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC), Expires: time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)}, true},
+		{"other tag", "AUTO-BOX", "true", batchLock{}, false},
+		{"bad time", "AUTO-BOX-LOCK:/web", "alice yesterday later", batchLock{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lock, ok := parseLockTag(types.Tag{Key: aws.String(tt.key), Value: aws.String(tt.value)})
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %t, want %t", ok, tt.wantOK)
+			}
+			if lock != tt.want {
+				t.Errorf("lock = %+v, want %+v", lock, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	app, err := loadSettings()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitFailed)
+	}
+
+	if len(os.Args) > 1 && IsCLICommand(os.Args[1]) {
+		os.Exit(RunCLI(app, os.Args[1:]))
+	}
+	ShowMenu(app)
+}
@@ -0,0 +1,609 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest lists every batch that should exist. AWS batches are counted by
+// their BatchTag, DigitalOcean ones by their AUTO-BOX-BATCH tag
+type Manifest struct {
+	Batches []ManifestBatch `json:"batches" yaml:"batches"`
+}
+
+// InstanceType and Ami are the size and image slug on DigitalOcean
+type ManifestBatch struct {
+	Name          string            `json:"name" yaml:"name"`
+	Provider      string            `json:"provider" yaml:"provider"`
+	Region        string            `json:"region" yaml:"region"`
+	InstanceType  string            `json:"instancetype" yaml:"instancetype"`
+	Ami           string            `json:"ami" yaml:"ami"`
+	Count         int               `json:"count" yaml:"count"`
+	Tags          map[string]string `json:"tags" yaml:"tags"`
+	SecurityRules []SecurityRule    `json:"securityrules" yaml:"securityrules"`
+	PostLaunchURL string            `json:"postlaunchurl" yaml:"postlaunchurl"`
+	UserData      string            `json:"userdata" yaml:"userdata"`
+}
+
+// what plan found for one batch, apply fills in Applied
+type manifestPlan struct {
+	Batch   ManifestBatch
+	Live    int
+	Drift   []manifestDrift
+	Applied string
+	Err     error
+}
+
+// ways the live batch can differ from the manifest besides the count
+const (
+	driftTags          = "tags"
+	driftUserData      = "user data"
+	driftInstanceType  = "instance type"
+	driftSecurityRules = "security rules"
+)
+
+// one way the live batch differs from the manifest. Apply fixes the tags and
+// the security rules of AWS batches, the rest says why it is left as it is
+type manifestDrift struct {
+	Kind   string
+	Detail string
+	// boxes that differ, none for the security group
+	InstanceIDs []string
+	// why apply leaves it, empty when apply fixes it
+	NotApplied string
+	// what apply did about it
+	Applied string
+	Err     error
+
+	// the batch's own security group and the rules to add and drop from it
+	groupID        string
+	missing, extra []ingressRule
+}
+
+// one protocol, port and CIDR a security group lets in
+type ingressRule struct {
+	Protocol string
+	Port     int32
+	Cidr     string
+}
+
+func ingressRules(permission types.IpPermission) []ingressRule {
+	rules := []ingressRule{}
+	for _, ipRange := range permission.IpRanges {
+		rules = append(rules, ingressRule{aws.ToString(permission.IpProtocol), aws.ToInt32(permission.FromPort), aws.ToString(ipRange.CidrIp)})
+	}
+	for _, ipRange := range permission.Ipv6Ranges {
+		rules = append(rules, ingressRule{aws.ToString(permission.IpProtocol), aws.ToInt32(permission.FromPort), aws.ToString(ipRange.CidrIpv6)})
+	}
+	return rules
+}
+
+func (r ingressRule) String() string {
+	return fmt.Sprintf("%s %d %s", r.Protocol, r.Port, r.Cidr)
+}
+
+func (r ingressRule) permission() types.IpPermission {
+	return (&AWS{}).securityPermission(SecurityRule{Protocol: r.Protocol, Port: r.Port, Cidr: r.Cidr})
+}
+
+func loadManifest(path string) (Manifest, error) {
+	manifest := Manifest{}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return manifest, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &manifest)
+	default:
+		err = json.Unmarshal(data, &manifest)
+	}
+	if err != nil {
+		return manifest, fmt.Errorf("error reading manifest %s: %w", path, err)
+	}
+
+	for i, batch := range manifest.Batches {
+		if batch.Name == "" {
+			return manifest, fmt.Errorf("batch #%d has no name", i+1)
+		}
+		if batch.Count < 0 {
+			return manifest, fmt.Errorf("batch %s has a negative count", batch.Name)
+		}
+		switch batch.Provider {
+		case "", "aws":
+			manifest.Batches[i].Provider = "aws"
+		case "digital":
+			if _, err := digitalBatchTag(batch.Name); err != nil {
+				return manifest, err
+			}
+		default:
+			return manifest, fmt.Errorf("batch %s has unknown provider %s", batch.Name, batch.Provider)
+		}
+	}
+
+	return manifest, nil
+}
+
+// swaps the batch settings into app while fn runs so the regular
+// deploy & post launch script code works on the batch
+func withBatchSettings(app *applicationMain, batch ManifestBatch, fn func()) {
+	provider, batchTag, url, digital := app.Provider, app.BatchTag, app.URL, app.Digital
+	awsRegion, instanceType, amiID, amiMap := app.Aws.Region, app.Aws.InstanceType, app.Aws.AmiID, app.Aws.AmiMap
+	userData, tags, rules := app.Aws.UserData, app.Aws.Tags, app.Aws.SecurityRules
+	defer func() {
+		app.Provider, app.BatchTag, app.URL, app.Digital = provider, batchTag, url, digital
+		app.Aws.Region, app.Aws.InstanceType, app.Aws.AmiID, app.Aws.AmiMap = awsRegion, instanceType, amiID, amiMap
+		app.Aws.UserData, app.Aws.Tags, app.Aws.SecurityRules = userData, tags, rules
+	}()
+
+	app.Provider = batch.Provider
+	app.BatchTag = batch.Name
+	if batch.PostLaunchURL != "" {
+		app.URL = batch.PostLaunchURL
+	}
+	if batch.Provider == "digital" {
+		if batch.Region != "" {
+			app.Digital.Region = batch.Region
+		}
+		if batch.InstanceType != "" {
+			app.Digital.Size = batch.InstanceType
+		}
+		if batch.Ami != "" {
+			app.Digital.Image = batch.Ami
+		}
+		fn()
+		return
+	}
+
+	if batch.Region != "" {
+		app.Aws.Region = batch.Region
+	}
+	if batch.InstanceType != "" {
+		app.Aws.InstanceType = batch.InstanceType
+	}
+	if batch.Ami != "" {
+		app.Aws.AmiMap = maps.Clone(amiMap)
+		app.Aws.setAmi(batch.Ami)
+	}
+	app.Aws.UserData = batch.UserData
+	app.Aws.Tags = batch.Tags
+	app.Aws.SecurityRules = batch.SecurityRules
+
+	fn()
+}
+
+// batches with their own firewall rules get their own security group
+func batchSecurityGroupName(batch ManifestBatch) string {
+	if len(batch.SecurityRules) > 0 {
+		return fmt.Sprintf("sgAutoBox-%s", batch.Name)
+	}
+	return "sgAutoBox"
+}
+
+func planManifest(app *applicationMain, manifest Manifest) []manifestPlan {
+	plans := []manifestPlan{}
+
+	for _, batch := range manifest.Batches {
+		plan := manifestPlan{Batch: batch}
+
+		withBatchSettings(app, batch, func() {
+			if batch.Provider == "digital" {
+				plan.Live, plan.Drift, plan.Err = app.Digital.planBatch(batch)
+				return
+			}
+			pepa, err := app.Aws.createEc2Client()
+			if err != nil {
+				plan.Err = err
+				return
+			}
+			plan.Live, plan.Drift, plan.Err = app.Aws.planBatch(pepa, batch)
+		})
+
+		plans = append(plans, plan)
+	}
+
+	return plans
+}
+
+// live boxes of the batch (app settings swapped in) and how they differ from it
+func (a *AWS) planBatch(client ec2API, batch ManifestBatch) (int, []manifestDrift, error) {
+	live, err := a.batchInstances(client, batch.Name, "pending", "running", "stopping", "stopped")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	drift := []manifestDrift{}
+	wrongType, wrongTags, wrongUserData := []string{}, []string{}, []string{}
+	tagKeys := map[string]bool{}
+	for _, instance := range live {
+		instanceID := *instance.InstanceId
+		if string(instance.InstanceType) != a.InstanceType {
+			wrongType = append(wrongType, instanceID)
+		}
+		differs := false
+		for key, value := range batch.Tags {
+			if got, ok := tagLookup(instance.Tags, key); !ok || got != value {
+				differs, tagKeys[key] = true, true
+			}
+		}
+		if differs {
+			wrongTags = append(wrongTags, instanceID)
+		}
+		// the user data of a box is only readable one box at a time
+		attribute, err := client.DescribeInstanceAttribute(a.jobContext(), &ec2.DescribeInstanceAttributeInput{
+			InstanceId: instance.InstanceId,
+			Attribute:  types.InstanceAttributeNameUserData,
+		})
+		if err != nil {
+			return 0, nil, err
+		}
+		userData := ""
+		if attribute.UserData != nil && attribute.UserData.Value != nil {
+			decoded, err := base64.StdEncoding.DecodeString(*attribute.UserData.Value)
+			if err != nil {
+				return 0, nil, fmt.Errorf("user data of %s: %w", instanceID, err)
+			}
+			userData = string(decoded)
+		}
+		if userData != batch.UserData {
+			wrongUserData = append(wrongUserData, instanceID)
+		}
+	}
+
+	if len(wrongTags) > 0 {
+		drift = append(drift, manifestDrift{
+			Kind:        driftTags,
+			Detail:      fmt.Sprintf("%d boxes differ on %s", len(wrongTags), strings.Join(slices.Sorted(maps.Keys(tagKeys)), ", ")),
+			InstanceIDs: wrongTags,
+		})
+	}
+	if len(wrongUserData) > 0 {
+		drift = append(drift, manifestDrift{
+			Kind:        driftUserData,
+			Detail:      fmt.Sprintf("%d boxes were launched with other user data", len(wrongUserData)),
+			InstanceIDs: wrongUserData,
+			NotApplied:  "user data only runs at launch, scale the boxes down and up again",
+		})
+	}
+	if len(wrongType) > 0 {
+		drift = append(drift, manifestDrift{
+			Kind:        driftInstanceType,
+			Detail:      fmt.Sprintf("%d boxes are not %s", len(wrongType), a.InstanceType),
+			InstanceIDs: wrongType,
+			NotApplied:  "use RESIZE Boxes",
+		})
+	}
+
+	rules, err := a.securityRulesDrift(client, batch)
+	if err != nil {
+		return 0, nil, err
+	}
+	if rules != nil {
+		drift = append(drift, *rules)
+	}
+	return len(live), drift, nil
+}
+
+// how the batch's own security group differs from its rules, nil when it
+// matches or is still to be created
+func (a *AWS) securityRulesDrift(client ec2API, batch ManifestBatch) (*manifestDrift, error) {
+	if len(batch.SecurityRules) == 0 {
+		return nil, nil
+	}
+	vpcID, err := a.resolveVpcID(client)
+	if err != nil {
+		return nil, err
+	}
+	sgName := a.scopedName(batchSecurityGroupName(batch))
+	group, err := a.findSecurityGroup(client, sgName, vpcID)
+	if err != nil || group == nil {
+		return nil, err
+	}
+
+	want, have := map[ingressRule]bool{}, map[ingressRule]bool{}
+	for _, rule := range batch.SecurityRules {
+		for _, r := range ingressRules(a.securityPermission(rule)) {
+			want[r] = true
+		}
+	}
+	for _, permission := range group.IpPermissions {
+		for _, r := range ingressRules(permission) {
+			have[r] = true
+		}
+	}
+	drift := manifestDrift{Kind: driftSecurityRules, groupID: *group.GroupId}
+	for r := range want {
+		if !have[r] {
+			drift.missing = append(drift.missing, r)
+		}
+	}
+	for r := range have {
+		if !want[r] {
+			drift.extra = append(drift.extra, r)
+		}
+	}
+	if len(drift.missing) == 0 && len(drift.extra) == 0 {
+		return nil, nil
+	}
+
+	byRule := func(x, y ingressRule) int { return strings.Compare(x.String(), y.String()) }
+	slices.SortFunc(drift.missing, byRule)
+	slices.SortFunc(drift.extra, byRule)
+	details := []string{}
+	if len(drift.missing) > 0 {
+		details = append(details, fmt.Sprintf("misses %s", joinRules(drift.missing)))
+	}
+	if len(drift.extra) > 0 {
+		details = append(details, fmt.Sprintf("also lets in %s", joinRules(drift.extra)))
+	}
+	drift.Detail = fmt.Sprintf("%s %s", sgName, strings.Join(details, ", "))
+	return &drift, nil
+}
+
+func joinRules(rules []ingressRule) string {
+	names := []string{}
+	for _, r := range rules {
+		names = append(names, r.String())
+	}
+	return strings.Join(names, ", ")
+}
+
+// fixes the tags and security rules drift, gone are boxes terminated meanwhile
+func (a *AWS) applyDrift(client ec2API, batch ManifestBatch, drift *manifestDrift, gone []string) {
+	ctx := a.jobContext()
+
+	switch {
+	case drift.NotApplied != "":
+		return
+	case drift.Kind == driftTags:
+		instanceIDs := slices.DeleteFunc(slices.Clone(drift.InstanceIDs), func(instanceID string) bool {
+			return slices.Contains(gone, instanceID)
+		})
+		if len(instanceIDs) == 0 {
+			drift.Applied = "boxes terminated"
+			return
+		}
+		tags := []types.Tag{}
+		for _, key := range slices.Sorted(maps.Keys(batch.Tags)) {
+			tags = append(tags, types.Tag{Key: aws.String(key), Value: aws.String(batch.Tags[key])})
+		}
+		_, drift.Err = client.CreateTags(ctx, &ec2.CreateTagsInput{
+			Resources: instanceIDs,
+			Tags:      tags,
+		})
+		drift.Applied = fmt.Sprintf("retagged %d boxes", len(instanceIDs))
+	case drift.Kind == driftSecurityRules:
+		for _, r := range drift.missing {
+			_, err := client.AuthorizeSecurityGroupIngress(ctx, &ec2.AuthorizeSecurityGroupIngressInput{
+				GroupId:       aws.String(drift.groupID),
+				IpPermissions: []types.IpPermission{r.permission()},
+			})
+			if err != nil {
+				drift.Err = err
+				return
+			}
+		}
+		for _, r := range drift.extra {
+			_, err := client.RevokeSecurityGroupIngress(ctx, &ec2.RevokeSecurityGroupIngressInput{
+				GroupId:       aws.String(drift.groupID),
+				IpPermissions: []types.IpPermission{r.permission()},
+			})
+			if err != nil {
+				drift.Err = err
+				return
+			}
+		}
+		drift.Applied = fmt.Sprintf("added %d rules, removed %d", len(drift.missing), len(drift.extra))
+	}
+}
+
+// live droplets of the batch (app settings swapped in). Droplets are resized
+// and firewalled as a whole elsewhere, what the manifest sets for that is not applied
+func (d *Digital) planBatch(batch ManifestBatch) (int, []manifestDrift, error) {
+	tag, err := digitalBatchTag(batch.Name)
+	if err != nil {
+		return 0, nil, err
+	}
+	droplets, err := d.taggedDroplets(tag)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	drift := []manifestDrift{}
+	if len(batch.Tags) > 0 {
+		drift = append(drift, manifestDrift{
+			Kind:       driftTags,
+			Detail:     fmt.Sprintf("%d set in the manifest", len(batch.Tags)),
+			NotApplied: "DigitalOcean tags carry no values, droplets only get their batch tag",
+		})
+	}
+	if batch.UserData != "" {
+		drift = append(drift, manifestDrift{
+			Kind:       driftUserData,
+			Detail:     "set in the manifest",
+			NotApplied: "droplets are launched without user data",
+		})
+	}
+	wrongSize := []string{}
+	for _, droplet := range droplets {
+		if droplet.SizeSlug != "" && droplet.SizeSlug != d.Size {
+			wrongSize = append(wrongSize, droplet.Name)
+		}
+	}
+	if len(wrongSize) > 0 {
+		drift = append(drift, manifestDrift{
+			Kind:        driftInstanceType,
+			Detail:      fmt.Sprintf("%d droplets are not %s", len(wrongSize), d.Size),
+			InstanceIDs: wrongSize,
+			NotApplied:  "resize is only available for AWS",
+		})
+	}
+	if len(batch.SecurityRules) > 0 {
+		drift = append(drift, manifestDrift{
+			Kind:       driftSecurityRules,
+			Detail:     fmt.Sprintf("%d set in the manifest", len(batch.SecurityRules)),
+			NotApplied: fmt.Sprintf("every droplet shares the %s firewall", digitalFirewall),
+		})
+	}
+	return len(droplets), drift, nil
+}
+
+// whether apply has something to fix in the batch besides its count
+func (plan manifestPlan) fixesDrift() bool {
+	return slices.ContainsFunc(plan.Drift, func(drift manifestDrift) bool {
+		return drift.NotApplied == ""
+	})
+}
+
+// progress gets a step per box scaled and per drift fixed
+func applyManifest(app *applicationMain, manifest Manifest, progress progressFunc) []manifestPlan {
+	plans := planManifest(app, manifest)
+
+	for i := range plans {
+		plan := &plans[i]
+		if plan.Err != nil || (plan.Live == plan.Batch.Count && !plan.fixesDrift()) {
+			continue
+		}
+
+		withBatchSettings(app, plan.Batch, func() {
+			if plan.Batch.Provider == "digital" {
+				launched, deleted, err := app.Digital.scaleBatch(plan.Batch.Name, plan.Batch.Count, progress)
+				plan.Applied = fmt.Sprintf("launched %d, deleted %d", launched, deleted)
+				plan.Err = err
+				return
+			}
+
+			pepa, err := app.Aws.createEc2Client()
+			if err != nil {
+				plan.Err = err
+				return
+			}
+			release, err := lockBatches(app, pepa, plan.Batch.Name)
+			if err != nil {
+				plan.Err = fmt.Errorf("not applying, %w", err)
+				return
+			}
+			defer release()
+
+			gone := []string{}
+			if plan.Live != plan.Batch.Count {
+				err = app.Aws.createPEMFile(pepa)
+				if err != nil {
+					plan.Err = err
+					return
+				}
+				sgAuto, err := app.Aws.createSecurityGroup(batchSecurityGroupName(plan.Batch), "pepita stuff", pepa)
+				if err != nil {
+					plan.Err = err
+					return
+				}
+				scaled, err := app.Aws.scaleEC2Batch(pepa, sgAuto, plan.Batch.Name, plan.Batch.Count, nil, progress)
+				plan.Applied = fmt.Sprintf("launched %d, terminated %d", len(scaled.Launched), len(scaled.Terminated))
+				// scripts of the boxes scaled before an error too
+				plan.Err = errors.Join(err, updateScaledScripts(app, pepa, scaled))
+				if plan.Err != nil {
+					return
+				}
+				for _, box := range scaled.Terminated {
+					gone = append(gone, box.InstanceID)
+				}
+			}
+			for d := range plan.Drift {
+				drift := &plan.Drift[d]
+				if drift.NotApplied != "" {
+					continue
+				}
+				step := fmt.Sprintf("%s %s", plan.Batch.Name, drift.Kind)
+				progress.start(step)
+				app.Aws.applyDrift(pepa, plan.Batch, drift, gone)
+				progress.finish(step, drift.Applied, drift.Err)
+			}
+		})
+	}
+
+	return plans
+}
+
+func renderManifestPlan(plans []manifestPlan) string {
+	lines := []string{}
+	for _, plan := range plans {
+		line := fmt.Sprintf("%-8s %-15s %-12s live %d -> %d", plan.Batch.Provider, plan.Batch.Name, plan.Batch.Region, plan.Live, plan.Batch.Count)
+		switch {
+		case plan.Err != nil:
+			line = fmt.Sprintf("%s  ERROR: %s", line, plan.Err)
+		case plan.Applied != "":
+			line = fmt.Sprintf("%s  %s", line, plan.Applied)
+		case plan.Live < plan.Batch.Count:
+			line = fmt.Sprintf("%s  +%d", line, plan.Batch.Count-plan.Live)
+		case plan.Live > plan.Batch.Count:
+			line = fmt.Sprintf("%s  -%d", line, plan.Live-plan.Batch.Count)
+		default:
+			line = fmt.Sprintf("%s  no changes", line)
+		}
+		lines = append(lines, line)
+		for _, drift := range plan.Drift {
+			outcome := "applied by APPLY"
+			switch {
+			case drift.Err != nil:
+				outcome = fmt.Sprintf("ERROR: %s", drift.Err)
+			case drift.Applied != "":
+				outcome = drift.Applied
+			case drift.NotApplied != "":
+				outcome = fmt.Sprintf("not applied, %s", drift.NotApplied)
+			}
+			lines = append(lines, fmt.Sprintf("    %s: %s  (%s)", drift.Kind, drift.Detail, outcome))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// plans (or applies) a manifest for the CLI, the error joins the errors of its batches
+func runManifest(app *applicationMain, path string, apply bool, progress progressFunc) (string, error) {
+	manifest, err := loadManifest(path)
+	if err != nil {
+		return "", err
+	}
+
+	var plans []manifestPlan
+	if apply {
+		plans = applyManifest(app, manifest, progress)
+	} else {
+		plans = planManifest(app, manifest)
+	}
+
+	err = manifestErrors(plans)
+	if err != nil {
+		return renderManifestPlan(plans), fmt.Errorf("manifest %s finished with errors\n%w", path, err)
+	}
+	return renderManifestPlan(plans), nil
+}
+
+// errors of the batches and of fixing their drift, nil when there are none
+func manifestErrors(plans []manifestPlan) error {
+	errs := []error{}
+	for _, plan := range plans {
+		if plan.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", plan.Batch.Name, plan.Err))
+		}
+		for _, drift := range plan.Drift {
+			if drift.Err != nil {
+				errs = append(errs, fmt.Errorf("%s %s: %w", plan.Batch.Name, drift.Kind, drift.Err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLoadManifest(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		provider string
+		wantErr  bool
+	}{
+		{"aws by default", "batches:\n  - name: web\n    count: 2\n", "aws", false},
+		{"aws", "batches:\n  - name: web\n    provider: aws\n    count: 2\n", "aws", false},
+		{"DigitalOcean", "batches:\n  - name: web\n    provider: digital\n    count: 2\n", "digital", false},
+		{"DigitalOcean batch tag", "batches:\n  - name: web app\n    provider: digital\n", "", true},
+		{"unknown provider", "batches:\n  - name: web\n    provider: gcp\n", "", true},
+		{"negative count", "batches:\n  - name: web\n    count: -1\n", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "manifest.yaml")
+			err := os.WriteFile(path, []byte(tt.manifest), 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			manifest, err := loadManifest(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %t", err, tt.wantErr)
+			}
+			if err == nil && manifest.Batches[0].Provider != tt.provider {
+				t.Errorf("provider = %q, want %s", manifest.Batches[0].Provider, tt.provider)
+			}
+		})
+	}
+}
+
+func TestPlanAndApplyBatchDrift(t *testing.T) {
+	client := newFakeEC2()
+	client.images = testImages()
+	a := testAWS()
+	a.Tags = map[string]string{"team": "web"}
+	a.UserData = "echo web"
+	a.SecurityRules = []SecurityRule{{Protocol: "tcp", Port: 22}}
+	sgID, err := a.createSecurityGroup("sgAutoBox-web", "pepita stuff", client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if _, err := a.createEC2Instance(sgID, client, "web", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the manifest moved on from what the boxes were launched with
+	batch := ManifestBatch{
+		Name:          "web",
+		Tags:          map[string]string{"team": "api"},
+		UserData:      "echo api",
+		SecurityRules: []SecurityRule{{Protocol: "tcp", Port: 443}},
+	}
+	a.InstanceType = "m5.large"
+	live, drift, err := a.planBatch(client, batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if live != 2 {
+		t.Errorf("live = %d, want 2", live)
+	}
+	kinds := []string{}
+	for _, d := range drift {
+		kinds = append(kinds, d.Kind)
+	}
+	if !slices.Equal(kinds, []string{driftTags, driftUserData, driftInstanceType, driftSecurityRules}) {
+		t.Fatalf("drift = %v, want every kind", kinds)
+	}
+	for _, d := range drift {
+		fixed := d.Kind == driftTags || d.Kind == driftSecurityRules
+		if fixed != (d.NotApplied == "") {
+			t.Errorf("%s not applied %q", d.Kind, d.NotApplied)
+		}
+	}
+
+	for i := range drift {
+		a.applyDrift(client, batch, &drift[i], nil)
+		if drift[i].Err != nil {
+			t.Errorf("%s: %s", drift[i].Kind, drift[i].Err)
+		}
+	}
+	_, drift, err = a.planBatch(client, batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds = kinds[:0]
+	for _, d := range drift {
+		kinds = append(kinds, d.Kind)
+	}
+	if !slices.Equal(kinds, []string{driftUserData, driftInstanceType}) {
+		t.Errorf("drift after apply = %v, want what apply leaves", kinds)
+	}
+}
+
+func TestPlanDigitalManifest(t *testing.T) {
+	fake, d := newFakeDigital(t)
+	for range 2 {
+		if err := d.createBox("web"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	app := &applicationMain{Digital: *d}
+	manifest := Manifest{Batches: []ManifestBatch{
+		{Name: "web", Provider: "digital", InstanceType: "s-2vcpu-2gb", Count: 1, Tags: map[string]string{"team": "web"}},
+	}}
+
+	plans := planManifest(app, manifest)
+	if plans[0].Err != nil {
+		t.Fatal(plans[0].Err)
+	}
+	if plans[0].Live != 2 || len(plans[0].Drift) != 2 || plans[0].fixesDrift() {
+		t.Errorf("plan = %+v, want 2 live with size and tags drift not applied", plans[0])
+	}
+	if app.Digital.Size != "s-1vcpu-1gb" {
+		t.Errorf("size %s left swapped in", app.Digital.Size)
+	}
+
+	plans = applyManifest(app, manifest, nil)
+	if plans[0].Err != nil || plans[0].Applied != "launched 0, deleted 1" {
+		t.Errorf("apply = %q %v, want one droplet deleted", plans[0].Applied, plans[0].Err)
+	}
+	if len(fake.droplets) != 1 {
+		t.Errorf("%d droplets left, want 1", len(fake.droplets))
+	}
+}
@@ -0,0 +1,2169 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	digitalColorFront   = "39"
+	awsColorFront       = "220"
+	headerColorFront    = "46"
+	manifestColorFront  = "39"
+	batchTagColor       = "197"
+	lipTitleStyle       = lipgloss.NewStyle().MarginLeft(2).Foreground(lipgloss.Color("205"))
+	itemStyle           = lipgloss.NewStyle().PaddingLeft(4)
+	selectedItemStyle   = lipgloss.NewStyle().PaddingLeft(2).Foreground(lipgloss.Color("170"))
+	paginationStyle     = list.DefaultStyles().PaginationStyle.PaddingLeft(4)
+	helpStyle           = list.DefaultStyles().HelpStyle.PaddingLeft(4).PaddingBottom(1)
+	textPromptColor     = "141" //"100" //nice: 141
+	textInputColor      = "193" //"40" //nice: 193
+	textErrorColorBack  = "1"
+	textErrorColorFront = "15"
+	textResultJob       = "141" //PINK"205"
+	textJobOutcomeFront = "216"
+
+	menuTOP = []string{
+		"TAG deployment",
+		"DEPLOY Boxes",
+		"CREATE Post Launch Scripts",
+		"RUN Post Launch URLs",
+		"VERIFY Boxes (TightVNC)",
+		"DELETE Boxes",
+		"Toggle Provider",
+		"Enter API Token",
+		"Enter AWS Key",
+		"Enter AWS Secret",
+		"Set Region to deploy",
+		"Set # of Boxes to deploy",
+		"Set URL Post Launch",
+		"Set AMI to deploy",
+		"COPY AMI to Region",
+		"POWER Boxes (stop/start/reboot/hibernate)",
+		"RESIZE Boxes",
+		"SCALE Batch to # of Boxes",
+		"PLAN Manifest",
+		"APPLY Manifest",
+		"GET Windows Passwords",
+		"DIAGNOSE Box (console + screenshot)",
+		"Toggle Elastic IPs",
+		"Set VPC & Subnets",
+		"Toggle AZ spread",
+		"Set Placement Group",
+		"Toggle Private boxes (no public IP)",
+		"Set Bastion host",
+		"Toggle IPv6",
+		"Toggle Hibernation (encrypted root volume)",
+		"Set AWS Endpoint",
+		"ADOPT Boxes (launched outside AUTO-BOX)",
+		"RETAG Batches (rename/move/merge)",
+		"Set Workspace",
+		"CLAIM Boxes into Workspace",
+		"FORCE Unlock Batch",
+		"Set Hourly Rates (inventory cost)",
+		"INVENTORY (table of boxes)",
+		"JOBS (queue, logs & results)",
+		"Save Settings",
+	}
+)
+
+// App States
+type MenuState int
+
+const (
+	StateMainMenu MenuState = iota
+	StateSettingsMenu
+	StateResultDisplay
+	StateSpinner
+	StateTextInput
+	StatePicker
+	StateJobs
+	StateInventory
+	StateConfirm
+)
+
+// Messsage returend when the background job finishes
+type backgroundJobMsg struct {
+	result string
+	failed bool
+	// the job stopped because it was cancelled
+	cancelled bool
+	// settings the job changed on its copy, applied to the menu's settings
+	settings func(app *applicationMain)
+}
+
+// // message returned when you have to continue the prompting of data
+//
+//	type continueJobs struct {
+//		result string
+//	}
+type JobList int
+
+type MenuList struct {
+	list                list.Model
+	choice              string
+	header              string
+	state               MenuState
+	prevState           MenuState
+	prevMenuState       MenuState
+	spinner             spinner.Model
+	backgroundJobResult string
+	textInput           textinput.Model
+	inputPrompt         string
+	textInputError      bool
+	jobOutcome          string
+	amiHeader           string
+	amiGen              int
+	picker              boxPicker
+	pickerAction        string
+	pickerTarget        string
+	jobs                jobRunner
+	jobNotice           string
+	jobCursor           int
+	inventory           inventoryScreen
+	confirm             confirmDialog
+	app                 *applicationMain
+}
+
+func (m MenuList) Init() tea.Cmd {
+	return lookupAmiHeader(m.amiGen, m.app)
+}
+
+// AMI line of the header, dropped when gen is not the latest lookup
+type amiHeaderMsg struct {
+	gen    int
+	header string
+}
+
+// resolves the AMI off the Update loop, the header says so meanwhile
+func (m *MenuList) loadAmiHeader() tea.Cmd {
+	m.amiGen++
+	if m.app.Provider == "aws" {
+		m.amiHeader = "AMI: looking up..."
+	}
+	return lookupAmiHeader(m.amiGen, m.app)
+}
+
+func lookupAmiHeader(gen int, app *applicationMain) tea.Cmd {
+	if app.Provider != "aws" {
+		return nil
+	}
+	snapshot := snapshotApp(app)
+	return func() tea.Msg {
+		return amiHeaderMsg{gen: gen, header: snapshot.Aws.amiHeader()}
+	}
+}
+
+func (m MenuList) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch event := msg.(type) {
+	case progressEvent:
+		// keep reading progress whatever the state, the job blocks otherwise
+		return m, m.jobs.progress(event)
+	case jobDoneMsg:
+		return m.jobDone(event)
+	case inventoryMsg:
+		return m, m.inventory.listed(event)
+	case amiHeaderMsg:
+		if event.gen == m.amiGen {
+			m.amiHeader = event.header
+		}
+		return m, nil
+	case inventoryTickMsg:
+		// the refresh stops once the table is off screen
+		if m.state != StateInventory || event.gen != m.inventory.gen {
+			return m, nil
+		}
+		return m, m.inventory.load(m.app)
+	case spinner.TickMsg:
+		// the spinner keeps going on every screen while jobs run
+		running, _ := m.jobs.counts()
+		if m.state != StateSpinner && running == 0 {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+	}
+	return m.updateState(msg)
+}
+
+func (m MenuList) updateState(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch m.state {
+	case StateMainMenu:
+		return m.updateMainMenu(msg)
+	case StateSpinner:
+		return m.updateSpinner(msg)
+	case StateTextInput:
+		return m.updateTextInput(msg)
+	case StateResultDisplay:
+		return m.updateResultDisplay(msg)
+	case StatePicker:
+		return m.updatePicker(msg)
+	case StateJobs:
+		return m.updateJobs(msg)
+	case StateInventory:
+		return m.updateInventory(msg)
+	case StateConfirm:
+		return m.updateConfirm(msg)
+	default:
+		return m, nil
+	}
+}
+
+// the result of a job the menu waits on is shown right away, the others
+// wait on the jobs screen
+func (m MenuList) jobDone(msg jobDoneMsg) (tea.Model, tea.Cmd) {
+	e, waited, next := m.jobs.done(msg)
+	if e == nil {
+		return m, nil
+	}
+	if done, ok := msg.msg.(backgroundJobMsg); ok && done.settings != nil {
+		done.settings(m.app)
+		m.header = m.app.getAppHeader()
+	}
+	if !waited {
+		m.jobNotice = fmt.Sprintf("Job #%d %s: %s", e.id, e.status, e.job.title)
+		return m, next
+	}
+	if e.confirm != nil {
+		// the plan as the entry keeps it, tagged with the job
+		msg.msg = *e.confirm
+	}
+	model, cmd := m.updateState(msg.msg)
+	return model, tea.Batch(cmd, next)
+}
+
+func (m *MenuList) updateMainMenu(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	// case tea.MouseMsg:
+	// 	if msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft {
+	// 		err := clipboard.WriteAll(m.headerIP)
+	// 		if err != nil {
+	// 			fmt.Println("Failed to copy to clipboard:", err)
+	// 		}
+	// 	}
+	// 	return m, nil
+	case tea.KeyMsg:
+		switch keypress := msg.String(); keypress {
+		case "q", "ctrl+c", "Q":
+			return m, tea.Quit
+		case "r", "R":
+			m.header = m.app.getAppHeader()
+			return m, m.loadAmiHeader()
+		case "enter":
+			i, ok := m.list.SelectedItem().(item)
+			if ok {
+				m.choice = string(i)
+				switch m.choice {
+				case menuTOP[6]:
+					if m.app.Provider == "digital" {
+						m.app.Provider = "aws"
+						manifestColorFront = awsColorFront
+					} else if m.app.Provider == "aws" {
+						m.app.Provider = "digital"
+						manifestColorFront = digitalColorFront
+					}
+
+					m.header = m.app.getAppHeader()
+					return m, m.loadAmiHeader()
+				case menuTOP[7]:
+					m.prevMenuState = m.state
+					m.prevState = m.state
+					m.state = StateTextInput
+					m.inputPrompt = menuTOP[7]
+					m.textInput = textinput.New()
+					m.textInput.Placeholder = "e.g., dop_v1_a0xx"
+					m.textInput.Focus()
+					m.textInput.CharLimit = 200
+					m.textInput.Width = 200
+					m.textInput.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor))
+					m.textInput.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textInputColor))
+					return m, nil
+				case menuTOP[8]:
+					m.prevMenuState = m.state
+					m.prevState = m.state
+					m.state = StateTextInput
+					m.inputPrompt = menuTOP[8]
+					m.textInput = textinput.New()
+					m.textInput.Placeholder = "e.g., AKIAYxx"
+					m.textInput.Focus()
+					m.textInput.CharLimit = 200
+					m.textInput.Width = 200
+					m.textInput.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor))
+					m.textInput.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textInputColor))
+					return m, nil
+				case menuTOP[9]:
+					m.prevMenuState = m.state
+					m.prevState = m.state
+					m.state = StateTextInput
+					m.inputPrompt = menuTOP[9]
+					m.textInput = textinput.New()
+					m.textInput.Placeholder = "e.g., it's a secret.."
+					m.textInput.Focus()
+					m.textInput.CharLimit = 200
+					m.textInput.Width = 200
+					m.textInput.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor))
+					m.textInput.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textInputColor))
+					return m, nil
+				case menuTOP[12]:
+					m.prevMenuState = m.state
+					m.prevState = m.state
+					m.state = StateTextInput
+					m.inputPrompt = menuTOP[12]
+					m.textInput = textinput.New()
+					m.textInput.Placeholder = "e.g., https://www.whatever.com"
+					m.textInput.Focus()
+					m.textInput.CharLimit = 200
+					m.textInput.Width = 200
+					m.textInput.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor))
+					m.textInput.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textInputColor))
+					return m, nil
+				case menuTOP[10]:
+					m.prevMenuState = m.state
+					m.prevState = m.state
+					m.state = StateTextInput
+					m.inputPrompt = menuTOP[10]
+					m.textInput = textinput.New()
+					m.textInput.Placeholder = "e.g., us-east-1"
+					m.textInput.Focus()
+					m.textInput.CharLimit = 200
+					m.textInput.Width = 200
+					m.textInput.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor))
+					m.textInput.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textInputColor))
+					return m, nil
+				case menuTOP[11]:
+					m.prevMenuState = m.state
+					m.prevState = m.state
+					m.state = StateTextInput
+					m.inputPrompt = menuTOP[11]
+					m.textInput = textinput.New()
+					m.textInput.Placeholder = "e.g., 5"
+					m.textInput.Focus()
+					m.textInput.CharLimit = 10
+					m.textInput.Width = 10
+					m.textInput.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor))
+					m.textInput.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textInputColor))
+					return m, nil
+				case menuTOP[0]:
+					m.prevMenuState = m.state
+					m.prevState = m.state
+					m.state = StateTextInput
+					m.inputPrompt = menuTOP[0]
+					m.textInput = textinput.New()
+					m.textInput.Placeholder = "e.g., TAG you're it"
+					m.textInput.Focus()
+					m.textInput.CharLimit = 10
+					m.textInput.Width = 10
+					m.textInput.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor))
+					m.textInput.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textInputColor))
+					return m, nil
+				case menuTOP[1]:
+					m.prevState = m.state
+					m.prevMenuState = m.state
+					return m, m.startJob(backgroundJobCreateBox())
+				case menuTOP[2]:
+					m.prevState = m.state
+					m.prevMenuState = m.state
+					return m, m.startJob(backgroundJobPS1scripts(nil))
+				case menuTOP[3]:
+					m.prevState = m.state
+					m.prevMenuState = m.state
+					return m, m.startJob(backgroundJobRunPostURL(nil))
+					// m.prevMenuState = m.state
+					// m.prevState = m.state
+					// m.state = StateTextInput
+					// m.inputPrompt = menuTOP[6]
+					// m.textInput = textinput.New()
+					// m.textInput.Placeholder = "e.g., Type All or box #"
+					// m.textInput.Focus()
+					// m.textInput.CharLimit = 10
+					// m.textInput.Width = 10
+					// m.textInput.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor))
+					// m.textInput.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textInputColor))
+					// return m, nil
+				case menuTOP[4]:
+					m.prevState = m.state
+					m.prevMenuState = m.state
+					return m, m.startJob(backgroundJobVerifyVNC(nil))
+				case menuTOP[5]:
+					m.prevState = m.state
+					m.prevMenuState = m.state
+					return m, m.startDelete(nil)
+				case menuTOP[13]:
+					m.prevMenuState = m.state
+					m.prevState = m.state
+					m.state = StateTextInput
+					m.inputPrompt = menuTOP[13]
+					m.textInput = textinput.New()
+					m.textInput.Placeholder = "e.g., ami-0abc123, amazon:Windows_Server-2022-English-Full-Base-* or /aws/service/ami-windows-latest/..."
+					m.textInput.Focus()
+					m.textInput.CharLimit = 200
+					m.textInput.Width = 200
+					m.textInput.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor))
+					m.textInput.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textInputColor))
+					return m, nil
+				case menuTOP[14]:
+					m.prevMenuState = m.state
+					m.prevState = m.state
+					m.state = StateTextInput
+					m.inputPrompt = menuTOP[14]
+					m.textInput = textinput.New()
+					m.textInput.Placeholder = "e.g., us-west-2"
+					m.textInput.Focus()
+					m.textInput.CharLimit = 200
+					m.textInput.Width = 200
+					m.textInput.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor))
+					m.textInput.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textInputColor))
+					return m, nil
+				case menuTOP[15]:
+					m.prevMenuState = m.state
+					m.prevState = m.state
+					m.state = StateTextInput
+					m.inputPrompt = menuTOP[15]
+					m.textInput = textinput.New()
+					m.textInput.Placeholder = "e.g., stop, start, reboot or hibernate on AWS (optional: i-0abc,i-0def or droplet IPs)"
+					m.textInput.Focus()
+					m.textInput.CharLimit = 200
+					m.textInput.Width = 200
+					m.textInput.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor))
+					m.textInput.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textInputColor))
+					return m, nil
+				case menuTOP[16]:
+					m.prevMenuState = m.state
+					m.prevState = m.state
+					m.state = StateTextInput
+					m.inputPrompt = menuTOP[16]
+					m.textInput = textinput.New()
+					m.textInput.Placeholder = "e.g., t3.large 2 (new type, boxes resized at a time)"
+					m.textInput.Focus()
+					m.textInput.CharLimit = 50
+					m.textInput.Width = 50
+					m.textInput.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor))
+					m.textInput.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textInputColor))
+					return m, nil
+				case menuTOP[17]:
+					m.prevMenuState = m.state
+					m.prevState = m.state
+					m.state = StateTextInput
+					m.inputPrompt = menuTOP[17]
+					m.textInput = textinput.New()
+					m.textInput.Placeholder = "e.g., 8 or 8 oldest (terminate newest|oldest first)"
+					m.textInput.Focus()
+					m.textInput.CharLimit = 20
+					m.textInput.Width = 20
+					m.textInput.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor))
+					m.textInput.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textInputColor))
+					return m, nil
+				case menuTOP[18], menuTOP[19]:
+					m.prevMenuState = m.state
+					m.prevState = m.state
+					m.state = StateTextInput
+					m.inputPrompt = m.choice
+					m.textInput = textinput.New()
+					m.textInput.Placeholder = "e.g., ./manifest.yaml"
+					m.textInput.Focus()
+					m.textInput.CharLimit = 200
+					m.textInput.Width = 200
+					m.textInput.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor))
+					m.textInput.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textInputColor))
+					return m, nil
+				case menuTOP[20]:
+					m.prevState = m.state
+					m.prevMenuState = m.state
+					return m, m.startJob(backgroundJobWindowsPasswords())
+				case menuTOP[21]:
+					m.prevMenuState = m.state
+					m.prevState = m.state
+					m.state = StateTextInput
+					m.inputPrompt = menuTOP[21]
+					m.textInput = textinput.New()
+					m.textInput.Placeholder = "e.g., i-0abc123 or 54.12.34.56"
+					m.textInput.Focus()
+					m.textInput.CharLimit = 50
+					m.textInput.Width = 50
+					m.textInput.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor))
+					m.textInput.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textInputColor))
+					return m, nil
+				case menuTOP[22]:
+					m.app.Aws.ElasticIPs = !m.app.Aws.ElasticIPs
+					m.prevState = m.state
+					m.prevMenuState = m.state
+					m.state = StateResultDisplay
+					m.backgroundJobResult = fmt.Sprintf("Elastic IPs per box: %t", m.app.Aws.ElasticIPs)
+					return m, nil
+				case menuTOP[23]:
+					m.prevMenuState = m.state
+					m.prevState = m.state
+					m.state = StateTextInput
+					m.inputPrompt = menuTOP[23]
+					m.textInput = textinput.New()
+					m.textInput.Placeholder = "e.g., vpc-0abc subnet-1,subnet-2 or tag:Env=lab tag:Tier=public (blank = default VPC, subnets alone = their VPC)"
+					m.textInput.Focus()
+					m.textInput.CharLimit = 200
+					m.textInput.Width = 200
+					m.textInput.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor))
+					m.textInput.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textInputColor))
+					return m, nil
+				case menuTOP[24]:
+					m.app.Aws.SpreadAZs = !m.app.Aws.SpreadAZs
+					m.prevState = m.state
+					m.prevMenuState = m.state
+					m.state = StateResultDisplay
+					m.backgroundJobResult = fmt.Sprintf("Spread boxes across AZs: %t", m.app.Aws.SpreadAZs)
+					return m, nil
+				case menuTOP[25]:
+					m.prevMenuState = m.state
+					m.prevState = m.state
+					m.state = StateTextInput
+					m.inputPrompt = menuTOP[25]
+					m.textInput = textinput.New()
+					m.textInput.Placeholder = "e.g., autobox-pg spread (cluster|spread|partition, blank = none)"
+					m.textInput.Focus()
+					m.textInput.CharLimit = 100
+					m.textInput.Width = 100
+					m.textInput.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor))
+					m.textInput.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textInputColor))
+					return m, nil
+				case menuTOP[26]:
+					m.app.Aws.PrivateOnly = !m.app.Aws.PrivateOnly
+					m.prevState = m.state
+					m.prevMenuState = m.state
+					m.state = StateResultDisplay
+					m.backgroundJobResult = fmt.Sprintf("Private boxes (no public IP): %t", m.app.Aws.PrivateOnly)
+					return m, nil
+				case menuTOP[27]:
+					m.prevMenuState = m.state
+					m.prevState = m.state
+					m.state = StateTextInput
+					m.inputPrompt = menuTOP[27]
+					m.textInput = textinput.New()
+					m.textInput.Placeholder = "e.g., auto (a box of the batch), i-0abc123 or ec2-user@1.2.3.4 (blank = none)"
+					m.textInput.Focus()
+					m.textInput.CharLimit = 200
+					m.textInput.Width = 200
+					m.textInput.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor))
+					m.textInput.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textInputColor))
+					return m, nil
+				case menuTOP[28]:
+					m.app.Aws.IPv6 = !m.app.Aws.IPv6
+					m.prevState = m.state
+					m.prevMenuState = m.state
+					m.state = StateResultDisplay
+					m.backgroundJobResult = fmt.Sprintf("IPv6 addresses on boxes: %t", m.app.Aws.IPv6)
+					return m, nil
+				case menuTOP[29]:
+					m.app.Aws.Hibernation = !m.app.Aws.Hibernation
+					m.prevState = m.state
+					m.prevMenuState = m.state
+					m.state = StateResultDisplay
+					m.backgroundJobResult = fmt.Sprintf("Hibernation on new boxes: %t", m.app.Aws.Hibernation)
+					if m.app.Aws.Hibernation {
+						m.backgroundJobResult += "\nTheir root volume is encrypted and has to hold the RAM of the instance type"
+					}
+					return m, nil
+				case menuTOP[30]:
+					m.prevMenuState = m.state
+					m.prevState = m.state
+					m.state = StateTextInput
+					m.inputPrompt = menuTOP[30]
+					m.textInput = textinput.New()
+					m.textInput.Placeholder = "e.g., http://localhost:4566 for LocalStack, add 'insecure' to skip TLS checks (blank = AWS)"
+					m.textInput.Focus()
+					m.textInput.CharLimit = 200
+					m.textInput.Width = 200
+					m.textInput.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor))
+					m.textInput.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textInputColor))
+					return m, nil
+				case menuTOP[31]:
+					m.prevState = m.state
+					m.prevMenuState = m.state
+					return m, m.startJob(backgroundJobListAdoptable(m.app.Aws.Region))
+				case menuTOP[32]:
+					m.prevMenuState = m.state
+					m.prevState = m.state
+					m.state = StateTextInput
+					m.inputPrompt = menuTOP[32]
+					m.textInput = textinput.New()
+					m.textInput.Placeholder = "e.g., rename OLD NEW | move NEW [i-0abc,i-0def] | merge TARGET A,B"
+					m.textInput.Focus()
+					m.textInput.CharLimit = 200
+					m.textInput.Width = 200
+					m.textInput.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor))
+					m.textInput.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textInputColor))
+					return m, nil
+				case menuTOP[33]:
+					m.prevMenuState = m.state
+					m.prevState = m.state
+					m.state = StateTextInput
+					m.inputPrompt = menuTOP[33]
+					m.textInput = textinput.New()
+					m.textInput.Placeholder = "e.g., alice or team-qa, boxes of other workspaces are left alone (blank = every AUTO-BOX box)"
+					m.textInput.Focus()
+					m.textInput.CharLimit = 50
+					m.textInput.Width = 50
+					m.textInput.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor))
+					m.textInput.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textInputColor))
+					return m, nil
+				case menuTOP[34]:
+					m.prevState = m.state
+					m.prevMenuState = m.state
+					return m, m.startJob(backgroundJobClaimBoxes())
+				case menuTOP[35]:
+					m.prevState = m.state
+					m.prevMenuState = m.state
+					return m, m.startConfirmed(confirmForceUnlock, backgroundJobPlanForceUnlock(), backgroundJobForceUnlock())
+				case menuTOP[36]:
+					m.prevMenuState = m.state
+					m.prevState = m.state
+					m.state = StateTextInput
+					m.inputPrompt = menuTOP[36]
+					m.textInput = textinput.New()
+					m.textInput.Placeholder = "e.g., t3.micro=0.0104 t3.medium=0.0416, USD per hour of each type (blank = no Cost column prices)"
+					m.textInput.SetValue(formatHourlyRates(m.app.Aws.HourlyRates))
+					m.textInput.Focus()
+					m.textInput.CharLimit = 500
+					m.textInput.Width = 100
+					m.textInput.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor))
+					m.textInput.TextStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(textInputColor))
+					return m, nil
+				case menuTOP[37]:
+					m.prevState = m.state
+					m.prevMenuState = m.state
+					return m, m.openInventory()
+				case menuTOP[38]:
+					m.prevState = m.state
+					m.prevMenuState = m.state
+					m.state = StateJobs
+					m.jobCursor = 0
+					return m, nil
+				case menuTOP[39]:
+					m.prevState = m.state
+					m.prevMenuState = m.state
+					return m, m.startJob(backgroundSaveSettings())
+				}
+			}
+			return m, nil
+		}
+		// case jobListMsg:
+
+		// 	// m.state = StateResultDisplay
+		// 	// return m, nil
+		// 	m.prevState = m.state
+		// 	m.state = StateSpinner
+		// 	return m, tea.Batch(m.spinner.Tick, m.startBackgroundJob())
+	}
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+	return m, cmd
+}
+
+func (m *MenuList) updateTextInput(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	m.textInput, cmd = m.textInput.Update(msg)
+	m.textInputError = false
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.Type {
+		case tea.KeyEnter:
+			inputValue := m.textInput.Value() // User pressed enter, save the input
+
+			switch m.inputPrompt {
+			case menuTOP[7]:
+				m.app.Digital.ApiToken = inputValue
+				m.backgroundJobResult = fmt.Sprintf("Saved API: %s", inputValue)
+				m.header = m.app.getAppHeader()
+			case menuTOP[8]:
+				m.app.Aws.Key = inputValue
+				m.backgroundJobResult = fmt.Sprintf("Saved AWS Key: %s", inputValue)
+				m.header = m.app.getAppHeader()
+			case menuTOP[9]:
+				m.app.Aws.Secret = inputValue
+				m.backgroundJobResult = fmt.Sprintf("Saved AWS Secret: %s", inputValue)
+				m.header = m.app.getAppHeader()
+			case menuTOP[12]:
+				m.app.URL = inputValue
+				m.backgroundJobResult = fmt.Sprintf("Saved URL: %s", inputValue)
+				m.header = m.app.getAppHeader()
+			case menuTOP[11]:
+				boxes, err := strconv.Atoi(inputValue)
+				if err != nil {
+					m.backgroundJobResult = "Data inputed is not a valid Number"
+				} else {
+					m.app.NumberBoxes = boxes
+					m.backgroundJobResult = fmt.Sprintf("Number of Boxes = %s", inputValue)
+					m.header = m.app.getAppHeader()
+				}
+			case menuTOP[10]:
+				if m.app.Provider == "digital" {
+					m.app.Digital.Region = inputValue
+				} else { //AWS
+					m.app.Aws.Region = inputValue
+					cmd = m.loadAmiHeader()
+				}
+				m.backgroundJobResult = fmt.Sprintf("Saved Region: %s", inputValue)
+				m.header = m.app.getAppHeader()
+			case menuTOP[0]:
+				m.app.BatchTag = inputValue
+				m.backgroundJobResult = fmt.Sprintf("Saved Batch Tag: %s", inputValue)
+				m.header = m.app.getAppHeader()
+			case menuTOP[13]:
+				m.app.Aws.setAmi(inputValue)
+				m.backgroundJobResult = fmt.Sprintf("Saved AMI: %s", inputValue)
+				m.header = m.app.getAppHeader()
+				cmd = m.loadAmiHeader()
+			case menuTOP[14]:
+				m.prevState = m.state
+				return m, m.startJob(backgroundJobCopyAmi(inputValue))
+			case menuTOP[15]:
+				m.prevState = m.state
+				action, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(inputValue)), " ")
+				if action == "stop" || action == "hibernate" {
+					return m, m.startConfirmed(confirmStop, backgroundJobPlanPower(inputValue), backgroundJobPowerBoxes(inputValue))
+				}
+				return m, m.startJob(backgroundJobPowerBoxes(inputValue))
+			case menuTOP[16]:
+				fields := strings.Fields(inputValue)
+				rolling := 0
+				if len(fields) > 1 {
+					rolling, _ = strconv.Atoi(fields[1])
+				}
+				if len(fields) == 0 || rolling < 0 {
+					m.backgroundJobResult = "Enter the new instance type and optionally how many boxes to resize at a time"
+					m.textInputError = true
+				} else {
+					m.prevState = m.state
+					return m, m.startJob(backgroundJobResizeBoxes(fields[0], rolling))
+				}
+			case menuTOP[17]:
+				fields := strings.Fields(inputValue)
+				desired := -1
+				if len(fields) > 0 {
+					desired, _ = strconv.Atoi(fields[0])
+				}
+				policy := m.app.Aws.ScalePolicy
+				if len(fields) > 1 {
+					policy = strings.ToLower(fields[1])
+				}
+				if desired < 0 {
+					m.backgroundJobResult = "Data inputed is not a valid Number"
+					m.textInputError = true
+				} else if err := checkScalePolicy(policy); err != nil {
+					m.backgroundJobResult = "Boxes go newest or oldest first when scaling down"
+					m.textInputError = true
+				} else {
+					m.app.Aws.ScalePolicy = policy
+					m.prevState = m.state
+					return m, m.startConfirmed(confirmScaleDown, backgroundJobPlanScale(m.app.BatchTag, desired), backgroundJobScaleBatch(m.app.BatchTag, desired, nil))
+				}
+			case menuTOP[32]:
+				fields := strings.Fields(inputValue)
+				command := ""
+				if len(fields) > 0 {
+					command = strings.ToLower(fields[0])
+				}
+				switch {
+				case command == "rename" && len(fields) == 3:
+					m.prevState = m.state
+					return m, m.startJob(backgroundJobRetagBatches(command, fields[2], fields[1:2]))
+				case command == "merge" && len(fields) >= 3,
+					command == "move" && len(fields) >= 3:
+					m.prevState = m.state
+					return m, m.startJob(backgroundJobRetagBatches(command, fields[1], splitBoxList(strings.Join(fields[2:], ","))))
+				case command == "move" && len(fields) == 2:
+					// no boxes given, pick them from the current batch
+					m.prevState = m.state
+					return m, m.startJob(backgroundJobListBatchBoxes(fields[1]))
+				default:
+					m.backgroundJobResult = "Use: rename OLD NEW | move NEW [i-0abc,i-0def] | merge TARGET A,B"
+					m.textInputError = true
+				}
+			case menuTOP[18]:
+				m.prevState = m.state
+				return m, m.startJob(backgroundJobManifest(inputValue, false))
+			case menuTOP[19]:
+				m.prevState = m.state
+				return m, m.startConfirmed(confirmManifest, backgroundJobPlanManifest(inputValue), backgroundJobManifest(inputValue, true))
+			case menuTOP[21]:
+				m.prevState = m.state
+				return m, m.startJob(backgroundJobDiagnoseBox(strings.TrimSpace(inputValue)))
+			case menuTOP[23]:
+				fields := strings.Fields(inputValue)
+				m.app.Aws.Vpc, m.app.Aws.Subnets = "", ""
+				if len(fields) > 0 && strings.HasPrefix(fields[0], "subnet-") {
+					// subnets alone deploy into their VPC
+					fields = append([]string{""}, fields...)
+				}
+				if len(fields) > 0 {
+					m.app.Aws.Vpc = fields[0]
+				}
+				if len(fields) > 1 {
+					m.app.Aws.Subnets = fields[1]
+				}
+				m.backgroundJobResult = fmt.Sprintf("Saved VPC: %s\nSubnets: %s", m.app.Aws.Vpc, m.app.Aws.Subnets)
+			case menuTOP[25]:
+				fields := strings.Fields(inputValue)
+				m.app.Aws.PlacementGroup, m.app.Aws.PlacementStrategy = "", ""
+				if len(fields) > 0 {
+					m.app.Aws.PlacementGroup = fields[0]
+				}
+				if len(fields) > 1 {
+					m.app.Aws.PlacementStrategy = fields[1]
+				}
+				m.backgroundJobResult = fmt.Sprintf("Saved Placement Group: %s %s", m.app.Aws.PlacementGroup, m.app.Aws.PlacementStrategy)
+			case menuTOP[27]:
+				m.app.Aws.Bastion = strings.TrimSpace(inputValue)
+				m.backgroundJobResult = fmt.Sprintf("Saved Bastion: %s", m.app.Aws.Bastion)
+			case menuTOP[30]:
+				fields := strings.Fields(inputValue)
+				m.app.Aws.Endpoint, m.app.Aws.EndpointInsecureTLS = "", false
+				if len(fields) > 0 {
+					m.app.Aws.Endpoint = fields[0]
+				}
+				if len(fields) > 1 {
+					m.app.Aws.EndpointInsecureTLS = fields[1] == "insecure"
+				}
+				m.backgroundJobResult = fmt.Sprintf("Saved AWS Endpoint: %s\nInsecure TLS: %t", m.app.Aws.Endpoint, m.app.Aws.EndpointInsecureTLS)
+			case menuTOP[33]:
+				m.app.Aws.Workspace = strings.TrimSpace(inputValue)
+				m.backgroundJobResult = fmt.Sprintf("Saved Workspace: %s", m.app.Aws.Workspace)
+				if m.app.Aws.Workspace == "" {
+					m.backgroundJobResult += "\nNo Workspace: lookups reach every AUTO-BOX box in the region, deleting every batch is refused"
+				}
+			case menuTOP[36]:
+				rates, err := parseHourlyRates(inputValue)
+				if err != nil {
+					m.backgroundJobResult = fmt.Sprintf("Hourly Rates not saved:\n%s", err)
+				} else {
+					m.app.Aws.HourlyRates = rates
+					m.backgroundJobResult = fmt.Sprintf("Saved Hourly Rates: %s", cmp.Or(formatHourlyRates(rates), "none"))
+				}
+			}
+			m.prevState = m.state
+			m.state = StateResultDisplay
+			// the AMI lookup of a new region or AMI, if any
+			return m, cmd
+
+		case tea.KeyEsc:
+			// m.state = StateSettingsMenu
+			m.state = m.prevState
+			return m, nil
+		}
+	}
+
+	return m, cmd
+}
+
+func (m *MenuList) updateSpinner(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		// case "q", "esc":
+		// 	m.backgroundJobResult = "Job Cancelled"
+		// 	m.state = StateResultDisplay
+		// 	return m, nil
+		case "esc":
+			// the job goes on, its result waits on the jobs screen
+			if e := m.jobs.entry(m.jobs.waiting); e != nil {
+				m.jobNotice = fmt.Sprintf("Job #%d in the background: %s", e.id, e.job.title)
+			}
+			m.jobs.waiting = 0
+			m.state = m.prevMenuState
+			return m, nil
+		default:
+			// For other key presses, update the spinner
+			var cmd tea.Cmd
+			m.spinner, cmd = m.spinner.Update(msg)
+			return m, cmd
+		}
+	case backgroundJobMsg:
+		m.backgroundJobResult = m.jobOutcome + "\n\n" + msg.result + "\n"
+		m.state = StateResultDisplay
+		return m, nil
+	case pickerListMsg:
+		if msg.result != "" {
+			m.backgroundJobResult = msg.result
+			m.state = StateResultDisplay
+			return m, nil
+		}
+		m.picker = newBoxPicker(msg.title, msg.items)
+		m.pickerAction = msg.action
+		m.pickerTarget = msg.target
+		m.state = StatePicker
+		return m, nil
+	case confirmMsg:
+		if msg.result != "" {
+			m.backgroundJobResult = msg.result
+			m.state = StateResultDisplay
+			return m, nil
+		}
+		return m, m.openConfirm(msg)
+	// case continueJobs:
+	// 	return m, tea.Batch(m.spinner.Tick, m.startBackgroundJob())
+	default:
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+	}
+}
+
+func (m *MenuList) updatePicker(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	if keyMsg.String() == "ctrl+c" {
+		return m, tea.Quit
+	}
+
+	confirmed, cancelled := m.picker.update(keyMsg)
+	switch {
+	case cancelled:
+		m.state = m.prevMenuState
+		m.updateListItems()
+		return m, nil
+	case confirmed:
+		switch m.pickerAction {
+		case "adopt":
+			return m, m.startJob(backgroundJobAdoptBoxes(m.picker.selectedIDs()))
+		case "move":
+			return m, m.startJob(backgroundJobRetagBatches("move", m.pickerTarget, m.picker.selectedIDs()))
+		}
+	}
+	return m, nil
+}
+
+func (m *MenuList) updateResultDisplay(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "esc":
+			if m.textInputError {
+				m.state = m.prevState
+			} else {
+				m.state = m.prevMenuState
+			}
+			m.updateListItems()
+			return m, nil
+		case "ctrl+c":
+			return m, tea.Quit
+		}
+	}
+	return m, nil
+}
+
+func (m MenuList) viewResultDisplay() string {
+	outro := "Press 'esc' to return."
+	outroRender := lipgloss.NewStyle().Foreground(lipgloss.Color("231")).Bold(true).Render(outro)
+	lipgloss.NewStyle().Foreground(lipgloss.Color("231")).Bold(true)
+	if m.textInputError {
+		m.backgroundJobResult = lipgloss.NewStyle().Foreground(lipgloss.Color(textErrorColorFront)).Background(lipgloss.Color(textErrorColorBack)).Bold(true).Render(m.backgroundJobResult)
+	} else {
+		m.backgroundJobResult = lipgloss.NewStyle().Foreground(lipgloss.Color(textResultJob)).Render(m.backgroundJobResult)
+	}
+	return fmt.Sprintf("\n\n%s\n\n%s", m.backgroundJobResult, outroRender)
+
+	// //repeat interval
+	// if m.configSettings.Interval > 0 {
+
+	// }
+}
+
+func (m MenuList) View() string {
+	switch m.state {
+	case StateMainMenu, StateSettingsMenu:
+		if m.app.Provider == "aws" && m.amiHeader != "" {
+			return m.header + "\n" + lipgloss.NewStyle().Foreground(lipgloss.Color(awsColorFront)).Render(m.amiHeader) + "\n" + m.viewJobsStatus() + m.list.View()
+		}
+		return m.header + "\n" + m.viewJobsStatus() + m.list.View()
+	case StateSpinner:
+		return m.viewSpinner()
+	case StateTextInput:
+		return m.viewTextInput()
+	case StateResultDisplay:
+		return m.viewResultDisplay()
+	case StatePicker:
+		return m.picker.view()
+	case StateJobs:
+		return m.viewJobs()
+	case StateInventory:
+		return m.viewInventory()
+	case StateConfirm:
+		return m.confirm.view()
+	default:
+		return "Unknown state"
+	}
+}
+
+func (m MenuList) viewSpinner() string {
+	// tea.ClearScreen()
+	title, checklist := "", ""
+	if e := m.jobs.entry(m.jobs.waiting); e != nil {
+		title, checklist = e.job.title, e.checklist.view(m.spinner.View())
+	}
+	spinnerBase := fmt.Sprintf("\n\n   %s %s\n\n", m.spinner.View(), title)
+	spinnerBase += checklist
+	spinnerBase += lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render("esc: keep it running in the background") + "\n\n"
+
+	// return spinnerBase + m.jobOutcome
+	return spinnerBase + lipgloss.NewStyle().Foreground(lipgloss.Color(textJobOutcomeFront)).Bold(true).Render(m.jobOutcome)
+}
+
+func (m MenuList) viewTextInput() string {
+	promptStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor)).Bold(true)
+	return fmt.Sprintf("\n\n%s\n\n%s", promptStyle.Render(m.inputPrompt), m.textInput.View())
+
+}
+
+func (m *MenuList) updateListItems() {
+	switch m.state {
+	case StateMainMenu:
+		items := []list.Item{}
+		for _, value := range menuTOP {
+			items = append(items, item(value))
+		}
+		m.list.SetItems(items)
+		// case StateSettingsMenu:
+		// 	items := []list.Item{}
+		// 	for _, value := range menuSettings {
+		// 		items = append(items, item(value[0]))
+		// 	}
+		// 	m.list.SetItems(items)
+	}
+
+	m.list.ResetSelected()
+}
+
+func backgroundSaveSettings() job {
+	return job{
+		title: "Saving Settings",
+		color: "13",
+		run: func(_ context.Context, app *applicationMain, _ progressFunc) tea.Msg {
+			time.Sleep(1 * time.Second)
+			return jobResultMsg("Settings Saved", saveSettings(app))
+		},
+	}
+}
+
+func backgroundJobCreateBox() job {
+	return job{
+		title: "Creating Boxes...",
+		color: "82",
+		run: func(ctx context.Context, app *applicationMain, progress progressFunc) tea.Msg {
+			return jobResultMsg(deployBoxes(ctx, app, progress))
+		},
+	}
+}
+
+func backgroundJobRunPostURL(only boxList) job {
+	return job{
+		title: pickedTitle("Running Post Launch Scripts", only),
+		color: "82",
+		run: func(ctx context.Context, app *applicationMain, progress progressFunc) tea.Msg {
+			return jobResultMsg(runPostURLs(ctx, app, only, progress))
+		},
+	}
+}
+
+func backgroundJobPS1scripts(only boxList) job {
+	return job{
+		title: pickedTitle("Creating Post Launch scripts...", only),
+		color: "82",
+		run: func(ctx context.Context, app *applicationMain, progress progressFunc) tea.Msg {
+			return jobResultMsg(createPostScripts(ctx, app, only, progress))
+		},
+	}
+}
+
+// planned are the boxes a confirmed delete expects to find, nil when not confirmed
+func backgroundJobDeleteBox(only boxList, planned []string) job {
+	return job{
+		title: pickedTitle("Deleting Boxes", only),
+		color: "82",
+		run: func(ctx context.Context, app *applicationMain, progress progressFunc) tea.Msg {
+			return jobResultMsg(deleteBoxes(ctx, app, only, planned, progress))
+		},
+	}
+}
+
+// title of a job limited to picked boxes says how many
+func pickedTitle(title string, only boxList) string {
+	if only.picked() {
+		return fmt.Sprintf("%s (%d picked)", title, len(only))
+	}
+	return title
+}
+
+// looks up what a delete removes for the operator to confirm
+func backgroundJobPlanDelete(only boxList) job {
+	return job{
+		title: "Checking what the delete removes",
+		color: "82",
+		wait:  true,
+		run: func(_ context.Context, app *applicationMain, _ progressFunc) tea.Msg {
+			plan, err := planDelete(app, only)
+			if err != nil {
+				return confirmMsg{result: fmt.Sprintf("Not deleting:\n%s", err)}
+			}
+			return confirmMsg{
+				title:    pickedTitle("DELETE Boxes", only) + " will remove",
+				summary:  plan.summary(),
+				phrase:   plan.phrase,
+				run:      backgroundJobDeleteBox(only, plan.boxIDs),
+				settings: app,
+			}
+		},
+	}
+}
+
+func backgroundJobCopyAmi(targetRegion string) job {
+	return job{
+		title: fmt.Sprintf("Copying AMI to %s", targetRegion),
+		color: "82",
+		run: func(ctx context.Context, app *applicationMain, progress progressFunc) tea.Msg {
+			if app.Provider == "digital" {
+				return backgroundJobMsg{result: "AMI copy is only available for AWS"}
+			}
+
+			amiID, err := app.Aws.copyAmiToRegion(ctx, targetRegion, progress)
+			if err != nil {
+				return jobFailedMsg(fmt.Sprintf("Error copying AMI:\n%s", err), err)
+			}
+
+			amiMap, amiSources := app.Aws.AmiMap, app.Aws.AmiSources
+			return backgroundJobMsg{
+				result: fmt.Sprintf("AMI %s ready in %s\nSave Settings to keep the region mapping", amiID, targetRegion),
+				settings: func(app *applicationMain) {
+					if app.Aws.AmiMap == nil {
+						app.Aws.AmiMap = map[string]string{}
+					}
+					maps.Copy(app.Aws.AmiMap, amiMap)
+					if app.Aws.AmiSources == nil {
+						app.Aws.AmiSources = map[string]string{}
+					}
+					maps.Copy(app.Aws.AmiSources, amiSources)
+				},
+			}
+		},
+	}
+}
+
+func backgroundJobPowerBoxes(command string) job {
+	action, boxList, _ := strings.Cut(strings.TrimSpace(command), " ")
+	action = strings.ToLower(action)
+	return job{
+		title: fmt.Sprintf("Power %s Boxes", action),
+		color: "82",
+		run: func(_ context.Context, app *applicationMain, progress progressFunc) tea.Msg {
+
+			if app.Provider == "digital" {
+				return powerDigitalBoxes(app, action, splitBoxList(boxList), progress)
+			}
+			if !slices.Contains([]string{"stop", "hibernate", "reboot", "start"}, action) {
+				return backgroundJobMsg{result: fmt.Sprintf("Unknown power action: %s", action)}
+			}
+
+			pepa, err := app.Aws.createEc2Client()
+			if err != nil {
+				return jobFailedMsg(fmt.Sprintf("error getting AWS credentials:\n%s", err), err)
+			}
+
+			instanceIDs := splitBoxList(boxList)
+			if len(instanceIDs) == 0 {
+				state := "running"
+				if action == "start" {
+					state = "stopped"
+				}
+				instanceIDs, err = app.Aws.batchInstanceIDs(pepa, app.BatchTag, state)
+				if err != nil {
+					return jobFailedMsg(fmt.Sprintf("Error listing boxes:\n%s", err), err)
+				}
+			}
+
+			result := fmt.Sprintf("%d - Boxes %s", len(instanceIDs), action)
+			// the boxes go together, each step finishes once they all did
+			progress.total(len(instanceIDs))
+			for _, instanceID := range instanceIDs {
+				progress.start(instanceID)
+			}
+			var notHibernated []string
+			switch action {
+			case "stop":
+				_, err = app.Aws.stopEC2Instances(pepa, instanceIDs, false)
+			case "hibernate":
+				notHibernated, err = app.Aws.stopEC2Instances(pepa, instanceIDs, true)
+			case "reboot":
+				err = app.Aws.rebootEC2Instances(pepa, instanceIDs)
+			case "start":
+				err = app.Aws.startEC2Instances(pepa, instanceIDs)
+			}
+			for _, instanceID := range instanceIDs {
+				detail := action
+				if slices.Contains(notHibernated, instanceID) {
+					detail = "stop"
+				}
+				progress.finish(instanceID, detail, err)
+			}
+			if action == "start" && err == nil {
+				// public IPs change on start
+				err = regeneratePostScripts(app, pepa)
+			}
+			if err != nil {
+				result = fmt.Sprintf("Error running %s:\n%s", action, err)
+			}
+			if len(notHibernated) > 0 {
+				result = fmt.Sprintf("%s\n\n%d boxes were launched without hibernation and got a regular stop:\n%s",
+					result, len(notHibernated), strings.Join(notHibernated, "\n"))
+			}
+
+			return backgroundJobMsg{result: result}
+		},
+	}
+}
+
+// droplets are stopped, started and rebooted one by one, at addresses when given
+func powerDigitalBoxes(app *applicationMain, action string, addresses []string, progress progressFunc) tea.Msg {
+	if _, ok := digitalPowerActions[action]; !ok {
+		return backgroundJobMsg{result: fmt.Sprintf("Power %s is not available for DigitalOcean, use stop, start or reboot", action)}
+	}
+	droplets, err := app.Digital.powerTargets(app.BatchTag, action, addresses)
+	if err != nil {
+		return jobFailedMsg(fmt.Sprintf("Error listing boxes:\n%s", err), err)
+	}
+	err = app.Digital.powerDroplets(droplets, action, progress)
+	if err != nil {
+		return jobFailedMsg(fmt.Sprintf("Error running %s:\n%s", action, err), err)
+	}
+	return backgroundJobMsg{result: fmt.Sprintf("%d - Boxes %s", len(droplets), action)}
+}
+
+// the droplets stop takes down, by public IP
+func planPowerDigital(app *applicationMain, action string, addresses []string) confirmMsg {
+	if _, ok := digitalPowerActions[action]; !ok {
+		// the power job says why not
+		return confirmMsg{run: backgroundJobPowerBoxes(action), settings: app}
+	}
+	droplets, err := app.Digital.powerTargets(app.BatchTag, action, addresses)
+	if err != nil {
+		return confirmMsg{result: fmt.Sprintf("Not running %s:\nerror listing boxes:\n%s", action, err)}
+	}
+	if len(droplets) == 0 {
+		return confirmMsg{result: "No running boxes found"}
+	}
+	picked := []string{}
+	for _, droplet := range droplets {
+		picked = append(picked, cmp.Or(droplet.publicIP(), strconv.Itoa(droplet.ID)))
+	}
+	phrase := app.Digital.Region
+	if len(addresses) == 0 {
+		phrase = cmp.Or(app.BatchTag, app.Digital.Region)
+	}
+	return confirmMsg{
+		title: fmt.Sprintf("Power %s will take down", action),
+		summary: [][2]string{
+			{"Batch", batchOrAll(app.BatchTag)},
+			{"Boxes", fmt.Sprintf("%d: %s", len(picked), shortList(picked))},
+		},
+		phrase:   phrase,
+		run:      backgroundJobPowerBoxes(fmt.Sprintf("%s %s", action, strings.Join(picked, ","))),
+		settings: app,
+	}
+}
+
+// stop and hibernate list the boxes they take down, the boxes listed are the
+// ones powered off once confirmed
+func backgroundJobPlanPower(command string) job {
+	action, boxList, _ := strings.Cut(strings.TrimSpace(command), " ")
+	action = strings.ToLower(action)
+	return job{
+		title: fmt.Sprintf("Checking what %s takes down", action),
+		color: "82",
+		wait:  true,
+		run: func(_ context.Context, app *applicationMain, _ progressFunc) tea.Msg {
+			if app.Provider == "digital" {
+				return planPowerDigital(app, action, splitBoxList(boxList))
+			}
+
+			pepa, err := app.Aws.createEc2Client()
+			if err != nil {
+				return confirmMsg{result: fmt.Sprintf("Not running %s:\nerror getting AWS credentials:\n%s", action, err)}
+			}
+			instanceIDs := splitBoxList(boxList)
+			phrase := app.Aws.Region
+			if len(instanceIDs) == 0 {
+				instanceIDs, err = app.Aws.batchInstanceIDs(pepa, app.BatchTag, "running")
+				if err != nil {
+					return confirmMsg{result: fmt.Sprintf("Not running %s:\nerror listing boxes:\n%s", action, err)}
+				}
+				phrase = cmp.Or(app.BatchTag, app.Aws.Region)
+			}
+			if len(instanceIDs) == 0 {
+				return confirmMsg{result: "No running boxes found"}
+			}
+
+			return confirmMsg{
+				title: fmt.Sprintf("Power %s will take down", action),
+				summary: [][2]string{
+					{"Batch", batchOrAll(app.BatchTag)},
+					{"Boxes", fmt.Sprintf("%d: %s", len(instanceIDs), shortList(instanceIDs))},
+				},
+				phrase:   phrase,
+				run:      backgroundJobPowerBoxes(fmt.Sprintf("%s %s", action, strings.Join(instanceIDs, ","))),
+				settings: app,
+			}
+		},
+	}
+}
+
+func backgroundJobResizeBoxes(instanceType string, rolling int) job {
+	return job{
+		title: fmt.Sprintf("Resizing Boxes to %s", instanceType),
+		color: "82",
+		run: func(_ context.Context, app *applicationMain, progress progressFunc) tea.Msg {
+			if app.Provider == "digital" {
+				return backgroundJobMsg{result: "Resize is only available for AWS"}
+			}
+
+			pepa, err := app.Aws.createEc2Client()
+			if err != nil {
+				return jobFailedMsg(fmt.Sprintf("error getting AWS credentials:\n%s", err), err)
+			}
+			release, err := lockBatches(app, pepa, app.BatchTag)
+			if err != nil {
+				return jobFailedMsg(fmt.Sprintf("Not resizing, %s", err), err)
+			}
+			defer release()
+			instanceIDs, err := app.Aws.batchInstanceIDs(pepa, app.BatchTag, "running", "stopped")
+			if err != nil {
+				return jobFailedMsg(fmt.Sprintf("Error listing boxes:\n%s", err), err)
+			}
+
+			results, err := app.Aws.resizeEC2Instances(pepa, instanceIDs, instanceType, rolling, progress)
+			if err != nil {
+				return jobFailedMsg(fmt.Sprintf("Error resizing boxes:\n%s", err), err)
+			}
+
+			resized := 0
+			lines := []string{}
+			for _, r := range results {
+				if r.Err != nil {
+					lines = append(lines, fmt.Sprintf("%s: %s", r.InstanceID, r.Err))
+				} else {
+					resized++
+					lines = append(lines, fmt.Sprintf("%s: %s", r.InstanceID, instanceType))
+				}
+			}
+			result := fmt.Sprintf("%d of %d - Boxes resized\n\n%s", resized, len(results), strings.Join(lines, "\n"))
+
+			// stop/start hands out new public IPs
+			if resized > 0 {
+				err = regeneratePostScripts(app, pepa)
+				if err != nil {
+					result = fmt.Sprintf("%s\n\nError updating post launch scripts:\n%s", result, err)
+				}
+			}
+
+			return backgroundJobMsg{result: result}
+		},
+	}
+}
+
+// planned are the boxes confirmed to go, nil when the scale was not confirmed
+func backgroundJobScaleBatch(batchT string, desired int, planned []string) job {
+	return job{
+		title: fmt.Sprintf("Scaling %s to %d Boxes", batchT, desired),
+		color: "82",
+		run: func(_ context.Context, app *applicationMain, progress progressFunc) tea.Msg {
+			if app.Provider == "digital" {
+				return backgroundJobMsg{result: "Scaling is only available for AWS"}
+			}
+			if app.BatchTag == "" {
+				return backgroundJobMsg{result: "Set a TAG deployment before scaling"}
+			}
+
+			pepa, err := app.Aws.createEc2Client()
+			if err != nil {
+				return jobFailedMsg(fmt.Sprintf("error getting AWS credentials:\n%s", err), err)
+			}
+			err = app.Aws.createPEMFile(pepa)
+			if err != nil {
+				return jobFailedMsg(fmt.Sprintf("error creating PEM:\n%s", err), err)
+			}
+			sgAuto, err := app.Aws.createSecurityGroup("sgAutoBox", "pepita stuff", pepa)
+			if err != nil {
+				return jobFailedMsg(fmt.Sprintf("error creating Security Group:\n%s", err), err)
+			}
+			err = app.Aws.acquireLock(pepa, sgAuto, app.BatchTag)
+			if err != nil {
+				return jobFailedMsg(fmt.Sprintf("Not scaling, %s", err), err)
+			}
+			defer app.Aws.releaseLock(pepa, sgAuto, app.BatchTag)
+
+			scaled, err := app.Aws.scaleEC2Batch(pepa, sgAuto, app.BatchTag, desired, planned, progress)
+			result := fmt.Sprintf("%s = %d Boxes\nLaunched: %d\nTerminated: %d", app.BatchTag, desired, len(scaled.Launched), len(scaled.Terminated))
+			if err != nil {
+				result = fmt.Sprintf("Error scaling batch:\n%s\n\n%s", err, result)
+			}
+
+			err = updateScaledScripts(app, pepa, scaled)
+			if err != nil {
+				result = fmt.Sprintf("Error updating post launch scripts\n%s\n\n%s", err, result)
+			}
+
+			return backgroundJobMsg{result: result}
+		},
+	}
+}
+
+// scaling up goes ahead, scaling down lists the boxes it terminates
+func backgroundJobPlanScale(batchT string, desired int) job {
+	return job{
+		title: fmt.Sprintf("Checking what scaling %s terminates", batchT),
+		color: "82",
+		wait:  true,
+		run: func(_ context.Context, app *applicationMain, _ progressFunc) tea.Msg {
+			if app.Provider == "digital" || app.BatchTag == "" {
+				// the scale job says why not
+				return confirmMsg{run: backgroundJobScaleBatch(batchT, desired, nil), settings: app}
+			}
+
+			pepa, err := app.Aws.createEc2Client()
+			if err != nil {
+				return confirmMsg{result: fmt.Sprintf("Not scaling:\nerror getting AWS credentials:\n%s", err)}
+			}
+			live, err := app.Aws.batchInstances(pepa, app.BatchTag, "pending", "running", "stopping", "stopped")
+			if err != nil {
+				return confirmMsg{result: fmt.Sprintf("Not scaling:\nerror listing boxes:\n%s", err)}
+			}
+			if len(live) <= desired {
+				// nothing to confirm, the scale still refuses to terminate any box
+				return confirmMsg{run: backgroundJobScaleBatch(batchT, desired, []string{}), settings: app}
+			}
+
+			app.Aws.scaleDownOrder(live)
+			terminated := []string{}
+			for _, instance := range live[:len(live)-desired] {
+				terminated = append(terminated, aws.ToString(instance.InstanceId))
+			}
+			return confirmMsg{
+				title: fmt.Sprintf("Scaling %s to %d Boxes will terminate", app.BatchTag, desired),
+				summary: [][2]string{
+					{"Boxes", fmt.Sprintf("%d live, %d terminated", len(live), len(terminated))},
+					{"Goes first", cmp.Or(app.Aws.ScalePolicy, "newest")},
+					{"Terminated", shortList(terminated)},
+				},
+				phrase:   app.BatchTag,
+				run:      backgroundJobScaleBatch(batchT, desired, terminated),
+				settings: app,
+			}
+		},
+	}
+}
+
+func backgroundJobManifest(path string, apply bool) job {
+	title := "Planning Manifest"
+	if apply {
+		title = "Applying Manifest"
+	}
+	return job{
+		title: title,
+		color: manifestColorFront,
+		run: func(_ context.Context, app *applicationMain, progress progressFunc) tea.Msg {
+			manifest, err := loadManifest(path)
+			if err != nil {
+				return jobFailedMsg(fmt.Sprintf("Error loading manifest:\n%s", err), err)
+			}
+
+			var plans []manifestPlan
+			if apply {
+				plans = applyManifest(app, manifest, progress)
+			} else {
+				plans = planManifest(app, manifest)
+			}
+
+			if err := manifestErrors(plans); err != nil {
+				return jobFailedMsg(renderManifestPlan(plans), err)
+			}
+			return backgroundJobMsg{result: renderManifestPlan(plans)}
+		},
+	}
+}
+
+// applying goes ahead unless a batch of the manifest scales down
+func backgroundJobPlanManifest(path string) job {
+	apply := backgroundJobManifest(path, true)
+	return job{
+		title: "Checking what the manifest terminates",
+		color: manifestColorFront,
+		wait:  true,
+		run: func(_ context.Context, app *applicationMain, _ progressFunc) tea.Msg {
+			manifest, err := loadManifest(path)
+			if err != nil {
+				return confirmMsg{result: fmt.Sprintf("Error loading manifest:\n%s", err)}
+			}
+
+			summary := [][2]string{}
+			terminates := false
+			for _, plan := range planManifest(app, manifest) {
+				change := fmt.Sprintf("%d -> %d", plan.Live, plan.Batch.Count)
+				switch {
+				case plan.Err != nil:
+					change = fmt.Sprintf("%s, ERROR: %s", change, plan.Err)
+				case plan.Live > plan.Batch.Count:
+					terminates = true
+					change = fmt.Sprintf("%s, %d terminated", change, plan.Live-plan.Batch.Count)
+				}
+				summary = append(summary, [2]string{plan.Batch.Name, change})
+			}
+			if !terminates {
+				return confirmMsg{run: apply, settings: app}
+			}
+			return confirmMsg{
+				title:    "Applying the manifest will scale",
+				summary:  summary,
+				phrase:   filepath.Base(path),
+				run:      apply,
+				settings: app,
+			}
+		},
+	}
+}
+
+func backgroundJobWindowsPasswords() job {
+	return job{
+		title: "Waiting for Windows passwords",
+		color: "82",
+		run: func(_ context.Context, app *applicationMain, _ progressFunc) tea.Msg {
+			if app.Provider == "digital" {
+				return backgroundJobMsg{result: "Windows passwords are only available for AWS"}
+			}
+
+			pepa, err := app.Aws.createEc2Client()
+			if err != nil {
+				return jobFailedMsg(fmt.Sprintf("error getting AWS credentials:\n%s", err), err)
+			}
+			creds, err := app.Aws.getWindowsPasswords(pepa, app.BatchTag)
+			if err != nil {
+				return jobFailedMsg(fmt.Sprintf("Error getting passwords:\n%s", err), err)
+			}
+			if len(creds) == 0 {
+				return backgroundJobMsg{result: "No running boxes found"}
+			}
+
+			lines := []string{}
+			for _, cred := range creds {
+				if cred.Err != nil {
+					lines = append(lines, fmt.Sprintf("%s  %s  error: %s", cred.InstanceID, cred.PublicIP, cred.Err))
+				} else {
+					lines = append(lines, fmt.Sprintf("%s  %s  %s / %s", cred.InstanceID, cred.PublicIP, cred.Username, cred.Password))
+				}
+			}
+			result := strings.Join(lines, "\n")
+
+			exported, err := app.Aws.exportWindowsPasswords(creds, app.BatchTag)
+			if err != nil {
+				result = fmt.Sprintf("%s\n\nError exporting passwords:\n%s", result, err)
+			} else {
+				result = fmt.Sprintf("%s\n\nExported to %s", result, exported)
+			}
+			err = clipboard.WriteAll(strings.Join(lines, "\n"))
+			if err == nil {
+				result = fmt.Sprintf("%s\nCopied to clipboard", result)
+			}
+
+			return backgroundJobMsg{result: result}
+		},
+	}
+}
+
+func backgroundJobDiagnoseBox(box string) job {
+	return job{
+		title: fmt.Sprintf("Fetching console of %s", box),
+		color: "82",
+		run: func(_ context.Context, app *applicationMain, _ progressFunc) tea.Msg {
+			if app.Provider == "digital" {
+				return backgroundJobMsg{result: "Diagnostics are only available for AWS"}
+			}
+
+			pepa, err := app.Aws.createEc2Client()
+			if err != nil {
+				return jobFailedMsg(fmt.Sprintf("error getting AWS credentials:\n%s", err), err)
+			}
+			diag, err := app.Aws.diagnoseEC2Instance(pepa, box)
+
+			result := fmt.Sprintf("Console output of %s:\n\n%s", diag.InstanceID, lastLines(diag.ConsoleOutput, 20))
+			if diag.ScreenshotFile != "" {
+				result = fmt.Sprintf("%s\n\nScreenshot saved to %s", result, diag.ScreenshotFile)
+			}
+			if err != nil {
+				result = fmt.Sprintf("%s\n\nError fetching diagnostics:\n%s", result, err)
+			}
+
+			return backgroundJobMsg{result: result}
+		},
+	}
+}
+
+func backgroundJobListAdoptable(region string) job {
+	return job{
+		title: fmt.Sprintf("Looking for boxes to adopt in %s", region),
+		color: "82",
+		wait:  true,
+		run: func(_ context.Context, app *applicationMain, _ progressFunc) tea.Msg {
+			if app.Provider == "digital" {
+				return pickerListMsg{result: "Adopting boxes is only available for AWS"}
+			}
+
+			pepa, err := app.Aws.createEc2Client()
+			if err != nil {
+				return pickerListMsg{result: fmt.Sprintf("error getting AWS credentials:\n%s", err)}
+			}
+			candidates, err := app.Aws.adoptableInstances(pepa)
+			if err != nil {
+				return pickerListMsg{result: fmt.Sprintf("error listing instances:\n%s", err)}
+			}
+			if len(candidates) == 0 {
+				return pickerListMsg{result: fmt.Sprintf("No instances outside AUTO-BOX in %s", app.Aws.Region)}
+			}
+
+			items := []pickerItem{}
+			for _, candidate := range candidates {
+				address := candidate.PublicIP
+				if address == "" {
+					address = candidate.PrivateIP
+				}
+				items = append(items, pickerItem{
+					ID:    candidate.InstanceID,
+					Label: fmt.Sprintf("%-20s %-20s %-12s %-9s %s", candidate.InstanceID, candidate.Name, candidate.InstanceType, candidate.State, address),
+					Notes: candidate.Warnings,
+				})
+			}
+			return pickerListMsg{
+				action: "adopt",
+				title:  fmt.Sprintf("Adopt into batch '%s'", app.BatchTag),
+				items:  items,
+			}
+		},
+	}
+}
+
+func backgroundJobAdoptBoxes(instanceIDs []string) job {
+	return job{
+		title: fmt.Sprintf("Adopting %d boxes", len(instanceIDs)),
+		color: "82",
+		run: func(_ context.Context, app *applicationMain, progress progressFunc) tea.Msg {
+			pepa, err := app.Aws.createEc2Client()
+			if err != nil {
+				return jobFailedMsg(fmt.Sprintf("error getting AWS credentials:\n%s", err), err)
+			}
+			sgAuto, err := app.Aws.createSecurityGroup("sgAutoBox", "pepita stuff", pepa)
+			if err != nil {
+				return jobFailedMsg(fmt.Sprintf("error creating security group:\n%s", err), err)
+			}
+			results, err := app.Aws.adoptEC2Instances(pepa, instanceIDs, app.BatchTag, sgAuto, progress)
+			if err != nil {
+				return jobFailedMsg(fmt.Sprintf("error adopting boxes:\n%s", err), err)
+			}
+
+			lines := []string{}
+			adopted := 0
+			for _, result := range results {
+				line := fmt.Sprintf("%s adopted", result.InstanceID)
+				if result.Err != nil {
+					line = fmt.Sprintf("%s failed: %s", result.InstanceID, result.Err)
+				} else {
+					adopted++
+				}
+				for _, warning := range result.Warnings {
+					line = fmt.Sprintf("%s\n   %s", line, warning)
+				}
+				lines = append(lines, line)
+			}
+
+			resultX := fmt.Sprintf("%d of %d boxes adopted into batch '%s'\n\n%s", adopted, len(instanceIDs), app.BatchTag, strings.Join(lines, "\n"))
+			err = regeneratePostScripts(app, pepa)
+			if err != nil {
+				resultX = fmt.Sprintf("%s\n\nError updating post launch scripts:\n%s", resultX, err)
+			}
+			return backgroundJobMsg{result: resultX}
+		},
+	}
+}
+
+func backgroundJobListBatchBoxes(target string) job {
+	return job{
+		title: "Listing boxes",
+		color: "82",
+		wait:  true,
+		run: func(_ context.Context, app *applicationMain, _ progressFunc) tea.Msg {
+			if app.Provider == "digital" {
+				return pickerListMsg{result: "Retagging batches is only available for AWS"}
+			}
+
+			pepa, err := app.Aws.createEc2Client()
+			if err != nil {
+				return pickerListMsg{result: fmt.Sprintf("error getting AWS credentials:\n%s", err)}
+			}
+			instances, err := app.Aws.batchInstances(pepa, app.BatchTag, "pending", "running", "stopping", "stopped")
+			if err != nil {
+				return pickerListMsg{result: fmt.Sprintf("error listing boxes:\n%s", err)}
+			}
+
+			items := []pickerItem{}
+			for _, instance := range instances {
+				box := instanceIPs(instance)
+				batchT := instanceTag(instance, "BatchTag")
+				if batchT == target {
+					continue
+				}
+				items = append(items, pickerItem{
+					ID:    box.InstanceID,
+					Label: fmt.Sprintf("%-20s %-12s %-9s %s", box.InstanceID, batchT, instance.State.Name, app.Aws.boxAddress(box)),
+				})
+			}
+			if len(items) == 0 {
+				return pickerListMsg{result: fmt.Sprintf("No boxes to move to batch '%s'", target)}
+			}
+			return pickerListMsg{
+				action: "move",
+				target: target,
+				title:  fmt.Sprintf("Move to batch '%s'", target),
+				items:  items,
+			}
+		},
+	}
+}
+
+// target is the batch the boxes end up in, args the batch to rename,
+// the boxes to move or the batches to merge
+func backgroundJobRetagBatches(command, target string, args []string) job {
+	return job{
+		title: "Retagging boxes",
+		color: "82",
+		run: func(_ context.Context, app *applicationMain, progress progressFunc) tea.Msg {
+			if app.Provider == "digital" {
+				return backgroundJobMsg{result: "Retagging batches is only available for AWS"}
+			}
+
+			pepa, err := app.Aws.createEc2Client()
+			if err != nil {
+				return jobFailedMsg(fmt.Sprintf("error getting AWS credentials:\n%s", err), err)
+			}
+
+			to := target
+			// the batches the boxes leave and the one they join, args are
+			// boxes when moving and batches otherwise
+			batches := append([]string{to}, args...)
+			if command == "move" {
+				picked, err := deleteTargetsAws(app, pepa, boxList(args))
+				if err != nil {
+					return jobFailedMsg(err.Error(), err)
+				}
+				batches = append(picked.batches, to)
+			}
+			release, err := lockBatches(app, pepa, batches...)
+			if err != nil {
+				return jobFailedMsg(fmt.Sprintf("Not retagging, %s", err), err)
+			}
+			defer release()
+
+			var moves []batchMove
+			switch command {
+			case "rename":
+				moves, err = app.Aws.renameBatch(pepa, args[0], to, progress)
+			case "move":
+				moves, err = app.Aws.moveEC2Instances(pepa, args, to, progress)
+			case "merge":
+				moves, err = app.Aws.mergeBatches(pepa, to, args, progress)
+			}
+
+			resultX := fmt.Sprintf("%d boxes moved to batch '%s'", len(moves), to)
+			for _, move := range moves {
+				resultX = fmt.Sprintf("%s\n%s  %s -> %s", resultX, move.Box.InstanceID, move.From, to)
+			}
+			if err != nil {
+				resultX = fmt.Sprintf("%s\n\nError retagging boxes:\n%s", resultX, err)
+			}
+
+			err = renameBatchScripts(app, pepa, moves, to)
+			if err != nil {
+				resultX = fmt.Sprintf("%s\n\nError renaming post launch scripts:\n%s", resultX, err)
+			}
+			// keep working on the renamed batch
+			var settings func(app *applicationMain)
+			if command == "rename" && app.BatchTag == args[0] && len(moves) > 0 {
+				app.BatchTag = to
+				settings = func(app *applicationMain) { app.BatchTag = to }
+				resultX = fmt.Sprintf("%s\n\nBatch Tag is now '%s', Save Settings to keep it", resultX, to)
+			}
+			if app.BatchTag == to && len(moves) > 0 {
+				err = regeneratePostScripts(app, pepa)
+				if err != nil {
+					resultX = fmt.Sprintf("%s\n\nError updating post launch scripts:\n%s", resultX, err)
+				}
+			}
+
+			return backgroundJobMsg{result: resultX, settings: settings}
+		},
+	}
+}
+
+// script files carry the batch tag and the box address in their name, the
+// scripts of moved boxes are written again under the new batch rather than
+// renamed, a tag inside the address or the prefix stays as it is
+func renameBatchScripts(app *applicationMain, pepa ec2API, moves []batchMove, to string) error {
+	scriptsFolder := fmt.Sprintf("./%s", app.Aws.Region)
+	entries, _ := os.ReadDir(scriptsFolder)
+	retagged := snapshotApp(app)
+	retagged.BatchTag = to
+	sshConfig := ""
+	for _, move := range moves {
+		address := app.Aws.boxAddress(move.Box)
+		if address == "" || move.From == "" {
+			continue
+		}
+		found := false
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || name != postScriptName(move.From, address) {
+				continue
+			}
+			found = true
+			err := os.Remove(filepath.Join(scriptsFolder, name))
+			if err != nil {
+				return err
+			}
+		}
+		if !found {
+			continue
+		}
+		if sshConfig == "" {
+			var err error
+			sshConfig, err = writeBatchSSHConfig(retagged, pepa)
+			if sshConfig == "" {
+				return err
+			}
+		}
+		err := retagged.createPostSCRIPT(address, sshConfig)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Elastic IPs nobody uses still cost money
+func idleElasticIPsWarning(app *applicationMain, pepa *ec2.Client) string {
+	idle, err := app.Aws.idleElasticIPs(pepa)
+	if err != nil || len(idle) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\n\nWarning: %d AUTO-BOX Elastic IPs are not associated and still cost money:\n%s", len(idle), strings.Join(idle, "\n"))
+}
+
+// only touches the scripts of the boxes that changed
+func updateScaledScripts(app *applicationMain, pepa ec2API, scaled scaleResult) error {
+	scriptsFolder := fmt.Sprintf("./%s", app.Aws.Region)
+	entries, _ := os.ReadDir(scriptsFolder)
+	for _, box := range scaled.Terminated {
+		address := app.Aws.boxAddress(box)
+		for _, entry := range entries {
+			if address != "" && entry.Name() == postScriptName(app.BatchTag, address) {
+				err := os.Remove(filepath.Join(scriptsFolder, entry.Name()))
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if len(scaled.Launched) == 0 {
+		return nil
+	}
+	sshConfig, err := writeBatchSSHConfig(app, pepa)
+	if sshConfig == "" {
+		return err
+	}
+	for _, box := range scaled.Launched {
+		address := app.Aws.boxAddress(box)
+		if address == "" {
+			continue
+		}
+		err := app.createPostSCRIPT(address, sshConfig)
+		if err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// drops batch scripts for IPs that are gone and writes scripts for the current IPs
+func regeneratePostScripts(app *applicationMain, pepa *ec2.Client) error {
+	_, boxes, err := app.Aws.compileIPaddressesAws(pepa, app.BatchTag)
+	if err != nil {
+		return err
+	}
+	ips := []string{}
+	for _, box := range boxes {
+		if address := app.Aws.boxAddress(box); address != "" {
+			ips = append(ips, address)
+		}
+	}
+
+	scriptsFolder := fmt.Sprintf("./%s", app.Aws.Region)
+	entries, _ := os.ReadDir(scriptsFolder)
+	for _, entry := range entries {
+		if entry.IsDir() || !isBoxScript(entry.Name(), app.BatchTag, nil, nil) {
+			continue
+		}
+		if !isBoxScript(entry.Name(), app.BatchTag, nil, ips) {
+			err = os.Remove(filepath.Join(scriptsFolder, entry.Name()))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	sshConfig, err := writeBoxesSSHConfig(app, pepa, nil, boxes)
+	if sshConfig == "" {
+		return err
+	}
+	for _, ip := range ips {
+		err := app.createPostSCRIPT(ip, sshConfig)
+		if err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// "i-0abc,i-0def" or "i-0abc i-0def"
+func splitBoxList(boxList string) []string {
+	return strings.FieldsFunc(boxList, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
+
+// tags the AUTO-BOX boxes of the batch (all when empty) created before
+// workspaces with the current Workspace
+func backgroundJobClaimBoxes() job {
+	return job{
+		title: "Claiming boxes",
+		color: "82",
+		run: func(_ context.Context, app *applicationMain, _ progressFunc) tea.Msg {
+			if app.Provider == "digital" {
+				return backgroundJobMsg{result: "Workspaces are only available for AWS"}
+			}
+
+			pepa, err := app.Aws.createEc2Client()
+			if err != nil {
+				return jobFailedMsg(fmt.Sprintf("error getting AWS credentials:\n%s", err), err)
+			}
+
+			claimed, err := app.Aws.claimResources(pepa, app.BatchTag)
+			if err != nil {
+				return jobFailedMsg(fmt.Sprintf("Error claiming boxes:\n%s", err), err)
+			}
+
+			resultX := fmt.Sprintf("%d boxes, %d Elastic IPs, %d key pairs and %d security groups claimed into workspace '%s'",
+				len(claimed.InstanceIDs), len(claimed.AllocationIDs), len(claimed.KeyPairIDs), len(claimed.SecurityGroupIDs), app.Aws.Workspace)
+			for _, instanceID := range claimed.InstanceIDs {
+				resultX = fmt.Sprintf("%s\n%s", resultX, instanceID)
+			}
+			return backgroundJobMsg{result: resultX}
+		},
+	}
+}
+
+// drops the deploy/delete/scale lock on the current batch (ALL when no
+// batch) left behind by an operator who is gone
+func backgroundJobForceUnlock() job {
+	return job{
+		title: "Unlocking batch",
+		color: "82",
+		run: func(_ context.Context, app *applicationMain, _ progressFunc) tea.Msg {
+			if app.Provider == "digital" {
+				return backgroundJobMsg{result: "Batch locks are only available for AWS"}
+			}
+
+			pepa, err := app.Aws.createEc2Client()
+			if err != nil {
+				return jobFailedMsg(fmt.Sprintf("error getting AWS credentials:\n%s", err), err)
+			}
+			sgAuto, err := app.Aws.createSecurityGroup("sgAutoBox", "pepita stuff", pepa)
+			if err != nil {
+				return jobFailedMsg(fmt.Sprintf("error creating Security Group:\n%s", err), err)
+			}
+
+			lock, found, err := app.Aws.forceUnlock(pepa, sgAuto, app.BatchTag)
+			if err != nil {
+				return jobFailedMsg(fmt.Sprintf("Error unlocking batch:\n%s", err), err)
+			}
+			if !found {
+				return backgroundJobMsg{result: fmt.Sprintf("Batch '%s' is not locked", batchOrAll(app.BatchTag))}
+			}
+			return backgroundJobMsg{result: fmt.Sprintf("Removed lock on batch '%s' held by %s since %s",
+				batchOrAll(app.BatchTag), lock.Holder, lock.Since.Local().Format("2006-01-02 15:04"))}
+		},
+	}
+}
+
+// shows who holds the lock before it is taken from them
+func backgroundJobPlanForceUnlock() job {
+	unlock := backgroundJobForceUnlock()
+	return job{
+		title: "Checking who holds the lock",
+		color: "82",
+		wait:  true,
+		run: func(_ context.Context, app *applicationMain, _ progressFunc) tea.Msg {
+			if app.Provider == "digital" {
+				return confirmMsg{run: unlock, settings: app}
+			}
+
+			pepa, err := app.Aws.createEc2Client()
+			if err != nil {
+				return confirmMsg{result: fmt.Sprintf("Not unlocking:\nerror getting AWS credentials:\n%s", err)}
+			}
+			sgAuto, err := app.Aws.createSecurityGroup("sgAutoBox", "pepita stuff", pepa)
+			if err != nil {
+				return confirmMsg{result: fmt.Sprintf("Not unlocking:\nerror creating Security Group:\n%s", err)}
+			}
+			locks, err := app.Aws.batchLocks(pepa, sgAuto)
+			if err != nil {
+				return confirmMsg{result: fmt.Sprintf("Not unlocking:\n%s", err)}
+			}
+			want := batchLock{Workspace: app.Aws.Workspace, Batch: app.BatchTag}
+			index := slices.IndexFunc(locks, func(lock batchLock) bool {
+				return lock.tagKey() == want.tagKey()
+			})
+			if index < 0 {
+				return confirmMsg{result: fmt.Sprintf("Batch '%s' is not locked", batchOrAll(app.BatchTag))}
+			}
+
+			lock := locks[index]
+			return confirmMsg{
+				title: fmt.Sprintf("FORCE Unlock will take batch '%s' from", batchOrAll(app.BatchTag)),
+				summary: [][2]string{
+					{"Held by", lock.Holder},
+					{"Since", lock.Since.Local().Format("2006-01-02 15:04")},
+					{"Expires", lock.Expires.Local().Format("2006-01-02 15:04")},
+				},
+				phrase:   cmp.Or(app.BatchTag, app.Aws.Region),
+				run:      unlock,
+				settings: app,
+			}
+		},
+	}
+}
+
+func batchOrAll(batchT string) string {
+	if batchT == "" {
+		return "ALL"
+	}
+	return batchT
+}
+
+func backgroundJobVerifyVNC(only boxList) job {
+	return job{
+		title: pickedTitle("Verify with TightVNC", only),
+		color: "82",
+		run: func(ctx context.Context, app *applicationMain, progress progressFunc) tea.Msg {
+			return jobResultMsg(verifyBoxes(ctx, app, only, progress))
+		},
+	}
+}
+
+func ShowMenu(app *applicationMain) {
+	m := newMenuList(app)
+
+	//show Menu
+	_, err := tea.NewProgram(m, tea.WithAltScreen()).Run()
+	if err != nil {
+		fmt.Println("Error running program:", err)
+		os.Exit(1)
+	}
+}
+
+func newMenuList(app *applicationMain) MenuList {
+	const listWidth = 90
+	const listHeight = 14
+
+	// Initialize the list with empty items; items will be set in updateListItems
+	l := list.New([]list.Item{}, itemDelegate{}, listWidth, listHeight)
+	l.Title = ""
+	l.SetShowStatusBar(false)
+	l.SetFilteringEnabled(false)
+	l.SetShowTitle(true)
+	l.Styles.Title = lipTitleStyle
+	l.Styles.PaginationStyle = paginationStyle
+	l.Styles.HelpStyle = helpStyle
+	l.KeyMap.ShowFullHelp = key.NewBinding() // remove '?' help option
+
+	s := spinner.New()
+	s.Spinner = spinner.Pulse
+
+	m := MenuList{
+		list:      l,
+		header:    app.getAppHeader(),
+		state:     StateMainMenu,
+		spinner:   s,
+		inventory: newInventoryScreen(),
+		app:       app,
+	}
+	if app.Provider == "aws" {
+		// Init looks it up
+		m.amiHeader = "AMI: looking up..."
+	}
+
+	m.updateListItems()
+
+	m.list.KeyMap.Quit = key.NewBinding(
+		key.WithKeys("esc", "ctrl+c"),
+		key.WithHelp("esc", "quit"),
+	)
+	return m
+}
+
+type item string
+
+func (i item) FilterValue() string { return "" }
+
+type itemDelegate struct{}
+
+func (d itemDelegate) Height() int                             { return 1 }
+func (d itemDelegate) Spacing() int                            { return 0 }
+func (d itemDelegate) Update(_ tea.Msg, _ *list.Model) tea.Cmd { return nil }
+func (d itemDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
+	i, ok := listItem.(item)
+	if !ok {
+		return
+	}
+
+	str := fmt.Sprintf("%d. %s", index+1, i)
+
+	fn := itemStyle.Render
+	if index == m.Index() {
+		fn = func(s ...string) string {
+			return selectedItemStyle.Render("> " + strings.Join(s, " "))
+		}
+	}
+
+	fmt.Fprint(w, fn(str))
+}
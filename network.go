@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// "tag:Key=Value" -> filter on that tag
+func tagSelectorFilter(selector string) (types.Filter, bool) {
+	tag, found := strings.CutPrefix(selector, "tag:")
+	if !found {
+		return types.Filter{}, false
+	}
+	key, value, _ := strings.Cut(tag, "=")
+	return types.Filter{
+		Name:   aws.String(fmt.Sprintf("tag:%s", key)),
+		Values: []string{value},
+	}, true
+}
+
+// Vpc can be a VPC ID, "tag:Key=Value" or empty for the VPC of the Subnets,
+// or the default VPC when no Subnets are set either
+func (a *AWS) resolveVpcID(client ec2API) (string, error) {
+	ctx := a.jobContext()
+
+	if strings.HasPrefix(a.Vpc, "vpc-") {
+		return a.Vpc, nil
+	}
+	if a.Vpc == "" && a.Subnets != "" {
+		return a.subnetsVpcID(client)
+	}
+
+	filter := types.Filter{
+		Name:   aws.String("is-default"),
+		Values: []string{"true"},
+	}
+	if a.Vpc != "" {
+		tagFilter, ok := tagSelectorFilter(a.Vpc)
+		if !ok {
+			return "", fmt.Errorf("VPC setting %q is not a VPC ID or tag:Key=Value", a.Vpc)
+		}
+		filter = tagFilter
+	}
+
+	resp, err := client.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{
+		Filters: []types.Filter{filter},
+	})
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case len(resp.Vpcs) == 0 && a.Vpc == "":
+		return "", fmt.Errorf("no default VPC in %s, set the VPC to deploy into", a.Region)
+	case len(resp.Vpcs) == 0:
+		return "", fmt.Errorf("no VPC matches %s in %s", a.Vpc, a.Region)
+	case len(resp.Vpcs) > 1:
+		return "", fmt.Errorf("%d VPCs match %s, narrow it down", len(resp.Vpcs), a.Vpc)
+	}
+	return *resp.Vpcs[0].VpcId, nil
+}
+
+// the VPC the Subnets setting points into, regions without a default VPC
+// only have the subnets to go by
+func (a *AWS) subnetsVpcID(client ec2API) (string, error) {
+	ctx := a.jobContext()
+
+	input := &ec2.DescribeSubnetsInput{}
+	a.selectSubnets(input)
+	resp, err := client.DescribeSubnets(ctx, input)
+	if err != nil {
+		return "", err
+	}
+	vpcIDs := []string{}
+	for _, subnet := range resp.Subnets {
+		if !slices.Contains(vpcIDs, aws.ToString(subnet.VpcId)) {
+			vpcIDs = append(vpcIDs, aws.ToString(subnet.VpcId))
+		}
+	}
+	switch {
+	case len(vpcIDs) == 0:
+		return "", fmt.Errorf("no subnets match %s in %s", a.Subnets, a.Region)
+	case len(vpcIDs) > 1:
+		return "", fmt.Errorf("subnets %s are in %d VPCs (%s), set the VPC", a.Subnets, len(vpcIDs), strings.Join(vpcIDs, ", "))
+	}
+	return vpcIDs[0], nil
+}
+
+// narrows input down to the subnets of the Subnets setting
+func (a *AWS) selectSubnets(input *ec2.DescribeSubnetsInput) {
+	switch {
+	case strings.HasPrefix(a.Subnets, "tag:"):
+		tagFilter, _ := tagSelectorFilter(a.Subnets)
+		input.Filters = append(input.Filters, tagFilter)
+	case a.Subnets != "":
+		input.SubnetIds = strings.FieldsFunc(a.Subnets, func(r rune) bool {
+			return r == ',' || r == ' '
+		})
+	}
+}
+
+// Subnets can be a list of subnet IDs, "tag:Key=Value" or empty for every subnet of the VPC
+func (a *AWS) resolveSubnets(client ec2API, vpcID string) ([]types.Subnet, error) {
+	ctx := a.jobContext()
+
+	input := &ec2.DescribeSubnetsInput{
+		Filters: []types.Filter{
+			{
+				Name:   aws.String("vpc-id"),
+				Values: []string{vpcID},
+			},
+		},
+	}
+	if a.Subnets == "" && a.Vpc == "" {
+		input.Filters = append(input.Filters, types.Filter{
+			Name:   aws.String("default-for-az"),
+			Values: []string{"true"},
+		})
+	}
+	a.selectSubnets(input)
+
+	resp, err := client.DescribeSubnets(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Subnets) == 0 {
+		return nil, fmt.Errorf("no subnets found in %s", vpcID)
+	}
+
+	subnets := resp.Subnets
+	sort.Slice(subnets, func(i, j int) bool {
+		if *subnets[i].AvailabilityZone != *subnets[j].AvailabilityZone {
+			return *subnets[i].AvailabilityZone < *subnets[j].AvailabilityZone
+		}
+		return *subnets[i].SubnetId < *subnets[j].SubnetId
+	})
+	return subnets, nil
+}
+
+// one subnet per new box, offset is how many boxes the batch already has.
+// With SpreadAZs the boxes rotate through the availability zones, otherwise
+// they all go in the first subnet. Empty when nothing is configured so EC2
+// keeps picking the default subnet.
+func (a *AWS) launchSubnets(client ec2API, count, offset int) ([]string, error) {
+	subnetIDs := make([]string, count)
+	if a.Vpc == "" && a.Subnets == "" && !a.SpreadAZs && !a.IPv6 {
+		return subnetIDs, nil
+	}
+
+	vpcID, err := a.resolveVpcID(client)
+	if err != nil {
+		return nil, err
+	}
+	subnets, err := a.resolveSubnets(client, vpcID)
+	if err != nil {
+		return nil, err
+	}
+	if a.IPv6 {
+		subnets = dualStackSubnets(subnets)
+		if len(subnets) == 0 {
+			return nil, fmt.Errorf("no subnet in %s has an IPv6 CIDR block", vpcID)
+		}
+	}
+
+	perAZ := []string{}
+	seenAZ := map[string]bool{}
+	for _, subnet := range subnets {
+		if !seenAZ[*subnet.AvailabilityZone] {
+			seenAZ[*subnet.AvailabilityZone] = true
+			perAZ = append(perAZ, *subnet.SubnetId)
+		}
+	}
+
+	for i := range subnetIDs {
+		if a.SpreadAZs {
+			subnetIDs[i] = perAZ[(offset+i)%len(perAZ)]
+		} else {
+			subnetIDs[i] = *subnets[0].SubnetId
+		}
+	}
+	return subnetIDs, nil
+}
+
+// creates PlacementGroup with PlacementStrategy (spread by default) when it doesn't exist yet
+func (a *AWS) ensurePlacementGroup(client ec2API) error {
+	ctx := a.jobContext()
+
+	if a.PlacementGroup == "" {
+		return nil
+	}
+
+	existing, err := client.DescribePlacementGroups(ctx, &ec2.DescribePlacementGroupsInput{
+		Filters: []types.Filter{
+			{
+				Name:   aws.String("group-name"),
+				Values: []string{a.PlacementGroup},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if len(existing.PlacementGroups) > 0 {
+		return nil
+	}
+
+	strategy := types.PlacementStrategySpread
+	if a.PlacementStrategy != "" {
+		strategy = types.PlacementStrategy(a.PlacementStrategy)
+	}
+	_, err = client.CreatePlacementGroup(ctx, &ec2.CreatePlacementGroupInput{
+		GroupName: aws.String(a.PlacementGroup),
+		Strategy:  strategy,
+		TagSpecifications: []types.TagSpecification{
+			{
+				ResourceType: types.ResourceTypePlacementGroup,
+				Tags:         a.ownedTags(),
+			},
+		},
+	})
+	return err
+}
+
+func dualStackSubnets(subnets []types.Subnet) []types.Subnet {
+	dualStack := []types.Subnet{}
+	for _, subnet := range subnets {
+		if len(subnet.Ipv6CidrBlockAssociationSet) > 0 {
+			dualStack = append(dualStack, subnet)
+		}
+	}
+	return dualStack
+}
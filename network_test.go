@@ -0,0 +1,185 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func testNetwork(client *fakeEC2) {
+	client.vpcs = append(client.vpcs, types.Vpc{
+		VpcId: aws.String("vpc-lab"),
+		Tags:  []types.Tag{{Key: aws.String("Name"), Value: aws.String("lab")}},
+	})
+	subnet := func(id, vpcID, az string, defaultForAz, dualStack bool) types.Subnet {
+		subnet := types.Subnet{
+			SubnetId:         aws.String(id),
+			VpcId:            aws.String(vpcID),
+			AvailabilityZone: aws.String(az),
+			DefaultForAz:     aws.Bool(defaultForAz),
+		}
+		if dualStack {
+			subnet.Ipv6CidrBlockAssociationSet = []types.SubnetIpv6CidrBlockAssociation{
+				{Ipv6CidrBlock: aws.String("2600:1f18::/64")},
+			}
+		}
+		return subnet
+	}
+	client.subnets = []types.Subnet{
+		subnet("subnet-def-b", "vpc-default", "us-east-1b", true, false),
+		subnet("subnet-def-a", "vpc-default", "us-east-1a", true, false),
+		subnet("subnet-lab-a2", "vpc-lab", "us-east-1a", false, false),
+		subnet("subnet-lab-a1", "vpc-lab", "us-east-1a", false, true),
+		subnet("subnet-lab-c", "vpc-lab", "us-east-1c", false, true),
+	}
+}
+
+func TestTagSelectorFilter(t *testing.T) {
+	tests := []struct {
+		selector  string
+		wantOK    bool
+		wantName  string
+		wantValue string
+	}{
+		{"tag:Name=lab", true, "tag:Name", "lab"},
+		{"tag:Env=", true, "tag:Env", ""},
+		{"vpc-123", false, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			filter, ok := tagSelectorFilter(tt.selector)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %t, want %t", ok, tt.wantOK)
+			}
+			if ok && (aws.ToString(filter.Name) != tt.wantName || filter.Values[0] != tt.wantValue) {
+				t.Errorf("filter = %s %v, want %s %s", aws.ToString(filter.Name), filter.Values, tt.wantName, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestResolveVpcID(t *testing.T) {
+	tests := []struct {
+		name    string
+		vpc     string
+		subnets string
+		want    string
+		wantErr bool
+	}{
+		{"default VPC", "", "", "vpc-default", false},
+		{"VPC ID as is", "vpc-0abc", "", "vpc-0abc", false},
+		{"tag selector", "tag:Name=lab", "", "vpc-lab", false},
+		{"no match", "tag:Name=prod", "", "", true},
+		{"bad setting", "lab", "", "", true},
+		{"VPC of the subnets", "", "subnet-lab-c,subnet-lab-a2", "vpc-lab", false},
+		{"subnets in two VPCs", "", "subnet-lab-c,subnet-def-a", "", true},
+		{"no subnet matches", "", "subnet-gone", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeEC2()
+			testNetwork(client)
+			a := testAWS()
+			a.Vpc = tt.vpc
+			a.Subnets = tt.subnets
+
+			got, err := a.resolveVpcID(client)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %t", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolveVpcID = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLaunchSubnets(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(a *AWS)
+		count     int
+		offset    int
+		want      []string
+		wantErr   bool
+	}{
+		{"nothing configured", func(a *AWS) {}, 2, 0, []string{"", ""}, false},
+		{"default VPC spread", func(a *AWS) { a.SpreadAZs = true }, 3, 0, []string{"subnet-def-a", "subnet-def-b", "subnet-def-a"}, false},
+		{"spread continues after existing boxes", func(a *AWS) { a.SpreadAZs = true }, 1, 1, []string{"subnet-def-b"}, false},
+		{"VPC without spread uses first subnet", func(a *AWS) { a.Vpc = "tag:Name=lab" }, 2, 0, []string{"subnet-lab-a1", "subnet-lab-a1"}, false},
+		{"one subnet per AZ", func(a *AWS) {
+			a.Vpc = "vpc-lab"
+			a.SpreadAZs = true
+		}, 3, 0, []string{"subnet-lab-a1", "subnet-lab-c", "subnet-lab-a1"}, false},
+		{"subnet list", func(a *AWS) {
+			a.Vpc = "vpc-lab"
+			a.Subnets = "subnet-lab-c, subnet-lab-a2"
+		}, 1, 0, []string{"subnet-lab-a2"}, false},
+		{"subnet list without a VPC or default VPC", func(a *AWS) {
+			a.Subnets = "subnet-lab-c"
+			a.SpreadAZs = true
+		}, 2, 0, []string{"subnet-lab-c", "subnet-lab-c"}, false},
+		{"IPv6 skips single stack subnets", func(a *AWS) {
+			a.Vpc = "vpc-lab"
+			a.Subnets = "subnet-lab-a2,subnet-lab-c"
+			a.IPv6 = true
+		}, 1, 0, []string{"subnet-lab-c"}, false},
+		{"IPv6 without dual-stack subnet", func(a *AWS) { a.IPv6 = true }, 1, 0, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeEC2()
+			testNetwork(client)
+			a := testAWS()
+			tt.configure(a)
+
+			got, err := a.launchSubnets(client, tt.count, tt.offset)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %t", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("launchSubnets = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnsurePlacementGroup(t *testing.T) {
+	tests := []struct {
+		name         string
+		group        string
+		strategy     string
+		existing     bool
+		wantCreate   int
+		wantStrategy types.PlacementStrategy
+	}{
+		{"not configured", "", "", false, 0, ""},
+		{"created with spread by default", "pg-web", "", false, 1, types.PlacementStrategySpread},
+		{"created with the strategy", "pg-web", "cluster", false, 1, types.PlacementStrategyCluster},
+		{"existing group", "pg-web", "", true, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeEC2()
+			if tt.existing {
+				client.placementGroups = []types.PlacementGroup{{GroupName: aws.String(tt.group)}}
+			}
+			a := testAWS()
+			a.PlacementGroup = tt.group
+			a.PlacementStrategy = tt.strategy
+
+			err := a.ensurePlacementGroup(client)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := client.callCount("CreatePlacementGroup"); got != tt.wantCreate {
+				t.Fatalf("CreatePlacementGroup called %d times, want %d", got, tt.wantCreate)
+			}
+			if tt.wantCreate > 0 && client.placementGroups[0].Strategy != tt.wantStrategy {
+				t.Errorf("strategy = %s, want %s", client.placementGroups[0].Strategy, tt.wantStrategy)
+			}
+		})
+	}
+}
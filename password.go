@@ -0,0 +1,149 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+)
+
+// Windows generates the Administrator password a few minutes after first boot
+const passwordWaitTimeout = 20 * time.Minute
+
+type BoxCredential struct {
+	InstanceID string
+	PublicIP   string
+	Username   string
+	Password   string
+	Err        error
+}
+
+func (a *AWS) pemFilePath() string {
+	return filepath.Join(fmt.Sprintf("./%s", a.Region), fmt.Sprintf("%s.pem", a.keyPairName()))
+}
+
+func (a *AWS) loadPEMKey() (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(a.pemFilePath())
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", a.pemFilePath())
+	}
+
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err == nil {
+		return key, nil
+	}
+	parsed, err2 := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err2 != nil {
+		return nil, err
+	}
+	rsaKey, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an RSA key", a.pemFilePath())
+	}
+	return rsaKey, nil
+}
+
+// password data is base64 of the password encrypted with the key pair (RSA PKCS#1 v1.5)
+func decryptPasswordData(passwordData string, key *rsa.PrivateKey) (string, error) {
+	encrypted, err := base64.StdEncoding.DecodeString(passwordData)
+	if err != nil {
+		return "", err
+	}
+	password, err := rsa.DecryptPKCS1v15(rand.Reader, key, encrypted)
+	if err != nil {
+		return "", err
+	}
+	return string(password), nil
+}
+
+func (a *AWS) getWindowsPasswords(client ec2API, batchT string) ([]BoxCredential, error) {
+	ctx := a.jobContext()
+
+	key, err := a.loadPEMKey()
+	if err != nil {
+		return nil, err
+	}
+
+	instances, err := a.batchInstances(client, batchT, "running")
+	if err != nil {
+		return nil, err
+	}
+
+	creds := make([]BoxCredential, len(instances))
+	var wg sync.WaitGroup
+	for i, instance := range instances {
+		creds[i] = BoxCredential{
+			InstanceID: *instance.InstanceId,
+			PublicIP:   aws.ToString(instance.PublicIpAddress),
+			Username:   "Administrator",
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			waiter := ec2.NewPasswordDataAvailableWaiter(client)
+			resp, err := waiter.WaitForOutput(ctx, &ec2.GetPasswordDataInput{
+				InstanceId: instance.InstanceId,
+			}, passwordWaitTimeout)
+			if err != nil {
+				creds[i].Err = err
+				return
+			}
+			creds[i].Password, creds[i].Err = decryptPasswordData(aws.ToString(resp.PasswordData), key)
+		}()
+	}
+	wg.Wait()
+
+	return creds, nil
+}
+
+// writes the credentials next to the PEM key, readable by the owner only
+func (a *AWS) exportWindowsPasswords(creds []BoxCredential, batchT string) (string, error) {
+	scriptsFolder := fmt.Sprintf("./%s", a.Region)
+	err := os.MkdirAll(scriptsFolder, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	fileName := "passwords.csv"
+	if batchT != "" {
+		fileName = fmt.Sprintf("passwords-%s.csv", batchT)
+	}
+	filePath, err := filepath.Abs(filepath.Join(scriptsFolder, fileName))
+	if err != nil {
+		return "", err
+	}
+
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"instance", "ip", "username", "password"})
+	for _, cred := range creds {
+		if cred.Err != nil {
+			continue
+		}
+		writer.Write([]string{cred.InstanceID, cred.PublicIP, cred.Username, cred.Password})
+	}
+	writer.Flush()
+
+	return filePath, writer.Error()
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/pem"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func writeTestPEMKey(t *testing.T, a *AWS, pkcs8 bool) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+	if pkcs8 {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+
+	err = os.MkdirAll(a.Region, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(a.pemFilePath(), pem.EncodeToMemory(block), 0400)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func encryptTestPassword(t *testing.T, key *rsa.PrivateKey, password string) string {
+	t.Helper()
+
+	encrypted, err := rsa.EncryptPKCS1v15(rand.Reader, &key.PublicKey, []byte(password))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(encrypted)
+}
+
+func TestLoadPEMKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		pkcs8   bool
+		wantErr bool
+	}{
+		{"PKCS#1", "", false, false},
+		{"PKCS#8", "", true, false},
+		{"not a PEM file", "ssh-rsa AAAA", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chdirTemp(t)
+			a := testAWS()
+			if tt.content == "" {
+				writeTestPEMKey(t, a, tt.pkcs8)
+			} else {
+				os.MkdirAll(a.Region, 0755)
+				os.WriteFile(a.pemFilePath(), []byte(tt.content), 0600)
+			}
+
+			_, err := a.loadPEMKey()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetWindowsPasswords(t *testing.T) {
+	chdirTemp(t)
+	a := testAWS()
+	key := writeTestPEMKey(t, a, false)
+	client := newFakeEC2()
+	first := client.addInstance("web", types.InstanceStateNameRunning)
+	second := client.addInstance("web", types.InstanceStateNameRunning)
+	client.addInstance("web", types.InstanceStateNameStopped)
+	client.addInstance("db", types.InstanceStateNameRunning)
+	client.passwordData = map[string]string{
+		first: encryptTestPassword(t, key, "Pa55word!"),
+		// garbage, the box reports an error instead of failing the batch
+		second: base64.StdEncoding.EncodeToString([]byte("not encrypted with this key")),
+	}
+
+	creds, err := a.getWindowsPasswords(client, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(creds) != 2 {
+		t.Fatalf("%d credentials, want one per running box of the batch", len(creds))
+	}
+	for _, cred := range creds {
+		switch cred.InstanceID {
+		case first:
+			if cred.Err != nil || cred.Password != "Pa55word!" || cred.Username != "Administrator" {
+				t.Errorf("first box = %+v", cred)
+			}
+		case second:
+			if cred.Err == nil {
+				t.Error("second box decrypted garbage")
+			}
+		}
+	}
+
+	csvFile, err := a.exportWindowsPasswords(creds, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(csvFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[1][3] != "Pa55word!" {
+		t.Errorf("CSV rows = %v, want the header and the first box", rows)
+	}
+}
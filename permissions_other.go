@@ -0,0 +1,8 @@
+//go:build !windows
+
+package main
+
+// the PEM file is already written 0400, nothing else to restrict
+func (a *AWS) restrictWindowsFilePermissions(fileName string) error {
+	return nil
+}
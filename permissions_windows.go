@@ -0,0 +1,21 @@
+//go:build windows
+
+package main
+
+import "syscall"
+
+func (a *AWS) restrictWindowsFilePermissions(fileName string) error {
+	// Get file attributes
+	pointer, err := syscall.UTF16PtrFromString(fileName)
+	if err != nil {
+		return err
+	}
+
+	// Set file as readable only by the owner
+	err = syscall.SetFileAttributes(pointer, syscall.FILE_ATTRIBUTE_READONLY)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// multi-select list of boxes: space toggles, 'a' toggles all, enter confirms
+type boxPicker struct {
+	title    string
+	items    []pickerItem
+	cursor   int
+	selected map[int]bool
+}
+
+type pickerItem struct {
+	ID    string
+	Label string
+	Notes []string
+}
+
+// returned by the job listing the boxes to pick from, action says what the
+// picked IDs are for. result is shown instead when there is nothing to pick
+type pickerListMsg struct {
+	action string
+	target string
+	title  string
+	items  []pickerItem
+	result string
+}
+
+func newBoxPicker(title string, items []pickerItem) boxPicker {
+	return boxPicker{
+		title:    title,
+		items:    items,
+		selected: map[int]bool{},
+	}
+}
+
+// confirmed = enter with at least one box selected, cancelled = esc
+func (p *boxPicker) update(msg tea.KeyMsg) (confirmed, cancelled bool) {
+	switch msg.String() {
+	case "up", "k":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case "down", "j":
+		if p.cursor < len(p.items)-1 {
+			p.cursor++
+		}
+	case " ", "x":
+		p.selected[p.cursor] = !p.selected[p.cursor]
+	case "a":
+		all := len(p.selectedIDs()) < len(p.items)
+		for i := range p.items {
+			p.selected[i] = all
+		}
+	case "enter":
+		return len(p.selectedIDs()) > 0, false
+	case "esc", "q":
+		return false, true
+	}
+	return false, false
+}
+
+func (p boxPicker) selectedIDs() []string {
+	ids := []string{}
+	for i, item := range p.items {
+		if p.selected[i] {
+			ids = append(ids, item.ID)
+		}
+	}
+	return ids
+}
+
+func (p boxPicker) view() string {
+	promptStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(textPromptColor)).Bold(true)
+	noteStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(textJobOutcomeFront))
+	helpText := lipgloss.NewStyle().Foreground(lipgloss.Color("241")).
+		Render("space: select   a: all   enter: confirm   esc: back")
+
+	rows := strings.Builder{}
+	for i, item := range p.items {
+		check := "[ ]"
+		if p.selected[i] {
+			check = "[x]"
+		}
+		row := fmt.Sprintf("%s %s", check, item.Label)
+		if i == p.cursor {
+			rows.WriteString(selectedItemStyle.Render("> " + row))
+		} else {
+			rows.WriteString(itemStyle.Render(row))
+		}
+		rows.WriteString("\n")
+		for _, note := range item.Notes {
+			rows.WriteString(itemStyle.Render("    " + noteStyle.Render(note)))
+			rows.WriteString("\n")
+		}
+	}
+
+	return fmt.Sprintf("\n\n%s (%d selected)\n\n%s\n%s", promptStyle.Render(p.title), len(p.selectedIDs()), rows.String(), helpText)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+type stepStatus int
+
+const (
+	stepRunning stepStatus = iota
+	stepDone
+	stepFailed
+)
+
+// one progress event of a running job, Total > 0 only announces how many
+// steps are coming so the bar is right before they start
+type progressMsg struct {
+	Step   string
+	Detail string
+	Status stepStatus
+	Err    error
+	Total  int
+}
+
+// reports job progress, a nil progressFunc drops it
+type progressFunc func(progressMsg)
+
+func (p progressFunc) total(n int) {
+	if p != nil {
+		p(progressMsg{Total: n})
+	}
+}
+
+func (p progressFunc) start(step string) {
+	if p != nil {
+		p(progressMsg{Step: step, Status: stepRunning})
+	}
+}
+
+// done when err is nil, failed otherwise
+func (p progressFunc) finish(step, detail string, err error) {
+	if p == nil {
+		return
+	}
+	msg := progressMsg{Step: step, Detail: detail, Status: stepDone}
+	if err != nil {
+		msg.Status, msg.Err = stepFailed, err
+	}
+	p(msg)
+}
+
+// CLI progress: a line per finished step, for CI logs
+func printProgress(w io.Writer) progressFunc {
+	return func(msg progressMsg) {
+		if line := progressLine(msg); line != "" {
+			fmt.Fprintln(w, line)
+		}
+	}
+}
+
+// log line of a finished step, empty for the others
+func progressLine(msg progressMsg) string {
+	switch msg.Status {
+	case stepDone:
+		return fmt.Sprintf("ok    %s %s", msg.Step, msg.Detail)
+	case stepFailed:
+		return fmt.Sprintf("FAIL  %s: %s", msg.Step, oneLine(msg.Err))
+	}
+	return ""
+}
+
+// job errors read "what failed:\ncause", keep both on one row
+func oneLine(err error) string {
+	return strings.Join(strings.Fields(err.Error()), " ")
+}
+
+type checklistStep struct {
+	name   string
+	detail string
+	status stepStatus
+	err    error
+}
+
+// steps of the running job in the order they started
+type jobChecklist struct {
+	steps []checklistStep
+	total int
+}
+
+func (c *jobChecklist) update(msg progressMsg) {
+	if msg.Total > 0 {
+		c.total = msg.Total
+		return
+	}
+	for i := range c.steps {
+		if c.steps[i].name == msg.Step {
+			c.steps[i].status, c.steps[i].err = msg.Status, msg.Err
+			if msg.Detail != "" {
+				c.steps[i].detail = msg.Detail
+			}
+			return
+		}
+	}
+	c.steps = append(c.steps, checklistStep{name: msg.Step, detail: msg.Detail, status: msg.Status, err: msg.Err})
+}
+
+func (c jobChecklist) counts() (done, failed, total int) {
+	for _, step := range c.steps {
+		switch step.status {
+		case stepDone:
+			done++
+		case stepFailed:
+			failed++
+		}
+	}
+	return done, failed, max(c.total, len(c.steps))
+}
+
+func (c jobChecklist) view(spinner string) string {
+	if len(c.steps) == 0 {
+		return ""
+	}
+	doneStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("82"))
+	failStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(textErrorColorBack))
+	detailStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+
+	rows := strings.Builder{}
+	for _, step := range c.steps {
+		mark := spinner
+		switch step.status {
+		case stepDone:
+			mark = doneStyle.Render("✓")
+		case stepFailed:
+			mark = failStyle.Render("✗")
+		}
+		row := fmt.Sprintf("%s %s", mark, step.name)
+		if step.detail != "" {
+			row = fmt.Sprintf("%s %s", row, detailStyle.Render(step.detail))
+		}
+		if step.err != nil {
+			row = fmt.Sprintf("%s %s", row, failStyle.Render(oneLine(step.err)))
+		}
+		rows.WriteString(itemStyle.Render(row))
+		rows.WriteString("\n")
+	}
+
+	done, failed, total := c.counts()
+	const barWidth = 40
+	filled := barWidth * (done + failed) / total
+	bar := doneStyle.Render(strings.Repeat("█", filled)) + detailStyle.Render(strings.Repeat("░", barWidth-filled))
+	summary := fmt.Sprintf("%d/%d", done+failed, total)
+	if failed > 0 {
+		summary = fmt.Sprintf("%s, %s", summary, failStyle.Render(fmt.Sprintf("%d failed", failed)))
+	}
+	return fmt.Sprintf("%s\n%s\n", rows.String(), itemStyle.Render(bar+" "+summary))
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+type resizeResult struct {
+	InstanceID string
+	Err        error
+}
+
+// stop -> change type -> start for each box, rolling sets how many boxes are
+// resized at a time (0 = all at once). progress gets a step per box
+func (a *AWS) resizeEC2Instances(client ec2API, instanceIDs []string, instanceType string, rolling int, progress progressFunc) ([]resizeResult, error) {
+	ctx := a.jobContext()
+
+	if len(instanceIDs) == 0 {
+		return nil, fmt.Errorf("no boxes to resize")
+	}
+
+	typeResp, err := client.DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{
+		InstanceTypes: []types.InstanceType{types.InstanceType(instanceType)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(typeResp.InstanceTypes) == 0 {
+		return nil, fmt.Errorf("instance type %s is not offered in %s", instanceType, a.Region)
+	}
+	typeInfo := typeResp.InstanceTypes[0]
+
+	resp, err := client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: instanceIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	progress.total(len(instanceIDs))
+	results := []resizeResult{}
+	resizable := []types.Instance{}
+	for _, reservation := range resp.Reservations {
+		for _, instance := range reservation.Instances {
+			err := a.checkResizeCompatible(client, instance, typeInfo)
+			if err != nil {
+				progress.finish(*instance.InstanceId, "", err)
+				results = append(results, resizeResult{InstanceID: *instance.InstanceId, Err: err})
+				continue
+			}
+			resizable = append(resizable, instance)
+		}
+	}
+
+	if rolling <= 0 {
+		rolling = len(resizable)
+	}
+	for start := 0; start < len(resizable); start += rolling {
+		end := min(start+rolling, len(resizable))
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		for _, instance := range resizable[start:end] {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				progress.start(*instance.InstanceId)
+				err := a.resizeEC2Instance(client, instance, instanceType)
+				progress.finish(*instance.InstanceId, instanceType, err)
+				mu.Lock()
+				results = append(results, resizeResult{InstanceID: *instance.InstanceId, Err: err})
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+	}
+
+	return results, nil
+}
+
+func (a *AWS) checkResizeCompatible(client ec2API, instance types.Instance, typeInfo types.InstanceTypeInfo) error {
+	ctx := a.jobContext()
+
+	if instance.State != nil && instance.State.Name != types.InstanceStateNameRunning &&
+		instance.State.Name != types.InstanceStateNameStopped {
+		return fmt.Errorf("box is %s", instance.State.Name)
+	}
+
+	if typeInfo.ProcessorInfo != nil {
+		supported := false
+		for _, arch := range typeInfo.ProcessorInfo.SupportedArchitectures {
+			if string(arch) == string(instance.Architecture) {
+				supported = true
+			}
+		}
+		if !supported {
+			return fmt.Errorf("%s does not support the %s architecture of the AMI", typeInfo.InstanceType, instance.Architecture)
+		}
+	}
+
+	if typeInfo.NetworkInfo != nil && typeInfo.NetworkInfo.EnaSupport == types.EnaSupportRequired && !aws.ToBool(instance.EnaSupport) {
+		return fmt.Errorf("%s requires ENA which the AMI does not enable", typeInfo.InstanceType)
+	}
+
+	if instance.CurrentInstanceBootMode != "" && len(typeInfo.SupportedBootModes) > 0 {
+		supported := false
+		for _, mode := range typeInfo.SupportedBootModes {
+			if string(mode) == string(instance.CurrentInstanceBootMode) {
+				supported = true
+			}
+		}
+		if !supported {
+			return fmt.Errorf("%s does not support %s boot mode", typeInfo.InstanceType, instance.CurrentInstanceBootMode)
+		}
+	}
+
+	// one-time spot boxes terminate instead of stopping
+	if instance.InstanceLifecycle == types.InstanceLifecycleTypeSpot {
+		if instance.SpotInstanceRequestId == nil {
+			return fmt.Errorf("spot box without a spot request can't be stopped")
+		}
+		spotResp, err := client.DescribeSpotInstanceRequests(ctx, &ec2.DescribeSpotInstanceRequestsInput{
+			SpotInstanceRequestIds: []string{*instance.SpotInstanceRequestId},
+		})
+		if err != nil {
+			return err
+		}
+		if len(spotResp.SpotInstanceRequests) == 0 || spotResp.SpotInstanceRequests[0].Type != types.SpotInstanceTypePersistent {
+			return fmt.Errorf("one-time spot box can't be stopped, redeploy it with the new type instead")
+		}
+	}
+
+	return nil
+}
+
+func (a *AWS) resizeEC2Instance(client ec2API, instance types.Instance, instanceType string) error {
+	ctx := a.jobContext()
+
+	instanceID := *instance.InstanceId
+	if string(instance.InstanceType) == instanceType {
+		return nil
+	}
+
+	wasRunning := instance.State != nil && instance.State.Name == types.InstanceStateNameRunning
+	if wasRunning {
+		_, err := a.stopEC2Instances(client, []string{instanceID}, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := client.ModifyInstanceAttribute(ctx, &ec2.ModifyInstanceAttributeInput{
+		InstanceId: aws.String(instanceID),
+		InstanceType: &types.AttributeValue{
+			Value: aws.String(instanceType),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if wasRunning {
+		return a.startEC2Instances(client, []string{instanceID})
+	}
+	return nil
+}
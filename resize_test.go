@@ -0,0 +1,166 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func testInstanceType(instanceType types.InstanceType) types.InstanceTypeInfo {
+	return types.InstanceTypeInfo{
+		InstanceType: instanceType,
+		ProcessorInfo: &types.ProcessorInfo{
+			SupportedArchitectures: []types.ArchitectureType{types.ArchitectureTypeX8664},
+		},
+		NetworkInfo:        &types.NetworkInfo{EnaSupport: types.EnaSupportRequired},
+		SupportedBootModes: []types.BootModeType{types.BootModeTypeUefi, types.BootModeTypeLegacyBios},
+	}
+}
+
+func TestCheckResizeCompatible(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(instance *types.Instance, typeInfo *types.InstanceTypeInfo)
+		wantErr   bool
+	}{
+		{"compatible", func(instance *types.Instance, typeInfo *types.InstanceTypeInfo) {}, false},
+		{"pending box", func(instance *types.Instance, typeInfo *types.InstanceTypeInfo) {
+			instance.State.Name = types.InstanceStateNamePending
+		}, true},
+		{"architecture", func(instance *types.Instance, typeInfo *types.InstanceTypeInfo) {
+			instance.Architecture = types.ArchitectureValuesArm64
+		}, true},
+		{"ENA required", func(instance *types.Instance, typeInfo *types.InstanceTypeInfo) {
+			instance.EnaSupport = aws.Bool(false)
+		}, true},
+		{"boot mode", func(instance *types.Instance, typeInfo *types.InstanceTypeInfo) {
+			typeInfo.SupportedBootModes = []types.BootModeType{types.BootModeTypeUefi}
+		}, true},
+		{"persistent spot", func(instance *types.Instance, typeInfo *types.InstanceTypeInfo) {
+			instance.InstanceLifecycle = types.InstanceLifecycleTypeSpot
+			instance.SpotInstanceRequestId = aws.String("sir-persistent")
+		}, false},
+		{"one-time spot", func(instance *types.Instance, typeInfo *types.InstanceTypeInfo) {
+			instance.InstanceLifecycle = types.InstanceLifecycleTypeSpot
+			instance.SpotInstanceRequestId = aws.String("sir-onetime")
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeEC2()
+			client.spotRequests = []types.SpotInstanceRequest{
+				{SpotInstanceRequestId: aws.String("sir-persistent"), Type: types.SpotInstanceTypePersistent},
+				{SpotInstanceRequestId: aws.String("sir-onetime"), Type: types.SpotInstanceTypeOneTime},
+			}
+			instance := types.Instance{
+				InstanceId:              aws.String("i-0abc"),
+				State:                   &types.InstanceState{Name: types.InstanceStateNameRunning},
+				Architecture:            types.ArchitectureValuesX8664,
+				EnaSupport:              aws.Bool(true),
+				CurrentInstanceBootMode: types.InstanceBootModeValuesLegacyBios,
+			}
+			typeInfo := testInstanceType(types.InstanceTypeM5Large)
+			tt.configure(&instance, &typeInfo)
+
+			err := testAWS().checkResizeCompatible(client, instance, typeInfo)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResizeEC2Instances(t *testing.T) {
+	tests := []struct {
+		name         string
+		instanceType string
+		rolling      int
+		wantErr      bool
+	}{
+		{"all at once", "m5.large", 0, false},
+		{"one at a time", "m5.large", 1, false},
+		{"type not offered", "x9.huge", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeEC2()
+			client.instanceTypes = []types.InstanceTypeInfo{testInstanceType(types.InstanceTypeM5Large)}
+			running := client.addInstance("web", types.InstanceStateNameRunning)
+			stopped := client.addInstance("web", types.InstanceStateNameStopped)
+			arm := client.addInstance("web", types.InstanceStateNameRunning)
+			for _, instanceID := range []string{running, stopped, arm} {
+				client.instance(instanceID).EnaSupport = aws.Bool(true)
+			}
+			client.instance(arm).Architecture = types.ArchitectureValuesArm64
+
+			results, err := testAWS().resizeEC2Instances(client, []string{running, stopped, arm}, tt.instanceType, tt.rolling, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if len(results) != 3 {
+				t.Fatalf("%d results, want 3", len(results))
+			}
+			for _, result := range results {
+				if (result.Err != nil) != (result.InstanceID == arm) {
+					t.Errorf("%s: err = %v", result.InstanceID, result.Err)
+				}
+			}
+			want := map[string]types.InstanceStateName{running: "running", stopped: "stopped"}
+			for instanceID, state := range want {
+				instance := client.instance(instanceID)
+				if instance.InstanceType != types.InstanceTypeM5Large {
+					t.Errorf("%s is %s, want m5.large", instanceID, instance.InstanceType)
+				}
+				if instance.State.Name != state {
+					t.Errorf("%s is %s after resize, want %s", instanceID, instance.State.Name, state)
+				}
+			}
+			if client.instance(arm).InstanceType == types.InstanceTypeM5Large {
+				t.Error("incompatible box was resized")
+			}
+		})
+	}
+}
+
+// boxes launched the way createEC2Instance launches them are persistent spot
+// boxes, which resize stops and starts again
+func TestResizeDeployedBoxes(t *testing.T) {
+	client := newFakeEC2()
+	client.images = testImages()
+	client.instanceTypes = []types.InstanceTypeInfo{testInstanceType(types.InstanceTypeM5Large)}
+	a := testAWS()
+	instanceIDs := []string{}
+	for range 2 {
+		instanceID, err := a.createEC2Instance("sg-1", client, "web", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		instanceIDs = append(instanceIDs, instanceID)
+	}
+	for _, instanceID := range instanceIDs {
+		if client.instance(instanceID).InstanceLifecycle != types.InstanceLifecycleTypeSpot {
+			t.Fatalf("%s is not a spot box", instanceID)
+		}
+	}
+
+	results, err := a.resizeEC2Instances(client, instanceIDs, "m5.large", 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			t.Errorf("%s refused: %s", result.InstanceID, result.Err)
+		}
+	}
+	for _, instanceID := range instanceIDs {
+		instance := client.instance(instanceID)
+		if instance.InstanceType != types.InstanceTypeM5Large || instance.State.Name != types.InstanceStateNameRunning {
+			t.Errorf("%s is %s %s, want a running m5.large", instanceID, instance.State.Name, instance.InstanceType)
+		}
+	}
+}
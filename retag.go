@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// box that changed batch, From is needed to find its local script files
+type batchMove struct {
+	Box  EC2InstanceIP
+	From string
+}
+
+// moves every box of the batch, terminated ones included so the old name is gone
+func (a *AWS) renameBatch(client ec2API, from, to string, progress progressFunc) ([]batchMove, error) {
+	if from == "" || to == "" {
+		return nil, fmt.Errorf("rename needs the old and the new batch tag")
+	}
+	if from == to {
+		return nil, fmt.Errorf("batch is already called %s", to)
+	}
+
+	instances, err := a.batchInstances(client, from)
+	if err != nil {
+		return nil, err
+	}
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("batch %s has no boxes", from)
+	}
+	return a.retagEC2Instances(client, instances, to, progress)
+}
+
+// moves the given AUTO-BOX boxes to batch to, whatever batch they are in
+func (a *AWS) moveEC2Instances(client ec2API, instanceIDs []string, to string, progress progressFunc) ([]batchMove, error) {
+	ctx := a.jobContext()
+
+	if to == "" {
+		return nil, fmt.Errorf("move needs the batch tag to move to")
+	}
+	if len(instanceIDs) == 0 {
+		return nil, fmt.Errorf("no boxes to move")
+	}
+
+	resp, err := client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: instanceIDs,
+		Filters:     a.ownedFilters(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	instances := []types.Instance{}
+	for _, reservation := range resp.Reservations {
+		instances = append(instances, reservation.Instances...)
+	}
+	if len(instances) != len(instanceIDs) {
+		return nil, fmt.Errorf("only %d of the %d boxes are AUTO-BOX boxes", len(instances), len(instanceIDs))
+	}
+	return a.retagEC2Instances(client, instances, to, progress)
+}
+
+// renames every source batch to target
+func (a *AWS) mergeBatches(client ec2API, target string, sources []string, progress progressFunc) ([]batchMove, error) {
+	if target == "" || len(sources) == 0 {
+		return nil, fmt.Errorf("merge needs the target batch and the batches to merge into it")
+	}
+
+	moves := []batchMove{}
+	for _, source := range sources {
+		if source == target {
+			continue
+		}
+		moved, err := a.renameBatch(client, source, target, progress)
+		moves = append(moves, moved...)
+		if err != nil {
+			return moves, err
+		}
+	}
+	return moves, nil
+}
+
+// rewrites BatchTag on the boxes and on their Elastic IPs, progress gets a
+// step per box
+func (a *AWS) retagEC2Instances(client ec2API, instances []types.Instance, to string, progress progressFunc) ([]batchMove, error) {
+	ctx := a.jobContext()
+
+	moves := []batchMove{}
+	instanceIDs := []string{}
+	for _, instance := range instances {
+		from := instanceTag(instance, "BatchTag")
+		if from == to {
+			continue
+		}
+		moves = append(moves, batchMove{Box: instanceIPs(instance), From: from})
+		instanceIDs = append(instanceIDs, *instance.InstanceId)
+	}
+	if len(instanceIDs) == 0 {
+		return moves, nil
+	}
+	progress.total(len(instanceIDs))
+	for _, instanceID := range instanceIDs {
+		progress.start(instanceID)
+	}
+
+	batchTag := []types.Tag{
+		{
+			Key:   aws.String("BatchTag"),
+			Value: aws.String(to),
+		},
+	}
+	_, err := client.CreateTags(ctx, &ec2.CreateTagsInput{
+		Resources: instanceIDs,
+		Tags:      batchTag,
+	})
+	for _, move := range moves {
+		progress.finish(move.Box.InstanceID, fmt.Sprintf("%s -> %s", move.From, to), err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	addresses, err := a.taggedElasticIPs(client, "")
+	if err != nil {
+		return moves, err
+	}
+	allocationIDs := []string{}
+	for _, address := range addresses {
+		if slices.Contains(instanceIDs, aws.ToString(address.InstanceId)) {
+			allocationIDs = append(allocationIDs, aws.ToString(address.AllocationId))
+		}
+	}
+	if len(allocationIDs) > 0 {
+		_, err = client.CreateTags(ctx, &ec2.CreateTagsInput{
+			Resources: allocationIDs,
+			Tags:      batchTag,
+		})
+	}
+	return moves, err
+}
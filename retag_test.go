@@ -0,0 +1,142 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestRenameBatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		from      string
+		to        string
+		wantMoves int
+		wantErr   bool
+	}{
+		{"rename", "web", "frontend", 3, false},
+		{"into an existing batch", "web", "db", 3, false},
+		{"unknown batch", "nope", "frontend", 0, true},
+		{"same name", "web", "web", 0, true},
+		{"empty name", "web", "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeEC2()
+			a := testAWS()
+			web := []string{
+				client.addInstance("web", types.InstanceStateNameRunning),
+				client.addInstance("web", types.InstanceStateNameStopped),
+				client.addInstance("web", types.InstanceStateNameTerminated),
+			}
+			db := client.addInstance("db", types.InstanceStateNameRunning)
+			_, err := a.allocateElasticIPs(client, web[:1], "web")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			moves, err := a.renameBatch(client, tt.from, tt.to, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %t", err, tt.wantErr)
+			}
+			if len(moves) != tt.wantMoves {
+				t.Fatalf("%d moves, want %d", len(moves), tt.wantMoves)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			for _, move := range moves {
+				if move.From != "web" {
+					t.Errorf("%s moved from %s, want web", move.Box.InstanceID, move.From)
+				}
+			}
+			left, _ := a.batchInstanceIDs(client, "web")
+			if len(left) != 0 {
+				t.Errorf("boxes left in web: %v", left)
+			}
+			if batchT, _ := tagLookup(client.instance(db).Tags, "BatchTag"); batchT != "db" {
+				t.Errorf("db box moved to %s", batchT)
+			}
+			if batchT, _ := tagLookup(client.addresses[0].Tags, "BatchTag"); batchT != tt.to {
+				t.Errorf("Elastic IP still tagged %s, want %s", batchT, tt.to)
+			}
+		})
+	}
+}
+
+func TestMoveEC2Instances(t *testing.T) {
+	tests := []struct {
+		name      string
+		pick      func(web, db []string) []string
+		to        string
+		wantMoves int
+		wantErr   bool
+	}{
+		{"some boxes", func(web, db []string) []string { return web[:1] }, "canary", 1, false},
+		{"from two batches", func(web, db []string) []string { return []string{web[1], db[0]} }, "canary", 2, false},
+		{"already in the batch", func(web, db []string) []string { return web }, "web", 0, false},
+		{"not an AUTO-BOX box", func(web, db []string) []string { return []string{web[0], "i-notours"} }, "canary", 0, true},
+		{"nothing picked", func(web, db []string) []string { return nil }, "canary", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeEC2()
+			web := []string{
+				client.addInstance("web", types.InstanceStateNameRunning),
+				client.addInstance("web", types.InstanceStateNameRunning),
+			}
+			db := []string{client.addInstance("db", types.InstanceStateNameRunning)}
+			client.instances = append(client.instances, types.Instance{
+				InstanceId: aws.String("i-notours"),
+				State:      &types.InstanceState{Name: types.InstanceStateNameRunning},
+			})
+			picked := tt.pick(web, db)
+
+			moves, err := testAWS().moveEC2Instances(client, picked, tt.to, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %t", err, tt.wantErr)
+			}
+			if len(moves) != tt.wantMoves {
+				t.Fatalf("%d moves, want %d", len(moves), tt.wantMoves)
+			}
+			if tt.wantErr {
+				return
+			}
+			moved, _ := testAWS().batchInstanceIDs(client, tt.to)
+			for _, instanceID := range picked {
+				if !slices.Contains(moved, instanceID) {
+					t.Errorf("%s not in %s", instanceID, tt.to)
+				}
+			}
+		})
+	}
+}
+
+func TestMergeBatches(t *testing.T) {
+	client := newFakeEC2()
+	a := testAWS()
+	client.addInstance("web", types.InstanceStateNameRunning)
+	client.addInstance("blue", types.InstanceStateNameRunning)
+	client.addInstance("green", types.InstanceStateNameRunning)
+	client.addInstance("green", types.InstanceStateNameRunning)
+
+	moves, err := a.mergeBatches(client, "web", []string{"blue", "green", "web"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moves) != 3 {
+		t.Errorf("%d moves, want 3", len(moves))
+	}
+	web, _ := a.batchInstanceIDs(client, "web")
+	if len(web) != 4 {
+		t.Errorf("web has %d boxes after the merge, want 4", len(web))
+	}
+
+	_, err = a.mergeBatches(client, "web", []string{"blue"}, nil)
+	if err == nil {
+		t.Error("merging an empty batch should fail")
+	}
+}
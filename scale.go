@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+type scaleResult struct {
+	Launched   []EC2InstanceIP
+	Terminated []EC2InstanceIP
+}
+
+// launches or terminates boxes until the batch has the desired count.
+// ScalePolicy picks which boxes go first when scaling down: "newest" (default) or "oldest".
+// planned are the boxes the operator confirmed terminating, none when scaling
+// up: nothing changes when the live batch would terminate others. nil skips the check.
+// On an error the result still has the boxes launched or terminated before it.
+// progress gets a step per box launched or terminated
+func (a *AWS) scaleEC2Batch(client ec2API, securityGroupID, batchT string, desired int, planned []string, progress progressFunc) (scaleResult, error) {
+	ctx := a.jobContext()
+	result := scaleResult{}
+
+	if batchT == "" {
+		return result, fmt.Errorf("scaling needs a batch tag")
+	}
+	if desired < 0 {
+		return result, fmt.Errorf("desired count can't be negative")
+	}
+	err := checkScalePolicy(a.ScalePolicy)
+	if err != nil {
+		return result, err
+	}
+
+	live, err := a.batchInstances(client, batchT, "pending", "running", "stopping", "stopped")
+	if err != nil {
+		return result, err
+	}
+	a.scaleDownOrder(live)
+	terminating := []string{}
+	for _, instance := range live[:len(live)-min(desired, len(live))] {
+		terminating = append(terminating, *instance.InstanceId)
+	}
+	if planned != nil && !slices.Equal(slices.Sorted(slices.Values(planned)), slices.Sorted(slices.Values(terminating))) {
+		return result, fmt.Errorf("not scaling, the boxes changed since the summary (%d planned to terminate, %d now), scale again to see what goes", len(planned), len(terminating))
+	}
+
+	switch {
+	case len(live) < desired:
+		progress.total(desired - len(live))
+		err := a.ensurePlacementGroup(client)
+		if err != nil {
+			return result, err
+		}
+		subnetIDs, err := a.launchSubnets(client, desired-len(live), len(live))
+		if err != nil {
+			return result, err
+		}
+
+		launchedIDs := []string{}
+		var launchErr error
+		for i, subnetID := range subnetIDs {
+			step := fmt.Sprintf("%s box %d", batchT, len(live)+i+1)
+			progress.start(step)
+			instanceID, err := a.createEC2Instance(securityGroupID, client, batchT, subnetID)
+			progress.finish(step, instanceID, err)
+			if err != nil {
+				launchErr = fmt.Errorf("launched %d of %d boxes: %w", len(launchedIDs), len(subnetIDs), err)
+				break
+			}
+			launchedIDs = append(launchedIDs, instanceID)
+		}
+		if len(launchedIDs) == 0 {
+			return result, launchErr
+		}
+
+		if a.ElasticIPs {
+			result.Launched, err = a.allocateElasticIPs(client, launchedIDs, batchT)
+			if err != nil {
+				result.Launched = withLaunchedIDs(result.Launched, launchedIDs)
+			}
+			return result, errors.Join(launchErr, err)
+		}
+
+		waiter := ec2.NewInstanceRunningWaiter(client)
+		resp, err := waiter.WaitForOutput(ctx, &ec2.DescribeInstancesInput{
+			InstanceIds: launchedIDs,
+		}, stateWaitTimeout)
+		if err != nil {
+			result.Launched = withLaunchedIDs(result.Launched, launchedIDs)
+			return result, errors.Join(launchErr, err)
+		}
+		for _, reservation := range resp.Reservations {
+			for _, instance := range reservation.Instances {
+				result.Launched = append(result.Launched, instanceIPs(instance))
+			}
+		}
+		return result, launchErr
+
+	case len(live) > desired:
+		progress.total(len(terminating))
+		for _, instanceID := range terminating {
+			progress.start(instanceID)
+		}
+		finish := func(err error) {
+			for _, instanceID := range terminating {
+				progress.finish(instanceID, "terminated", err)
+			}
+		}
+		terminated := []EC2InstanceIP{}
+		for _, instance := range live[:len(live)-desired] {
+			terminated = append(terminated, instanceIPs(instance))
+		}
+		err := a.releaseElasticIPs(client, batchT, terminating)
+		if err == nil {
+			err = a.terminateEC2Instances(client, terminating)
+		}
+		finish(err)
+		if err != nil {
+			return result, err
+		}
+		result.Terminated = terminated
+	}
+
+	return result, nil
+}
+
+// ScalePolicy is empty (newest), "newest" or "oldest"
+func checkScalePolicy(policy string) error {
+	switch policy {
+	case "", "newest", "oldest":
+		return nil
+	}
+	return fmt.Errorf("scale policy %q is not newest or oldest", policy)
+}
+
+// launched boxes missing from boxes, by ID only, the lookup of their addresses failed
+func withLaunchedIDs(boxes []EC2InstanceIP, launchedIDs []string) []EC2InstanceIP {
+	for _, instanceID := range launchedIDs {
+		if !slices.ContainsFunc(boxes, func(box EC2InstanceIP) bool { return box.InstanceID == instanceID }) {
+			boxes = append(boxes, EC2InstanceIP{InstanceID: instanceID})
+		}
+	}
+	return boxes
+}
+
+// sorts live by which box scaling down takes first
+func (a *AWS) scaleDownOrder(live []types.Instance) {
+	sort.Slice(live, func(i, j int) bool {
+		if a.ScalePolicy == "oldest" {
+			return live[i].LaunchTime.Before(*live[j].LaunchTime)
+		}
+		return live[i].LaunchTime.After(*live[j].LaunchTime)
+	})
+}
+
+func instanceIPs(instance types.Instance) EC2InstanceIP {
+	ipInfo := EC2InstanceIP{
+		InstanceID: *instance.InstanceId,
+	}
+	if instance.PublicIpAddress != nil {
+		ipInfo.PublicIP = *instance.PublicIpAddress
+	}
+	if instance.PrivateIpAddress != nil {
+		ipInfo.PrivateIP = *instance.PrivateIpAddress
+	}
+	if instance.Ipv6Address != nil {
+		ipInfo.IPv6 = *instance.Ipv6Address
+	}
+	return ipInfo
+}
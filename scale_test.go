@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestScaleEC2Batch(t *testing.T) {
+	tests := []struct {
+		name           string
+		batchT         string
+		policy         string
+		elasticIPs     bool
+		desired        int
+		wantLaunched   int
+		wantTerminated []int
+		wantErr        bool
+	}{
+		{"scale up", "web", "", false, 5, 2, nil, false},
+		{"scale up with Elastic IPs", "web", "", true, 4, 1, nil, false},
+		{"already there", "web", "", false, 3, 0, nil, false},
+		{"scale down newest first", "web", "", false, 1, 0, []int{2, 1}, false},
+		{"scale down oldest first", "web", "oldest", false, 1, 0, []int{0, 1}, false},
+		{"scale to zero", "web", "", false, 0, 0, []int{2, 1, 0}, false},
+		{"needs a batch", "", "", false, 1, 0, nil, true},
+		{"negative count", "web", "", false, -1, 0, nil, true},
+		{"unknown policy", "web", "largest", false, 1, 0, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeEC2()
+			client.images = testImages()
+			// launched a minute apart, oldest first
+			existing := []string{
+				client.addInstance("web", types.InstanceStateNameRunning),
+				client.addInstance("web", types.InstanceStateNameStopped),
+				client.addInstance("web", types.InstanceStateNameRunning),
+			}
+			client.addInstance("web", types.InstanceStateNameTerminated)
+			client.addInstance("db", types.InstanceStateNameRunning)
+			a := testAWS()
+			a.ScalePolicy = tt.policy
+			a.ElasticIPs = tt.elasticIPs
+
+			result, err := a.scaleEC2Batch(client, "sg-1", tt.batchT, tt.desired, nil, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %t", err, tt.wantErr)
+			}
+			if len(result.Launched) != tt.wantLaunched {
+				t.Errorf("%d launched, want %d", len(result.Launched), tt.wantLaunched)
+			}
+			if tt.elasticIPs && len(client.addresses) != tt.wantLaunched {
+				t.Errorf("%d Elastic IPs, want one per launched box", len(client.addresses))
+			}
+
+			var terminated []string
+			for _, box := range result.Terminated {
+				terminated = append(terminated, box.InstanceID)
+			}
+			var wantTerminated []string
+			for _, i := range tt.wantTerminated {
+				wantTerminated = append(wantTerminated, existing[i])
+			}
+			if !slices.Equal(terminated, wantTerminated) {
+				t.Errorf("terminated %v, want %v", terminated, wantTerminated)
+			}
+		})
+	}
+}
+
+// boxes scaled before an error are in the result, the ones that failed are not
+func TestScaleEC2BatchPartial(t *testing.T) {
+	tests := []struct {
+		name           string
+		failOn         string
+		failAfter      int
+		desired        int
+		wantLaunched   int
+		wantTerminated int
+	}{
+		{"launch fails on the third box", "RunInstances", 2, 6, 2, 0},
+		{"launch fails on the first box", "RunInstances", 0, 6, 0, 0},
+		{"terminate fails", "TerminateInstances", 0, 1, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeEC2()
+			client.images = testImages()
+			for range 3 {
+				client.addInstance("web", types.InstanceStateNameRunning)
+			}
+			client.failOn = map[string]error{tt.failOn: errors.New("InsufficientInstanceCapacity")}
+			client.failAfter = map[string]int{tt.failOn: tt.failAfter}
+
+			result, err := testAWS().scaleEC2Batch(client, "sg-1", "web", tt.desired, nil, nil)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if len(result.Launched) != tt.wantLaunched || len(result.Terminated) != tt.wantTerminated {
+				t.Errorf("launched %d, terminated %d, want %d and %d",
+					len(result.Launched), len(result.Terminated), tt.wantLaunched, tt.wantTerminated)
+			}
+		})
+	}
+}
+
+// the boxes confirmed in the summary go, or nothing does
+func TestScaleEC2BatchPlanned(t *testing.T) {
+	tests := []struct {
+		name    string
+		added   bool
+		planned func(existing []string) []string
+		desired int
+		wantErr bool
+	}{
+		{"as confirmed", false, func(existing []string) []string { return existing[2:] }, 2, false},
+		{"a box launched since", true, func(existing []string) []string { return existing[2:] }, 2, true},
+		{"other boxes confirmed", false, func(existing []string) []string { return existing[:1] }, 2, true},
+		{"confirmed scaling up only", true, func([]string) []string { return []string{} }, 3, true},
+		{"scaling up as confirmed", false, func([]string) []string { return []string{} }, 4, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeEC2()
+			client.images = testImages()
+			existing := []string{}
+			for range 3 {
+				existing = append(existing, client.addInstance("web", types.InstanceStateNameRunning))
+			}
+			planned := tt.planned(existing)
+			if tt.added {
+				client.addInstance("web", types.InstanceStateNameRunning)
+			}
+
+			result, err := testAWS().scaleEC2Batch(client, "sg-1", "web", tt.desired, planned, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %t", err, tt.wantErr)
+			}
+			if tt.wantErr && (client.callCount("TerminateInstances") != 0 || len(result.Launched) != 0) {
+				t.Errorf("boxes changed after refusing: %+v", result)
+			}
+		})
+	}
+}
+
+// a step per box, like the deploy
+func TestScaleEC2BatchProgress(t *testing.T) {
+	client := newFakeEC2()
+	client.images = testImages()
+	client.addInstance("web", types.InstanceStateNameRunning)
+	a := testAWS()
+
+	checklist := jobChecklist{}
+	progress := progressFunc(func(msg progressMsg) { checklist.update(msg) })
+	scaled, err := a.scaleEC2Batch(client, "sg-1", "web", 3, nil, progress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done, failed, total := checklist.counts(); done != 2 || failed != 0 || total != 2 {
+		t.Errorf("scaling up %d/%d done, %d failed, want a step per launched box", done, total, failed)
+	}
+	if checklist.steps[1].name != "web box 3" || checklist.steps[1].detail != scaled.Launched[1].InstanceID {
+		t.Errorf("step %+v, want the third box of web", checklist.steps[1])
+	}
+
+	checklist = jobChecklist{}
+	scaled, err = a.scaleEC2Batch(client, "sg-1", "web", 1, nil, progress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, box := range scaled.Terminated {
+		if step := checklist.steps[i]; step.name != box.InstanceID || step.status != stepDone {
+			t.Errorf("step %+v, want %s terminated", step, box.InstanceID)
+		}
+	}
+	if len(checklist.steps) != 2 {
+		t.Errorf("%d steps scaling down, want the 2 terminated", len(checklist.steps))
+	}
+}
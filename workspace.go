@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// Workspace keeps operators sharing an account apart, every resource the
+// tool creates carries it and every lookup filters on it
+const workspaceTag = "AUTO-BOX-WORKSPACE"
+
+// AUTO-BOX resources of the workspace, plus the extra filters.
+// Without a Workspace every AUTO-BOX resource of the region matches.
+func (a *AWS) ownedFilters(filters ...types.Filter) []types.Filter {
+	owned := []types.Filter{
+		{
+			Name:   aws.String("tag:AUTO-BOX"),
+			Values: []string{"true"},
+		},
+	}
+	if a.Workspace != "" {
+		owned = append(owned, types.Filter{
+			Name:   aws.String("tag:" + workspaceTag),
+			Values: []string{a.Workspace},
+		})
+	}
+	return append(owned, filters...)
+}
+
+// key pair and security group names are unique in the region or VPC, each
+// workspace gets its own
+func (a *AWS) scopedName(name string) string {
+	if a.Workspace == "" {
+		return name
+	}
+	return fmt.Sprintf("%s-%s", name, a.Workspace)
+}
+
+// key pair the boxes launch with, its .pem is named after it
+func (a *AWS) keyPairName() string {
+	return a.scopedName(a.PemKeyFileName)
+}
+
+// a resource is the workspace's when tagged with it, without a Workspace
+// when no workspace claimed it
+func (a *AWS) owns(tags []types.Tag) bool {
+	workspace, _ := tagLookup(tags, workspaceTag)
+	return workspace == a.Workspace
+}
+
+// deleting every batch takes every AUTO-BOX box of the region without a
+// Workspace, other operators' included
+func (a *AWS) checkDeleteAll(batchT string) error {
+	if batchT == "" && a.Workspace == "" {
+		return fmt.Errorf("set a Workspace to delete every batch, or set a batch")
+	}
+	return nil
+}
+
+// tags every created resource gets
+func (a *AWS) ownedTags(tags ...types.Tag) []types.Tag {
+	owned := []types.Tag{
+		{
+			Key:   aws.String("AUTO-BOX"),
+			Value: aws.String("true"),
+		},
+	}
+	if a.Workspace != "" {
+		owned = append(owned, types.Tag{
+			Key:   aws.String(workspaceTag),
+			Value: aws.String(a.Workspace),
+		})
+	}
+	return append(owned, tags...)
+}
+
+type claimResult struct {
+	InstanceIDs      []string
+	AllocationIDs    []string
+	KeyPairIDs       []string
+	SecurityGroupIDs []string
+}
+
+// migration for resources created before workspaces: tags the AUTO-BOX boxes
+// of the batch (all batches when empty) and their Elastic IPs that have no
+// workspace yet. The key pairs and security groups the batches share are only
+// claimed along with every batch. Resources of other workspaces are left alone.
+func (a *AWS) claimResources(client ec2API, batchT string) (claimResult, error) {
+	ctx := a.jobContext()
+	result := claimResult{}
+
+	if a.Workspace == "" {
+		return result, fmt.Errorf("set a Workspace to claim boxes for")
+	}
+
+	filters := []types.Filter{
+		{
+			Name:   aws.String("tag:AUTO-BOX"),
+			Values: []string{"true"},
+		},
+		{
+			Name:   aws.String("instance-state-name"),
+			Values: []string{"pending", "running", "stopping", "stopped"},
+		},
+	}
+	if batchT != "" {
+		filters = append(filters, types.Filter{
+			Name:   aws.String("tag:BatchTag"),
+			Values: []string{batchT},
+		})
+	}
+	paginator := ec2.NewDescribeInstancesPaginator(client, &ec2.DescribeInstancesInput{
+		Filters: filters,
+	})
+	for paginator.HasMorePages() {
+		resp, err := paginator.NextPage(ctx)
+		if err != nil {
+			return result, err
+		}
+		for _, reservation := range resp.Reservations {
+			for _, instance := range reservation.Instances {
+				if instanceTag(instance, workspaceTag) == "" {
+					result.InstanceIDs = append(result.InstanceIDs, *instance.InstanceId)
+				}
+			}
+		}
+	}
+
+	addresses, err := client.DescribeAddresses(ctx, &ec2.DescribeAddressesInput{
+		Filters: filters[:1],
+	})
+	if err != nil {
+		return result, err
+	}
+	for _, address := range addresses.Addresses {
+		_, claimed := tagLookup(address.Tags, workspaceTag)
+		if !claimed && slices.Contains(result.InstanceIDs, aws.ToString(address.InstanceId)) {
+			result.AllocationIDs = append(result.AllocationIDs, aws.ToString(address.AllocationId))
+		}
+	}
+
+	if batchT == "" {
+		keyPairs, err := client.DescribeKeyPairs(ctx, &ec2.DescribeKeyPairsInput{
+			Filters: filters[:1],
+		})
+		if err != nil {
+			return result, err
+		}
+		for _, keyPair := range keyPairs.KeyPairs {
+			if _, claimed := tagLookup(keyPair.Tags, workspaceTag); !claimed {
+				result.KeyPairIDs = append(result.KeyPairIDs, aws.ToString(keyPair.KeyPairId))
+			}
+		}
+		groups, err := client.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{
+			Filters: filters[:1],
+		})
+		if err != nil {
+			return result, err
+		}
+		for _, group := range groups.SecurityGroups {
+			if _, claimed := tagLookup(group.Tags, workspaceTag); !claimed {
+				result.SecurityGroupIDs = append(result.SecurityGroupIDs, aws.ToString(group.GroupId))
+			}
+		}
+	}
+
+	resources := slices.Concat(result.InstanceIDs, result.AllocationIDs, result.KeyPairIDs, result.SecurityGroupIDs)
+	if len(resources) == 0 {
+		return result, nil
+	}
+	_, err = client.CreateTags(ctx, &ec2.CreateTagsInput{
+		Resources: resources,
+		Tags: []types.Tag{
+			{
+				Key:   aws.String(workspaceTag),
+				Value: aws.String(a.Workspace),
+			},
+		},
+	})
+	return result, err
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// one web box per workspace plus one created before workspaces existed
+func seedWorkspaces(client *fakeEC2) (alice, bob, legacy string) {
+	alice = client.addInstance("web", types.InstanceStateNameRunning, workspaceTag, "alice")
+	bob = client.addInstance("web", types.InstanceStateNameRunning, workspaceTag, "bob")
+	legacy = client.addInstance("web", types.InstanceStateNameRunning)
+	return alice, bob, legacy
+}
+
+func TestWorkspaceIsolation(t *testing.T) {
+	tests := []struct {
+		name      string
+		workspace string
+		want      func(alice, bob, legacy string) []string
+	}{
+		{"no workspace sees everything", "", func(alice, bob, legacy string) []string { return []string{alice, bob, legacy} }},
+		{"alice", "alice", func(alice, bob, legacy string) []string { return []string{alice} }},
+		{"unused workspace", "carol", func(alice, bob, legacy string) []string { return nil }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeEC2()
+			a := testAWS()
+			a.Workspace = tt.workspace
+			want := tt.want(seedWorkspaces(client))
+
+			ids, err := a.batchInstanceIDs(client, "web")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(ids, want) {
+				t.Errorf("batchInstanceIDs = %v, want %v", ids, want)
+			}
+
+			_, boxes, err := a.compileIPaddressesAws(client, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(boxes) != len(want) {
+				t.Errorf("compileIPaddressesAws found %d boxes, want %d", len(boxes), len(want))
+			}
+
+			// without a workspace nothing goes
+			err = a.deleteEC2Instances(client, "")
+			if (err != nil) != (tt.workspace == "") {
+				t.Fatalf("deleteEC2Instances err = %v", err)
+			}
+			for _, instance := range client.instances {
+				terminated := instance.State.Name == types.InstanceStateNameTerminated
+				if terminated != (tt.workspace != "" && slices.Contains(want, *instance.InstanceId)) {
+					t.Errorf("%s terminated = %t", *instance.InstanceId, terminated)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateInWorkspace(t *testing.T) {
+	client := newFakeEC2()
+	client.images = testImages()
+	a := testAWS()
+	a.Workspace = "alice"
+
+	instanceID, err := a.createEC2Instance("sg-1", client, "web", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if workspace, _ := tagLookup(client.instance(instanceID).Tags, workspaceTag); workspace != "alice" {
+		t.Errorf("box tagged with workspace %q, want alice", workspace)
+	}
+	_, err = a.allocateElasticIPs(client, []string{instanceID}, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if workspace, _ := tagLookup(client.addresses[0].Tags, workspaceTag); workspace != "alice" {
+		t.Errorf("Elastic IP tagged with workspace %q, want alice", workspace)
+	}
+}
+
+func TestClaimResources(t *testing.T) {
+	tests := []struct {
+		name       string
+		workspace  string
+		batchT     string
+		wantClaims int
+		wantErr    bool
+	}{
+		{"claim all legacy boxes", "alice", "", 2, false},
+		{"claim one batch", "alice", "db", 1, false},
+		{"no workspace", "", "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFakeEC2()
+			a := testAWS()
+			_, bob, legacy := seedWorkspaces(client)
+			db := client.addInstance("db", types.InstanceStateNameStopped)
+			client.addInstance("db", types.InstanceStateNameTerminated)
+			_, err := a.allocateElasticIPs(client, []string{legacy}, "web")
+			if err != nil {
+				t.Fatal(err)
+			}
+			autoBox := []types.Tag{{Key: aws.String("AUTO-BOX"), Value: aws.String("true")}}
+			bobs := append(slices.Clone(autoBox), types.Tag{Key: aws.String(workspaceTag), Value: aws.String("bob")})
+			client.keyPairs = []types.KeyPairInfo{
+				{KeyName: aws.String("autobox-test"), KeyPairId: aws.String("key-legacy"), Tags: autoBox},
+				{KeyName: aws.String("autobox-test-bob"), KeyPairId: aws.String("key-bob"), Tags: bobs},
+			}
+			client.securityGroups = []types.SecurityGroup{
+				{GroupName: aws.String("sgAutoBox"), GroupId: aws.String("sg-legacy"), Tags: autoBox},
+				{GroupName: aws.String("sgAutoBox-bob"), GroupId: aws.String("sg-bob"), Tags: bobs},
+			}
+
+			a.Workspace = tt.workspace
+			result, err := a.claimResources(client, tt.batchT)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %t", err, tt.wantErr)
+			}
+			if len(result.InstanceIDs) != tt.wantClaims {
+				t.Fatalf("claimed %v, want %d boxes", result.InstanceIDs, tt.wantClaims)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if workspace, _ := tagLookup(client.instance(bob).Tags, workspaceTag); workspace != "bob" {
+				t.Errorf("bob's box moved to workspace %q", workspace)
+			}
+			for _, instanceID := range result.InstanceIDs {
+				if workspace, _ := tagLookup(client.instance(instanceID).Tags, workspaceTag); workspace != tt.workspace {
+					t.Errorf("%s in workspace %q, want %s", instanceID, workspace, tt.workspace)
+				}
+			}
+			wantEIP := slices.Contains(result.InstanceIDs, legacy)
+			if claimed := len(result.AllocationIDs) == 1; claimed != wantEIP {
+				t.Errorf("Elastic IPs claimed %v, want claimed %t", result.AllocationIDs, wantEIP)
+			}
+			if tt.batchT == "db" && !slices.Equal(result.InstanceIDs, []string{db}) {
+				t.Errorf("claimed %v, want only %s", result.InstanceIDs, db)
+			}
+			// the shared key pair and group go along with every batch only
+			wantShared := tt.batchT == ""
+			if claimed := slices.Equal(result.KeyPairIDs, []string{"key-legacy"}); claimed != wantShared {
+				t.Errorf("key pairs claimed %v, want claimed %t", result.KeyPairIDs, wantShared)
+			}
+			if claimed := slices.Equal(result.SecurityGroupIDs, []string{"sg-legacy"}); claimed != wantShared {
+				t.Errorf("security groups claimed %v, want claimed %t", result.SecurityGroupIDs, wantShared)
+			}
+		})
+	}
+}
+
+// key pairs and security groups are named per workspace and used or deleted
+// only by the workspace that owns them
+func TestWorkspaceKeyPairAndGroup(t *testing.T) {
+	tests := []struct {
+		name        string
+		workspace   string
+		owner       string // workspace tag of the existing key pair and group
+		wantName    string
+		wantErr     bool
+		wantDeleted bool
+	}{
+		{"no workspace uses the legacy ones", "", "", "autobox-test", false, true},
+		{"legacy ones claimed by alice", "", "alice", "autobox-test", true, false},
+		{"alice uses her own", "alice", "alice", "autobox-test-alice", false, true},
+		{"bob's under alice's name", "alice", "bob", "autobox-test-alice", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chdirTemp(t)
+			a := testAWS()
+			a.Workspace = tt.workspace
+			if a.keyPairName() != tt.wantName {
+				t.Fatalf("key pair name = %s, want %s", a.keyPairName(), tt.wantName)
+			}
+			tags := []types.Tag{{Key: aws.String("AUTO-BOX"), Value: aws.String("true")}}
+			if tt.owner != "" {
+				tags = append(tags, types.Tag{Key: aws.String(workspaceTag), Value: aws.String(tt.owner)})
+			}
+			client := newFakeEC2()
+			client.keyPairs = []types.KeyPairInfo{{KeyName: aws.String(tt.wantName), KeyPairId: aws.String("key-1"), Tags: tags}}
+			client.securityGroups = []types.SecurityGroup{{
+				GroupId:   aws.String("sg-existing"),
+				GroupName: aws.String(a.scopedName("sgAutoBox")),
+				VpcId:     aws.String("vpc-default"),
+				Tags:      tags,
+			}}
+
+			err := a.createPEMFile(client)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("createPEMFile err = %v, wantErr %t", err, tt.wantErr)
+			}
+			_, err = a.createSecurityGroup("sgAutoBox", "test", client)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("createSecurityGroup err = %v, wantErr %t", err, tt.wantErr)
+			}
+			if created := client.callCount("CreateKeyPair") + client.callCount("CreateSecurityGroup"); created != 0 {
+				t.Errorf("%d resources created next to the existing ones", created)
+			}
+
+			deleted, err := a.deletePEMFile(client)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gone := len(client.keyPairs) == 0; deleted != tt.wantDeleted || gone != tt.wantDeleted {
+				t.Errorf("deleted = %t with %d key pairs left, want deleted %t", deleted, len(client.keyPairs), tt.wantDeleted)
+			}
+		})
+	}
+}